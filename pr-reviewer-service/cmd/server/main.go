@@ -11,7 +11,9 @@ import (
 
 	"pr-reviewer-service/internal/config"
 	"pr-reviewer-service/internal/db"
+	"pr-reviewer-service/internal/directory"
 	transport "pr-reviewer-service/internal/http"
+	"pr-reviewer-service/internal/notify"
 	"pr-reviewer-service/internal/repository"
 	"pr-reviewer-service/internal/service"
 )
@@ -19,6 +21,18 @@ import (
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import", "export":
+			if err := runOrgCommand(cfg, os.Args[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q\n%s", os.Args[1], orgUsage)
+		}
+	}
+
 	database, err := db.New(cfg.DSN)
 	if err != nil {
 		log.Fatalf("db connection failed: %v", err)
@@ -28,12 +42,35 @@ func main() {
 	teamsRepo := repository.NewTeamsRepo(database)
 	usersRepo := repository.NewUsersRepo(database)
 	prsRepo := repository.NewPRsRepo(database)
+	outboxRepo := repository.NewOutboxRepo(database)
+	subsRepo := repository.NewSubscriptionsRepo(database)
 
 	teamsSvc := service.NewTeamsService(teamsRepo)
 	usersSvc := service.NewUsersService(usersRepo)
-	prsSvc := service.NewPRService(prsRepo, usersRepo, database)
+	prsSvc := service.NewPRService(prsRepo, usersRepo, teamsRepo, database)
 
-	handler := transport.NewHandler(teamsSvc, usersSvc, prsSvc)
+	forgeSvc := service.NewForgeService(prsSvc, usersRepo)
+	subsSvc := service.NewSubscriptionsService(subsRepo, outboxRepo)
+
+	handler := transport.NewHandler(teamsSvc, usersSvc, prsSvc).
+		WithAdminToken(cfg.AdminToken).
+		WithGitHubWebhook(forgeSvc, cfg.GitHubWebhookSecret).
+		WithGitLabWebhook(forgeSvc, cfg.GitLabWebhookToken).
+		WithSubscriptions(subsSvc)
+	var dirSync *service.DirectorySync
+	if cfg.LDAPURL != "" {
+		dirSync = service.NewDirectorySync(prsSvc, &directory.LDAP{
+			URL:          cfg.LDAPURL,
+			BindDN:       cfg.LDAPBindDN,
+			BindPassword: cfg.LDAPBindPassword,
+			GroupBaseDN:  cfg.LDAPGroupBaseDN,
+			GroupNames:   cfg.LDAPGroups,
+			UserIDAttr:   cfg.LDAPUserIDAttr,
+			UsernameAttr: cfg.LDAPUsernameAttr,
+			Timeout:      30 * time.Second,
+		}, cfg.LDAPSyncInterval)
+		handler.WithDirectorySync(dirSync)
+	}
 	router := transport.NewRouter(handler)
 
 	srv := &http.Server{
@@ -41,6 +78,33 @@ func main() {
 		Handler: router,
 	}
 
+	// Рассылка событий из outbox, контроль SLA ревью, обработка отпусков и синхронизация каталога живут до остановки сервера.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	channels := []notify.Channel{
+		notify.NewWebhookChannel(subsRepo, nil),
+		notify.NewChatChannel(notify.RepoChatDirectory{Users: usersRepo, Teams: teamsRepo}, nil),
+	}
+	if cfg.SMTPAddr != "" {
+		channels = append(channels, notify.NewEmailChannel(usersRepo, notify.SMTPMailer{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}))
+	}
+	dispatcher := notify.NewDispatcher(outboxRepo, channels...)
+	go dispatcher.Run(bgCtx)
+	if cfg.ReviewSLAInterval > 0 {
+		go service.NewReviewScheduler(prsSvc, cfg.ReviewSLAInterval).Run(bgCtx)
+	}
+	if cfg.AvailabilityInterval > 0 {
+		go service.NewAvailabilityScheduler(prsSvc, cfg.AvailabilityInterval).Run(bgCtx)
+	}
+	if dirSync != nil && cfg.LDAPSyncInterval > 0 {
+		go dirSync.Run(bgCtx)
+	}
+
 	go func() {
 		log.Printf("listening on %s", cfg.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -51,6 +115,7 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pr-reviewer-service/internal/config"
+	"pr-reviewer-service/internal/db"
+	"pr-reviewer-service/internal/orgfile"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+)
+
+const orgUsage = `usage:
+  pr-reviewer-service import [-format csv|yaml] [-dry-run] FILE
+  pr-reviewer-service export [-format csv|yaml] [FILE]
+
+FILE "-" means stdin/stdout; the format defaults to the file extension, otherwise csv.`
+
+// runOrgCommand выполняет подкоманду import или export оргструктуры напрямую через базу.
+func runOrgCommand(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), orgUsage) }
+	formatFlag := fs.String("format", "", "csv or yaml")
+	dryRun := false
+	if args[0] == "import" {
+		fs.BoolVar(&dryRun, "dry-run", false, "validate and report changes without applying them")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	if fs.NArg() > 1 || (args[0] == "import" && path == "") {
+		fs.Usage()
+		return errors.New("bad arguments")
+	}
+	format, err := fileFormat(*formatFlag, path)
+	if err != nil {
+		return err
+	}
+
+	database, err := db.New(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("db connection failed: %w", err)
+	}
+	defer database.Close()
+	teams := service.NewTeamsService(repository.NewTeamsRepo(database))
+	ctx := context.Background()
+
+	if args[0] == "export" {
+		out := io.Writer(os.Stdout)
+		if path != "" && path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		return teams.Export(ctx, out, format)
+	}
+
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	res, err := teams.Import(ctx, in, format, dryRun)
+	var invalid *service.ImportError
+	if errors.As(err, &invalid) {
+		for _, d := range invalid.Details() {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, d)
+		}
+		return fmt.Errorf("%d invalid rows, nothing imported", len(invalid.Rows))
+	}
+	if err != nil {
+		return err
+	}
+	verb := "imported"
+	if res.DryRun {
+		verb = "dry run, would import"
+	}
+	fmt.Printf("%s: teams %d created, %d updated; users %d created, %d updated; memberships %d added, %d updated\n",
+		verb, res.TeamsCreated, res.TeamsUpdated, res.UsersCreated, res.UsersUpdated, res.MembershipsAdded, res.MembershipsUpdated)
+	return nil
+}
+
+// fileFormat возвращает формат из флага, а без него — по расширению файла (по умолчанию csv).
+func fileFormat(flagValue, path string) (orgfile.Format, error) {
+	if flagValue != "" {
+		return orgfile.ParseFormat(flagValue)
+	}
+	if f, err := orgfile.ParseFormat(strings.TrimPrefix(filepath.Ext(path), ".")); err == nil {
+		return f, nil
+	}
+	return orgfile.CSV, nil
+}
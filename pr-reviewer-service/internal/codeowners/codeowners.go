@@ -0,0 +1,136 @@
+// Package codeowners разбирает правила в формате CODEOWNERS и находит владельцев изменённых файлов.
+//
+// Поддерживается подмножество синтаксиса gitignore, которое используют GitHub и GitLab:
+// "*" и "?" внутри сегмента пути, "**" для любого числа сегментов, "/" в начале или середине
+// шаблона привязывает его к корню репозитория, "/" в конце — только к каталогу.
+// Шаблон, совпавший с каталогом, распространяется на всё его содержимое.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// ParseError — ошибка в строке файла CODEOWNERS.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+// Parse разбирает текст CODEOWNERS. Пустые строки и комментарии (#) пропускаются,
+// "@" перед владельцем необязателен. Секции GitLab ([Section]) не поддерживаются.
+func Parse(text string) ([]model.CodeownersRule, error) {
+	var rules []model.CodeownersRule
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, &ParseError{Line: n, Msg: "sections are not supported"}
+		}
+		fields := strings.Fields(line)
+		if _, err := compile(fields[0]); err != nil {
+			return nil, &ParseError{Line: n, Msg: fmt.Sprintf("bad pattern %q", fields[0])}
+		}
+		rule := model.CodeownersRule{Pattern: fields[0], Owners: []string{}}
+		for _, owner := range fields[1:] {
+			owner = strings.TrimPrefix(owner, "@")
+			if owner == "" || strings.ContainsAny(owner, "/@") {
+				return nil, &ParseError{Line: n, Msg: fmt.Sprintf("owner %q must be a user_id", owner)}
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// Owners возвращает для каждого владельца изменённые файлы из files, которыми он владеет.
+// Для файла действует последнее подходящее правило из rules.
+func Owners(rules []model.CodeownersRule, files []string) map[string][]string {
+	compiled := make([]*regexp.Regexp, len(rules))
+	for i, r := range rules {
+		compiled[i], _ = compile(r.Pattern)
+	}
+
+	res := make(map[string][]string)
+	for _, f := range files {
+		f = strings.TrimPrefix(f, "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			if compiled[i] == nil || !compiled[i].MatchString(f) {
+				continue
+			}
+			for _, owner := range rules[i].Owners {
+				res[owner] = append(res[owner], f)
+			}
+			break
+		}
+	}
+	for _, paths := range res {
+		sort.Strings(paths)
+	}
+	return res
+}
+
+// Match сообщает, подходит ли путь path под шаблон pattern.
+func Match(pattern, path string) bool {
+	re, err := compile(pattern)
+	return err == nil && re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.Trim(pattern, "/")
+	if p == "" {
+		if pattern == "" {
+			return nil, fmt.Errorf("empty pattern")
+		}
+		// "/" — весь репозиторий
+		return regexp.Compile(`^.+$`)
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "/**") && i+3 == len(p):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,84 @@
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*", "main.go", true},
+		{"*.go", "internal/service/prs.go", true},
+		{"*.go", "internal/service/prs.go.md", false},
+		{"/*.md", "README.md", true},
+		{"/*.md", "docs/README.md", false},
+		{"docs/", "docs/api/index.md", true},
+		{"docs/", "docs", false},
+		{"docs", "internal/docs/x.md", true},
+		{"/docs", "internal/docs/x.md", false},
+		{"internal/service/", "internal/service/prs.go", true},
+		{"internal/service", "pkg/internal/service/prs.go", false},
+		{"internal/**/handlers.go", "internal/http/handlers.go", true},
+		{"internal/**/handlers.go", "internal/handlers.go", true},
+		{"**/migrations", "db/migrations/001.sql", true},
+		{"apps/*/main.go", "apps/api/main.go", true},
+		{"apps/*/main.go", "apps/api/cmd/main.go", false},
+		{"apps/**", "apps/api/cmd/main.go", true},
+		{"file?.txt", "a/file1.txt", true},
+		{"/", "anything/at/all.go", true},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.path); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestParseAndOwners(t *testing.T) {
+	rules, err := Parse(`
+# владельцы по умолчанию
+*                   @u1
+*.sql               @u2 u3   # миграции
+/internal/notify/   @u4
+/internal/notify/email.go
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 4 || !reflect.DeepEqual(rules[1].Owners, []string{"u2", "u3"}) || len(rules[3].Owners) != 0 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	got := Owners(rules, []string{
+		"migrations/018.sql",
+		"internal/notify/chat.go",
+		"internal/notify/email.go",
+		"cmd/server/main.go",
+	})
+	want := map[string][]string{
+		"u1": {"cmd/server/main.go"},
+		"u2": {"migrations/018.sql"},
+		"u3": {"migrations/018.sql"},
+		"u4": {"internal/notify/chat.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, text := range []string{
+		"[Backend]\n*.go @u1",
+		"*.go @org/team",
+		"*.go user@example.com",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		} else if pe, ok := err.(*ParseError); !ok || pe.Line < 1 {
+			t.Errorf("expected ParseError with line for %q, got %v", text, err)
+		}
+	}
+}
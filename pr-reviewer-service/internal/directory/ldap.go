@@ -0,0 +1,130 @@
+// Package directory читает состав команд из внешнего каталога пользователей.
+package directory
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// LDAP читает группы groupOfNames: cn группы — имя команды, member — DN её участников.
+// У записей участников user_id берётся из атрибута UserIDAttr, имя — из UsernameAttr.
+type LDAP struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	// GroupBaseDN — поддерево, в котором ищутся группы.
+	GroupBaseDN string
+	// GroupNames — cn синхронизируемых групп.
+	GroupNames   []string
+	UserIDAttr   string
+	UsernameAttr string
+	Timeout      time.Duration
+}
+
+// Groups возвращает группы в порядке GroupNames. Если какой-то группы в каталоге нет, возвращается ошибка:
+// иначе её участники были бы деактивированы. Участники без записи или без user_id пропускаются.
+func (d *LDAP) Groups(ctx context.Context) ([]model.DirectoryGroup, error) {
+	if len(d.GroupNames) == 0 {
+		return nil, nil
+	}
+	conn, err := ldap.DialURL(d.URL, ldap.DialWithDialer(&net.Dialer{Timeout: d.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, conn.Close)
+	defer stop()
+	if d.Timeout > 0 {
+		conn.SetTimeout(d.Timeout)
+	}
+	if d.BindDN != "" {
+		if err := conn.Bind(d.BindDN, d.BindPassword); err != nil {
+			return nil, ctxErr(ctx, err)
+		}
+	}
+
+	var names strings.Builder
+	for _, g := range d.GroupNames {
+		names.WriteString("(cn=" + ldap.EscapeFilter(g) + ")")
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(d.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		"(&(objectClass=groupOfNames)(|"+names.String()+"))", []string{"cn", "member"}, nil))
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	members := make(map[string][]string, len(res.Entries))
+	for _, e := range res.Entries {
+		members[strings.ToLower(e.GetAttributeValue("cn"))] = e.GetAttributeValues("member")
+	}
+
+	users := make(map[string]*model.DirectoryUser)
+	groups := make([]model.DirectoryGroup, 0, len(d.GroupNames))
+	for _, name := range d.GroupNames {
+		dns, ok := members[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("ldap group %q not found", name)
+		}
+		g := model.DirectoryGroup{TeamName: name}
+		for _, dn := range dns {
+			u, seen := users[dn]
+			if !seen {
+				if u, err = d.user(conn, dn); err != nil {
+					return nil, ctxErr(ctx, err)
+				}
+				users[dn] = u
+			}
+			if u != nil {
+				g.Members = append(g.Members, *u)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// user читает запись участника; для удалённой записи или записи без user_id возвращает nil.
+func (d *LDAP) user(conn *ldap.Conn, dn string) (*model.DirectoryUser, error) {
+	idAttr, nameAttr := attr(d.UserIDAttr, "uid"), attr(d.UsernameAttr, "cn")
+	res, err := conn.Search(ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		"(objectClass=*)", []string{idAttr, nameAttr}, nil))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Entries) == 0 {
+		return nil, nil
+	}
+	e := res.Entries[0]
+	u := model.DirectoryUser{UserID: e.GetAttributeValue(idAttr), Username: e.GetAttributeValue(nameAttr)}
+	if u.UserID == "" {
+		return nil, nil
+	}
+	if u.Username == "" {
+		u.Username = u.UserID
+	}
+	return &u, nil
+}
+
+func attr(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// ctxErr возвращает ошибку контекста, если соединение закрыто из-за его отмены.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
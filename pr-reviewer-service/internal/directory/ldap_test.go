@@ -0,0 +1,234 @@
+package directory
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// fakeLDAP — LDAP-сервер в памяти: простой bind, поиск с фильтрами and/or/not/равенство/наличие
+// по областям base и subtree.
+type fakeLDAP struct {
+	ln       net.Listener
+	bindDN   string
+	password string
+	entries  []fakeEntry
+	searches atomic.Int32
+}
+
+type fakeEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+func startLDAP(t *testing.T, entries []fakeEntry) *fakeLDAP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeLDAP{ln: ln, bindDN: "cn=sync,dc=example,dc=com", password: "secret", entries: entries}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeLDAP) url() string { return "ldap://" + s.ln.Addr().String() }
+
+func (s *fakeLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id := p.Children[0].Value.(int64)
+		op := p.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := uint16(ldap.LDAPResultSuccess)
+			if op.Children[1].Value.(string) != s.bindDN || op.Children[2].Data.String() != s.password {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			s.write(conn, id, result(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			s.searches.Add(1)
+			base := strings.ToLower(op.Children[0].Value.(string))
+			scope := op.Children[1].Value.(int64)
+			var attrs []string
+			for _, a := range op.Children[7].Children {
+				attrs = append(attrs, a.Value.(string))
+			}
+			found := false
+			for _, e := range s.entries {
+				dn := strings.ToLower(e.dn)
+				inScope := dn == base || (scope == ldap.ScopeWholeSubtree && strings.HasSuffix(dn, ","+base))
+				if dn == base {
+					found = true
+				}
+				if inScope && match(e, op.Children[6]) {
+					s.write(conn, id, entryPacket(e, attrs))
+				}
+			}
+			code := uint16(ldap.LDAPResultSuccess)
+			if !found {
+				code = ldap.LDAPResultNoSuchObject
+			}
+			s.write(conn, id, result(ldap.ApplicationSearchResultDone, code))
+		default:
+			return
+		}
+	}
+}
+
+func (s *fakeLDAP) write(conn net.Conn, id int64, op *ber.Packet) {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	p.AppendChild(op)
+	conn.Write(p.Bytes())
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return p
+}
+
+func entryPacket(e fakeEntry, attrs []string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for _, name := range attrs {
+		values, ok := e.attrs[name]
+		if !ok {
+			continue
+		}
+		a := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		a.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		a.AppendChild(set)
+		list.AppendChild(a)
+	}
+	p.AppendChild(list)
+	return p
+}
+
+func match(e fakeEntry, f *ber.Packet) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !match(e, c) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if match(e, c) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !match(e, f.Children[0])
+	case ldap.FilterEqualityMatch:
+		want := f.Children[1].Value.(string)
+		for _, v := range e.attrs[f.Children[0].Value.(string)] {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		name := f.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(e.attrs[name]) > 0
+	}
+	return false
+}
+
+var directoryEntries = []fakeEntry{
+	{dn: "ou=groups,dc=example,dc=com", attrs: map[string][]string{"objectClass": {"organizationalUnit"}}},
+	{dn: "cn=backend,ou=groups,dc=example,dc=com", attrs: map[string][]string{
+		"objectClass": {"groupOfNames"}, "cn": {"backend"},
+		"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+	}},
+	{dn: "cn=frontend,ou=groups,dc=example,dc=com", attrs: map[string][]string{
+		"objectClass": {"groupOfNames"}, "cn": {"frontend"},
+		"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=ghost,ou=people,dc=example,dc=com"},
+	}},
+	{dn: "cn=sales,ou=groups,dc=example,dc=com", attrs: map[string][]string{
+		"objectClass": {"groupOfNames"}, "cn": {"sales"},
+		"member": {"uid=carol,ou=people,dc=example,dc=com"},
+	}},
+	{dn: "uid=alice,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"u1"}, "cn": {"Alice"}}},
+	{dn: "uid=bob,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"u2"}}},
+	{dn: "uid=carol,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"u3"}, "cn": {"Carol"}}},
+}
+
+func newLDAP(s *fakeLDAP, groups ...string) *LDAP {
+	return &LDAP{
+		URL:          s.url(),
+		BindDN:       s.bindDN,
+		BindPassword: s.password,
+		GroupBaseDN:  "ou=groups,dc=example,dc=com",
+		GroupNames:   groups,
+		Timeout:      5 * time.Second,
+	}
+}
+
+func TestLDAP_Groups(t *testing.T) {
+	s := startLDAP(t, directoryEntries)
+	groups, err := newLDAP(s, "frontend", "backend").Groups(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.DirectoryGroup{
+		{TeamName: "frontend", Members: []model.DirectoryUser{{UserID: "u1", Username: "Alice"}}},
+		{TeamName: "backend", Members: []model.DirectoryUser{{UserID: "u1", Username: "Alice"}, {UserID: "u2", Username: "u2"}}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("got %+v, want %+v", groups, want)
+	}
+	// Группы одним поиском, каждый участник — один раз.
+	if n := s.searches.Load(); n != 4 {
+		t.Fatalf("searches: got %d, want 4", n)
+	}
+}
+
+func TestLDAP_MissingGroup(t *testing.T) {
+	s := startLDAP(t, directoryEntries)
+	if _, err := newLDAP(s, "backend", "mobile").Groups(context.Background()); err == nil || !strings.Contains(err.Error(), "mobile") {
+		t.Fatalf("missing group must fail, got %v", err)
+	}
+}
+
+func TestLDAP_BadCredentials(t *testing.T) {
+	s := startLDAP(t, directoryEntries)
+	d := newLDAP(s, "backend")
+	d.BindPassword = "wrong"
+	if _, err := d.Groups(context.Background()); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Fatalf("got %v", err)
+	}
+}
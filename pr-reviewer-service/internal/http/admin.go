@@ -0,0 +1,124 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pr-reviewer-service/internal/orgfile"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+)
+
+// maxImportBody — предел размера файла оргструктуры.
+const maxImportBody = 10 << 20
+
+// ImportOrg загружает оргструктуру из тела запроса (?format=csv|yaml) одной транзакцией;
+// ?dry_run=true только показывает, что изменится. Доступно только администратору (X-Admin-Token).
+func (h *Handler) ImportOrg(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "admin token required")
+		return
+	}
+	q := r.URL.Query()
+	format, err := orgfile.ParseFormat(q.Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "format must be csv or yaml")
+		return
+	}
+	dryRun := false
+	if raw := q.Get("dry_run"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "dry_run must be a boolean")
+			return
+		}
+	}
+	res, err := h.teams.Import(r.Context(), io.LimitReader(r.Body, maxImportBody), format, dryRun)
+	if err != nil {
+		var invalid *service.ImportError
+		switch {
+		case errors.As(err, &invalid):
+			writeErrorDetails(w, http.StatusBadRequest, CodeInvalidImport, "import has invalid rows", invalid.Details())
+		case err == repository.ErrTeamCycle:
+			writeError(w, http.StatusConflict, CodeNotFound, "team hierarchy changed concurrently and would form a cycle")
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusConflict, CodeNotFound, "parent team was deleted concurrently")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// ExportOrg выгружает всю оргструктуру в формате ?format=csv|yaml (по умолчанию csv).
+// Доступно только администратору (X-Admin-Token).
+func (h *Handler) ExportOrg(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "admin token required")
+		return
+	}
+	format := orgfile.CSV
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		var err error
+		if format, err = orgfile.ParseFormat(raw); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "format must be csv or yaml")
+			return
+		}
+	}
+	// Выгрузка собирается целиком до ответа, чтобы ошибка не оборвала уже начатое тело.
+	var buf bytes.Buffer
+	if err := h.teams.Export(r.Context(), &buf, format); err != nil {
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// SyncDirectory сразу синхронизирует пользователей и команды с каталогом (LDAP);
+// ?dry_run=true только показывает план. Доступно только администратору (X-Admin-Token).
+func (h *Handler) SyncDirectory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "admin token required")
+		return
+	}
+	if h.dirSync == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeNotFound, "directory sync is not configured")
+		return
+	}
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "dry_run must be a boolean")
+			return
+		}
+	}
+	res, err := h.dirSync.Sync(r.Context(), dryRun)
+	if err != nil {
+		switch err {
+		case service.ErrEmptyDirectory:
+			writeError(w, http.StatusBadGateway, CodeNotFound, "directory groups are empty, nothing synchronized")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -8,26 +8,42 @@ import (
 type errorCode string
 
 const (
-	CodeTeamExists  errorCode = "TEAM_EXISTS"
-	CodePRExists    errorCode = "PR_EXISTS"
-	CodePRMerged    errorCode = "PR_MERGED"
-	CodeNotAssigned errorCode = "NOT_ASSIGNED"
-	CodeNoCandidate errorCode = "NO_CANDIDATE"
-	CodeNotFound    errorCode = "NOT_FOUND"
+	CodeTeamExists         errorCode = "TEAM_EXISTS"
+	CodePRExists           errorCode = "PR_EXISTS"
+	CodePRMerged           errorCode = "PR_MERGED"
+	CodeNotAssigned        errorCode = "NOT_ASSIGNED"
+	CodeNoCandidate        errorCode = "NO_CANDIDATE"
+	CodeNotFound           errorCode = "NOT_FOUND"
+	CodeNotEnoughReviewers errorCode = "NOT_ENOUGH_REVIEWERS"
+	CodeMergeBlocked       errorCode = "MERGE_BLOCKED"
+	CodeForbidden          errorCode = "FORBIDDEN"
+	CodeInvalidTransition  errorCode = "INVALID_TRANSITION"
+	CodePRNotOpen          errorCode = "PR_NOT_OPEN"
+	CodeInvalidSignature   errorCode = "INVALID_SIGNATURE"
+	CodeAlreadyMember      errorCode = "ALREADY_MEMBER"
+	CodeTeamNotEmpty       errorCode = "TEAM_NOT_EMPTY"
+	CodeTeamInUse          errorCode = "TEAM_IN_USE"
+	CodeInvalidImport      errorCode = "INVALID_IMPORT"
 )
 
 type errorResponse struct {
 	Error struct {
 		Code    errorCode `json:"code"`
 		Message string    `json:"message"`
+		Details []string  `json:"details,omitempty"`
 	} `json:"error"`
 }
 
 func writeError(w http.ResponseWriter, status int, code errorCode, msg string) {
+	writeErrorDetails(w, status, code, msg, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code errorCode, msg string, details []string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	var resp errorResponse
 	resp.Error.Code = code
 	resp.Error.Message = msg
+	resp.Error.Details = details
 	_ = json.NewEncoder(w).Encode(resp)
 }
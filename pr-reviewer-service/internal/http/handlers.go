@@ -1,8 +1,15 @@
 package http
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"pr-reviewer-service/internal/model"
 	"pr-reviewer-service/internal/repository"
@@ -13,89 +20,964 @@ type Handler struct {
 	teams *service.TeamsService
 	users *service.UsersService
 	prs   *service.PRService
+
+	adminToken string
+
+	forge        *service.ForgeService
+	githubSecret string
+	gitlabToken  string
+
+	subs *service.SubscriptionsService
+
+	dirSync *service.DirectorySync
 }
 
 func NewHandler(teams *service.TeamsService, users *service.UsersService, prs *service.PRService) *Handler {
 	return &Handler{teams: teams, users: users, prs: prs}
 }
 
+// WithAdminToken включает административные операции для запросов с заголовком X-Admin-Token.
+func (h *Handler) WithAdminToken(token string) *Handler {
+	h.adminToken = token
+	return h
+}
+
+// WithGitHubWebhook включает приём вебхуков GitHub, подписанных секретом secret.
+func (h *Handler) WithGitHubWebhook(forge *service.ForgeService, secret string) *Handler {
+	h.forge = forge
+	h.githubSecret = secret
+	return h
+}
+
+// WithGitLabWebhook включает приём вебхуков GitLab с токеном token (X-Gitlab-Token).
+func (h *Handler) WithGitLabWebhook(forge *service.ForgeService, token string) *Handler {
+	h.forge = forge
+	h.gitlabToken = token
+	return h
+}
+
+// WithSubscriptions включает управление подписками на исходящие вебхуки.
+func (h *Handler) WithSubscriptions(subs *service.SubscriptionsService) *Handler {
+	h.subs = subs
+	return h
+}
+
+// WithDirectorySync включает ручной запуск синхронизации с каталогом пользователей.
+func (h *Handler) WithDirectorySync(sync *service.DirectorySync) *Handler {
+	h.dirSync = sync
+	return h
+}
+
+func (h *Handler) isAdmin(r *http.Request) bool {
+	if h.adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(h.adminToken)) == 1
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status":"ok"}`))
 }
 
-func (h *Handler) AddTeam(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var t model.Team
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	team, err := h.teams.Create(r.Context(), t)
+	if err != nil {
+		var exist *repository.MembersExistError
+		switch {
+		case err == repository.ErrTeamExists:
+			writeError(w, http.StatusBadRequest, CodeTeamExists, "team_name already exists")
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "parent_team not found")
+		case errors.As(err, &exist):
+			writeErrorDetails(w, http.StatusConflict, CodeAlreadyMember, "users are already members of team_name", exist.IDs)
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"team": team})
+}
+
+// SetTeamParent переносит команду под другую; пустой parent_team делает её командой верхнего уровня.
+func (h *Handler) SetTeamParent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName   string `json:"team_name"`
+		ParentTeam string `json:"parent_team"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	team, err := h.teams.SetParent(r.Context(), req.TeamName, req.ParentTeam)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case repository.ErrTeamCycle:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "parent_team would create a cycle")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
+}
+
+// AddTeamMembers добавляет участников в команду: новые пользователи заводятся с ней как с основной,
+// уже заведённые начинают ревьюить и в ней (review_weight — их вес в этой команде).
+func (h *Handler) AddTeamMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req model.Team
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	team, err := h.teams.AddMembers(r.Context(), req.TeamName, req.Members)
+	if err != nil {
+		var exist *repository.MembersExistError
+		switch {
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case err == service.ErrInvalidMembers:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "members with user_id are required")
+		case errors.As(err, &exist):
+			writeErrorDetails(w, http.StatusConflict, CodeAlreadyMember, "users are already members of team_name", exist.IDs)
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
+}
+
+// RemoveTeamMember убирает пользователя из команды; reassign_open_reviews передаёт другим его PENDING-ревью
+// в PR этой команды.
+func (h *Handler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName string `json:"team_name"`
+		UserID   string `json:"user_id"`
+		Reassign bool   `json:"reassign_open_reviews"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name and user_id are required")
+		return
+	}
+	res, err := h.prs.RemoveMember(r.Context(), req.TeamName, req.UserID, req.Reassign)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrNotMember:
+			writeError(w, http.StatusNotFound, CodeNotFound, "user is not a member of team_name")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// MoveTeamMember меняет основную команду пользователя; reassign_open_reviews передаёт другим его
+// PENDING-ревью в PR прежней основной команды.
+func (h *Handler) MoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID   string `json:"user_id"`
+		TeamName string `json:"team_name"`
+		Reassign bool   `json:"reassign_open_reviews"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name and user_id are required")
+		return
+	}
+	res, err := h.prs.MoveMember(r.Context(), req.UserID, req.TeamName, req.Reassign)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrAlreadyMember:
+			writeError(w, http.StatusConflict, CodeAlreadyMember, "user is already a member of team_name")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// SetTeamMemberWeight задаёт вес участника в команде для стратегии weighted; null — общий вес пользователя.
+func (h *Handler) SetTeamMemberWeight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName     string `json:"team_name"`
+		UserID       string `json:"user_id"`
+		ReviewWeight *int   `json:"review_weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name and user_id are required")
+		return
+	}
+	if err := h.teams.SetMemberWeight(r.Context(), req.TeamName, req.UserID, req.ReviewWeight); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "user is not a member of team_name")
+		case service.ErrInvalidWeight:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "review_weight must be positive")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	team, err := h.teams.Get(r.Context(), req.TeamName, nil, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
+}
+
+// RenameTeam переименовывает команду вместе с участниками и настройками.
+func (h *Handler) RenameTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName    string `json:"team_name"`
+		NewTeamName string `json:"new_team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	team, err := h.teams.Rename(r.Context(), req.TeamName, req.NewTeamName)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case repository.ErrTeamExists:
+			writeError(w, http.StatusConflict, CodeTeamExists, "new_team_name already exists")
+		case service.ErrInvalidTeamName:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "new_team_name must be non-empty and differ from team_name")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
+}
+
+// DeleteTeam удаляет команду без участников.
+func (h *Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	if err := h.teams.Delete(r.Context(), req.TeamName); err != nil {
+		var inUse *repository.TeamInUseError
+		switch {
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case err == repository.ErrTeamNotEmpty:
+			writeError(w, http.StatusConflict, CodeTeamNotEmpty, "team has members or sub-teams, remove or move them first")
+		case errors.As(err, &inUse):
+			writeErrorDetails(w, http.StatusConflict, CodeTeamInUse, "team is a fallback of other teams", inUse.Teams)
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	name := r.URL.Query().Get("team_name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	subteams, ok := includeSubteams(w, r)
+	if !ok {
+		return
+	}
+	// optional filter: ?tags=postgres,security
+	team, err := h.teams.Get(r.Context(), name, splitList(r.URL.Query().Get("tags")), subteams)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidTag:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid tag")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, team)
+}
+
+func (h *Handler) GetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	name := r.URL.Query().Get("team_name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	settings, err := h.teams.GetSettings(r.Context(), name)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"settings": redactSettings(settings)})
+}
+
+// teamSettingsView — настройки команды в ответах: URL чат-вебхука — секрет, вместо него отдаётся только признак.
+type teamSettingsView struct {
+	model.TeamSettings
+	ChatWebhookURL *string `json:"chat_webhook_url,omitempty"`
+	ChatWebhookSet bool    `json:"chat_webhook_set"`
+}
+
+func redactSettings(s model.TeamSettings) teamSettingsView {
+	return teamSettingsView{TeamSettings: s, ChatWebhookSet: s.ChatWebhookURL != ""}
+}
+
+// SetTeamSettings частично обновляет настройки: поля, которых нет в запросе, сохраняют текущие значения.
+func (h *Handler) SetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+		return
+	}
+	settings, err := h.teams.GetSettings(r.Context(), req.TeamName)
+	if err == nil {
+		chatWebhookURL := settings.ChatWebhookURL
+		if err := json.Unmarshal(body, &settings); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+			return
+		}
+		// Сервис отправляет на chat_webhook_url запросы, поэтому менять его может только администратор.
+		if settings.ChatWebhookURL != chatWebhookURL && !h.isAdmin(r) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "changing chat_webhook_url requires admin token")
+			return
+		}
+		settings, err = h.teams.UpdateSettings(r.Context(), settings)
+	}
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidSettings:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid team settings")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"settings": redactSettings(settings)})
+}
+
+// codeownersScope возвращает область правил CODEOWNERS по team_name или repository (ровно одно из них).
+func codeownersScope(team, repo string) (model.CodeownersScope, string, bool) {
+	switch {
+	case team != "" && repo == "":
+		return model.CodeownersTeam, team, true
+	case repo != "" && team == "":
+		return model.CodeownersRepository, repo, true
+	}
+	return "", "", false
+}
+
+func (h *Handler) GetCodeowners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	scope, name, ok := codeownersScope(r.URL.Query().Get("team_name"), r.URL.Query().Get("repository"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "either team_name or repository is required")
+		return
+	}
+	rules, err := h.teams.GetCodeowners(r.Context(), scope, name)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rules": rules})
+}
+
+// SetCodeowners заменяет правила CODEOWNERS команды или репозитория содержимым файла (content).
+func (h *Handler) SetCodeowners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		TeamName   string `json:"team_name"`
+		Repository string `json:"repository"`
+		Content    string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	scope, name, ok := codeownersScope(req.TeamName, req.Repository)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "either team_name or repository is required")
+		return
+	}
+	rules, err := h.teams.SetCodeowners(r.Context(), scope, name, req.Content)
+	if err != nil {
+		var unknown *repository.UnknownUsersError
+		switch {
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case errors.Is(err, service.ErrInvalidCodeowners):
+			writeError(w, http.StatusBadRequest, CodeNotFound, err.Error())
+		case errors.As(err, &unknown):
+			writeError(w, http.StatusBadRequest, CodeNotFound, unknown.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rules": rules})
+}
+
+func (h *Handler) SetUserActive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID   string `json:"user_id"`
+		IsActive bool   `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (h *Handler) SetUserMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID         string `json:"user_id"`
+		MaxOpenReviews *int   `json:"max_open_reviews"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetMaxOpenReviews(r.Context(), req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidLimit:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "max_open_reviews must be non-negative")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (h *Handler) SetUserChatHandle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID     string  `json:"user_id"`
+		ChatHandle *string `json:"chat_handle"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetChatHandle(r.Context(), req.UserID, req.ChatHandle)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (h *Handler) SetUserEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID string  `json:"user_id"`
+		Email  *string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetEmail(r.Context(), req.UserID, req.Email)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidEmail:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid email")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	id := r.URL.Query().Get("user_id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	prefs, err := h.users.GetNotificationPreferences(r.Context(), id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"preferences": prefs})
+}
+
+// SetNotificationPreferences частично обновляет настройки уведомлений, как SetTeamSettings.
+func (h *Handler) SetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	prefs, err := h.users.GetNotificationPreferences(r.Context(), req.UserID)
+	if err == nil {
+		if err := json.Unmarshal(body, &prefs); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+			return
+		}
+		prefs.UserID = req.UserID
+		prefs, err = h.users.UpdateNotificationPreferences(r.Context(), prefs)
+	}
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"preferences": prefs})
+}
+
+func (h *Handler) SetUserWorkSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		UserID       string              `json:"user_id"`
+		WorkSchedule *model.WorkSchedule `json:"work_schedule"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetWorkSchedule(r.Context(), req.UserID, req.WorkSchedule)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidSchedule:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid work schedule: IANA timezone, start/end as HH:MM and days 1-7 are required")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+// SetUserLevel задаёт уровень пользователя (junior, middle, senior, lead); null или "" убирают его.
+func (h *Handler) SetUserLevel(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
 	}
-	var t model.Team
-	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+	var req struct {
+		UserID string      `json:"user_id"`
+		Level  model.Level `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
 		return
 	}
-	team, err := h.teams.Create(r.Context(), t)
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+		return
+	}
+	user, err := h.users.SetLevel(r.Context(), req.UserID, req.Level)
 	if err != nil {
 		switch err {
-		case repository.ErrTeamExists:
-			writeError(w, http.StatusBadRequest, CodeTeamExists, "team_name already exists")
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidLevel:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "level must be one of junior, middle, senior, lead")
 		default:
 			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]any{"team": team})
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
 }
 
-func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
+// UserAvailability управляет периодами недоступности: GET ?user_id= — текущие и будущие периоды,
+// POST — добавление, PUT — изменение (по id), DELETE ?id= — удаление.
+func (h *Handler) UserAvailability(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id := r.URL.Query().Get("user_id")
+		if id == "" {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+			return
+		}
+		windows, err := h.users.ListAvailability(r.Context(), id)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"availability": windows})
+	case http.MethodPost, http.MethodPut:
+		var req model.Availability
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+			return
+		}
+		var (
+			window model.Availability
+			err    error
+			status = http.StatusCreated
+		)
+		if r.Method == http.MethodPost {
+			window, err = h.users.AddAvailability(r.Context(), req)
+		} else {
+			if req.ID == 0 {
+				writeError(w, http.StatusBadRequest, CodeNotFound, "id is required")
+				return
+			}
+			window, err = h.users.UpdateAvailability(r.Context(), req)
+			status = http.StatusOK
+		}
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			case service.ErrInvalidAvailability:
+				writeError(w, http.StatusBadRequest, CodeNotFound, "user_id, startsAt and endsAt after startsAt are required")
+			default:
+				writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			}
+			return
+		}
+		writeJSON(w, status, map[string]any{"availability": window})
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "id is required")
+			return
+		}
+		if err := h.users.DeleteAvailability(r.Context(), id); err != nil {
+			if err == repository.ErrNotFound {
+				writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+	}
+}
+
+// ListUsers возвращает пользователей с фильтрами ?team_name= и ?tags=a,b (нужны все теги).
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
 	}
-	name := r.URL.Query().Get("team_name")
-	if name == "" {
-		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
+	q := r.URL.Query()
+	users, err := h.users.List(r.Context(), q.Get("team_name"), splitList(q.Get("tags")))
+	if err != nil {
+		if err == service.ErrInvalidTag {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid tag")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"users": users})
+}
+
+// UserTags — навыки пользователя: GET ?user_id= возвращает их, POST добавляет, PUT заменяет,
+// DELETE ?user_id=&tags=a,b снимает.
+func (h *Handler) UserTags(w http.ResponseWriter, r *http.Request) {
+	var (
+		user model.User
+		err  error
+	)
+	switch r.Method {
+	case http.MethodGet, http.MethodDelete:
+		id := r.URL.Query().Get("user_id")
+		if id == "" {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+			return
+		}
+		if r.Method == http.MethodGet {
+			user, err = h.users.Get(r.Context(), id)
+		} else {
+			user, err = h.users.RemoveTags(r.Context(), id, splitList(r.URL.Query().Get("tags")))
+		}
+	case http.MethodPost, http.MethodPut:
+		var req struct {
+			UserID string   `json:"user_id"`
+			Tags   []string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+			return
+		}
+		if req.UserID == "" {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
+			return
+		}
+		if r.Method == http.MethodPost {
+			user, err = h.users.AddTags(r.Context(), req.UserID, req.Tags)
+		} else {
+			user, err = h.users.SetTags(r.Context(), req.UserID, req.Tags)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
 	}
-	team, err := h.teams.Get(r.Context(), name)
 	if err != nil {
-		if err == repository.ErrNotFound {
+		switch err {
+		case repository.ErrNotFound:
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
-			return
+		case service.ErrInvalidTag:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "tags must be non-empty: lowercase letters, digits and + # . _ -")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
-		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, team)
+	tags := user.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": user.UserID, "tags": tags})
 }
 
-func (h *Handler) SetUserActive(w http.ResponseWriter, r *http.Request) {
+// includeSubteams разбирает query-параметр include_subteams; при ошибке ответ уже записан.
+func includeSubteams(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	raw := r.URL.Query().Get("include_subteams")
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "include_subteams must be a boolean")
+		return false, false
+	}
+	return v, true
+}
+
+// splitList разбирает список через запятую из query-параметра.
+func splitList(raw string) []string {
+	var res []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func (h *Handler) LinkUserAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
 	}
-	var req struct {
-		UserID   string `json:"user_id"`
-		IsActive bool   `json:"is_active"`
-	}
+	var req model.ExternalAccount
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
 		return
 	}
-	if req.UserID == "" {
-		writeError(w, http.StatusBadRequest, CodeNotFound, "user_id is required")
-		return
-	}
-	user, err := h.users.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	acc, err := h.users.LinkAccount(r.Context(), req)
 	if err != nil {
-		if err == repository.ErrNotFound {
+		switch err {
+		case repository.ErrNotFound:
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
-			return
+		case service.ErrInvalidAccount:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "provider, login and user_id are required")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
-		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+	writeJSON(w, http.StatusOK, map[string]any{"account": acc})
 }
 
 func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
@@ -104,9 +986,14 @@ func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		ID     string `json:"pull_request_id"`
-		Name   string `json:"pull_request_name"`
-		Author string `json:"author_id"`
+		ID           string   `json:"pull_request_id"`
+		Name         string   `json:"pull_request_name"`
+		Author       string   `json:"author_id"`
+		Draft        bool     `json:"draft"`
+		Repository   string   `json:"repository"`
+		ChangedFiles []string `json:"changed_files"`
+		RequiredTags []string `json:"required_tags"`
+		TeamName     string   `json:"team_name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
@@ -116,13 +1003,30 @@ func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "missing required fields")
 		return
 	}
-	pr, err := h.prs.Create(r.Context(), req.ID, req.Name, req.Author)
+	pr, err := h.prs.Create(r.Context(), service.CreateInput{
+		ID:           req.ID,
+		Name:         req.Name,
+		AuthorID:     req.Author,
+		Draft:        req.Draft,
+		Repository:   req.Repository,
+		ChangedFiles: req.ChangedFiles,
+		RequiredTags: req.RequiredTags,
+		TeamName:     req.TeamName,
+	})
 	if err != nil {
 		switch err {
 		case repository.ErrNotFound:
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrNotMember:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "author is not a member of team_name")
 		case repository.ErrPRExists:
 			writeError(w, http.StatusConflict, CodePRExists, "PR id already exists")
+		case service.ErrNotEnoughReviewers:
+			writeError(w, http.StatusConflict, CodeNotEnoughReviewers, "not enough active reviewers for team policy")
+		case service.ErrInvalidTag:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "invalid required tag")
+		case service.ErrSeniorityUnmet:
+			writeError(w, http.StatusConflict, CodeNotEnoughReviewers, "no reviewers satisfy team seniority rules")
 		default:
 			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
@@ -132,6 +1036,70 @@ func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) MergePR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		ID     string `json:"pull_request_id"`
+		Force  bool   `json:"force"`
+		Actor  string `json:"actor"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.ID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "pull_request_id is required")
+		return
+	}
+	if req.Force {
+		if !h.isAdmin(r) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "force merge requires admin token")
+			return
+		}
+		if req.Actor == "" {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "actor is required for force merge")
+			return
+		}
+	}
+	pr, err := h.prs.Merge(r.Context(), req.ID, service.MergeOptions{Force: req.Force, Actor: req.Actor, Reason: req.Reason})
+	if err != nil {
+		var blocked *service.MergeBlockedError
+		var transition *service.InvalidTransitionError
+		switch {
+		case err == repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case errors.As(err, &blocked):
+			writeErrorDetails(w, http.StatusConflict, CodeMergeBlocked, "merge requirements are not met", blocked.Unmet)
+		case errors.As(err, &transition):
+			writeError(w, http.StatusConflict, CodeInvalidTransition, transition.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": pr})
+}
+
+func (h *Handler) ReadyPR(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prs.Ready)
+}
+
+func (h *Handler) ConvertPRToDraft(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prs.ConvertToDraft)
+}
+
+func (h *Handler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prs.Close)
+}
+
+func (h *Handler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prs.Reopen)
+}
+
+func (h *Handler) transitionPR(w http.ResponseWriter, r *http.Request, apply func(context.Context, string) (model.PullRequest, error)) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
@@ -147,13 +1115,23 @@ func (h *Handler) MergePR(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "pull_request_id is required")
 		return
 	}
-	pr, err := h.prs.Merge(r.Context(), req.ID)
+	pr, err := apply(r.Context(), req.ID)
 	if err != nil {
-		if err == repository.ErrNotFound {
+		var transition *service.InvalidTransitionError
+		switch {
+		case err == repository.ErrNotFound:
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
-			return
+		case errors.As(err, &transition):
+			writeError(w, http.StatusConflict, CodeInvalidTransition, transition.Error())
+		case err == repository.ErrStatusChanged:
+			writeError(w, http.StatusConflict, CodeInvalidTransition, "PR status changed concurrently, retry")
+		case err == service.ErrNotEnoughReviewers:
+			writeError(w, http.StatusConflict, CodeNotEnoughReviewers, "not enough active reviewers for team policy")
+		case err == service.ErrSeniorityUnmet:
+			writeError(w, http.StatusConflict, CodeNotEnoughReviewers, "no reviewers satisfy team seniority rules")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
-		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"pr": pr})
@@ -183,10 +1161,12 @@ func (h *Handler) Reassign(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
 		case service.ErrPRMerged:
 			writeError(w, http.StatusConflict, CodePRMerged, "cannot reassign on merged PR")
+		case service.ErrPRNotOpen:
+			writeError(w, http.StatusConflict, CodePRNotOpen, "reviewers can be reassigned only on OPEN PR")
 		case service.ErrNotAssigned:
 			writeError(w, http.StatusConflict, CodeNotAssigned, "reviewer is not assigned to this PR")
 		case service.ErrNoCandidate:
-			writeError(w, http.StatusConflict, CodeNoCandidate, "no active replacement candidate in team")
+			writeError(w, http.StatusConflict, CodeNoCandidate, "no active replacement candidate in team or fallback teams")
 		default:
 			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		}
@@ -198,6 +1178,74 @@ func (h *Handler) Reassign(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		PRID   string            `json:"pull_request_id"`
+		UserID string            `json:"user_id"`
+		State  model.ReviewState `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.PRID == "" || req.UserID == "" || req.State == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "missing required fields")
+		return
+	}
+	pr, err := h.prs.SubmitReview(r.Context(), req.PRID, req.UserID, req.State)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": pr})
+}
+
+func (h *Handler) DismissReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	var req struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+		return
+	}
+	if req.PRID == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "missing required fields")
+		return
+	}
+	pr, err := h.prs.DismissReview(r.Context(), req.PRID, req.UserID)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": pr})
+}
+
+func writeReviewError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrNotFound:
+		writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+	case service.ErrInvalidReviewState:
+		writeError(w, http.StatusBadRequest, CodeNotFound, "state must be APPROVED or CHANGES_REQUESTED")
+	case service.ErrPRMerged:
+		writeError(w, http.StatusConflict, CodePRMerged, "cannot review merged PR")
+	case service.ErrPRNotOpen:
+		writeError(w, http.StatusConflict, CodePRNotOpen, "only OPEN PR can be reviewed")
+	case service.ErrNotAssigned:
+		writeError(w, http.StatusConflict, CodeNotAssigned, "reviewer is not assigned to this PR")
+	default:
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+	}
+}
+
 func (h *Handler) GetReviews(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
@@ -217,25 +1265,42 @@ func (h *Handler) GetReviews(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		return
 	}
-	prs, err := h.prs.ListByReviewer(r.Context(), userID)
+	// optional filter: ?state=PENDING,CHANGES_REQUESTED
+	var states []model.ReviewState
+	if raw := r.URL.Query().Get("state"); raw != "" {
+		for _, st := range strings.Split(raw, ",") {
+			states = append(states, model.ReviewState(strings.TrimSpace(st)))
+		}
+	}
+	reviews, err := h.prs.ListByReviewer(r.Context(), userID, states)
 	if err != nil {
+		if err == service.ErrInvalidReviewState {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "unknown review state")
+			return
+		}
 		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
 		return
 	}
 	// build short form
 	type prShort struct {
-		ID     string         `json:"pull_request_id"`
-		Name   string         `json:"pull_request_name"`
-		Author string         `json:"author_id"`
-		Status model.PRStatus `json:"status"`
+		ID          string            `json:"pull_request_id"`
+		Name        string            `json:"pull_request_name"`
+		Author      string            `json:"author_id"`
+		Status      model.PRStatus    `json:"status"`
+		ReviewState model.ReviewState `json:"review_state"`
+		AssignedAt  time.Time         `json:"assignedAt"`
+		ReviewedAt  *time.Time        `json:"reviewedAt"`
 	}
 	var resp []prShort
-	for _, pr := range prs {
+	for _, rv := range reviews {
 		resp = append(resp, prShort{
-			ID:     pr.ID,
-			Name:   pr.Name,
-			Author: pr.AuthorID,
-			Status: pr.Status,
+			ID:          rv.PullRequest.ID,
+			Name:        rv.PullRequest.Name,
+			Author:      rv.PullRequest.AuthorID,
+			Status:      rv.PullRequest.Status,
+			ReviewState: rv.Review.State,
+			AssignedAt:  rv.Review.AssignedAt,
+			ReviewedAt:  rv.Review.ReviewedAt,
 		})
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
@@ -244,6 +1309,32 @@ func (h *Handler) GetReviews(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetPRHistory возвращает журнал изменений PR: назначения, замены, смены статуса, ревью.
+func (h *Handler) GetPRHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "pull_request_id is required")
+		return
+	}
+	events, err := h.prs.History(r.Context(), prID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pull_request_id": prID,
+		"events":          events,
+	})
+}
+
 func (h *Handler) ReviewerStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
@@ -259,19 +1350,76 @@ func (h *Handler) ReviewerStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ReviewerPairs — матрица пар автор→ревьювер: ?team_name= ограничивает авторов командой,
+// ?days= задаёт окно (по умолчанию pair_window_days команды).
+func (h *Handler) ReviewerPairs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	days := 0
+	if raw := q.Get("days"); raw != "" {
+		var err error
+		if days, err = strconv.Atoi(raw); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "days must be a non-negative integer")
+			return
+		}
+	}
+	subteams, ok := includeSubteams(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.prs.PairStats(r.Context(), q.Get("team_name"), days, subteams)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+		case service.ErrInvalidWindow:
+			writeError(w, http.StatusBadRequest, CodeNotFound, "days must be a non-negative integer")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *Handler) ReviewEscalations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	subteams, ok := includeSubteams(w, r)
+	if !ok {
+		return
+	}
+	escalations, err := h.prs.Escalations(r.Context(), r.URL.Query().Get("team_name"), subteams)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"escalations": escalations})
+}
+
 func (h *Handler) DeactivateTeam(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
 		return
 	}
 	var req struct {
-		TeamName string `json:"team_name"`
+		TeamName        string `json:"team_name"`
+		IncludeSubteams bool   `json:"include_subteams"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
 		writeError(w, http.StatusBadRequest, CodeNotFound, "team_name is required")
 		return
 	}
-	res, err := h.prs.DeactivateTeam(r.Context(), req.TeamName)
+	res, err := h.prs.DeactivateTeam(r.Context(), req.TeamName, req.IncludeSubteams)
 	if err != nil {
 		if err == repository.ErrNotFound {
 			writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
@@ -12,10 +12,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"testing"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 
@@ -69,13 +70,19 @@ func applyMigration(t *testing.T, db *sql.DB) {
 		time.Sleep(300 * time.Millisecond)
 	}
 	_, file, _, _ := runtime.Caller(0)
-	path := filepath.Join(filepath.Dir(file), "..", "..", "migrations", "001_init.sql")
-	schema, err := os.ReadFile(path)
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(file), "..", "..", "migrations", "*.sql"))
 	if err != nil {
-		t.Fatalf("read migration: %v", err)
+		t.Fatalf("list migrations: %v", err)
 	}
-	if _, err := db.Exec(string(schema)); err != nil {
-		t.Fatalf("apply migration: %v", err)
+	sort.Strings(paths)
+	for _, path := range paths {
+		schema, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read migration %s: %v", filepath.Base(path), err)
+		}
+		if _, err := db.Exec(string(schema)); err != nil {
+			t.Fatalf("apply migration %s: %v", filepath.Base(path), err)
+		}
 	}
 }
 
@@ -95,7 +102,7 @@ func TestIntegration_CreateReassignMerge(t *testing.T) {
 	prsRepo := repository.NewPRsRepo(db)
 	teamsSvc := service.NewTeamsService(teamsRepo)
 	usersSvc := service.NewUsersService(usersRepo)
-	prsSvc := service.NewPRService(prsRepo, usersRepo, db)
+	prsSvc := service.NewPRService(prsRepo, usersRepo, teamsRepo, db)
 	h := NewHandler(teamsSvc, usersSvc, prsSvc)
 	router := NewRouter(h)
 	ts := httptest.NewServer(router)
@@ -155,3 +162,880 @@ do(http.MethodPost, "/team/add", bytes.NewBufferString(`{
 		t.Fatalf("merge not idempotent, responses differ")
 	}
 }
+
+const testAdminToken = "test-admin-token"
+
+// newTestServer поднимает сервис поверх свежей базы в отдельном контейнере.
+func newTestServer(t *testing.T) (*httptest.Server, *sql.DB) {
+	t.Helper()
+	dsn, terminate := startPostgres(t)
+	t.Cleanup(terminate)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	applyMigration(t, db)
+
+	teamsRepo := repository.NewTeamsRepo(db)
+	usersRepo := repository.NewUsersRepo(db)
+	prsRepo := repository.NewPRsRepo(db)
+	h := NewHandler(service.NewTeamsService(teamsRepo), service.NewUsersService(usersRepo),
+		service.NewPRService(prsRepo, usersRepo, teamsRepo, db)).WithAdminToken(testAdminToken)
+	ts := httptest.NewServer(NewRouter(h))
+	t.Cleanup(ts.Close)
+	return ts, db
+}
+
+// doRequest отправляет JSON-запрос и проверяет код ответа; header — пары имя, значение.
+func doRequest(t *testing.T, ts *httptest.Server, method, path, body string, want int, header ...string) []byte {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	if res.StatusCode != want {
+		t.Fatalf("%s %s unexpected status %d body=%s", method, path, res.StatusCode, string(b))
+	}
+	return b
+}
+
+func TestIntegration_LeastLoadedAndReviewCap(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true},
+            {"user_id":"u4","username":"Dave","is_active":true}
+        ]}`, http.StatusCreated)
+
+	// три PR по два ревьювера: least_loaded раздаёт каждому ровно по два ревью
+	create := func(id string) []string {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+			fmt.Sprintf(`{"pull_request_id":%q,"pull_request_name":"load","author_id":"u1"}`, id), http.StatusCreated)
+		var created struct {
+			PR struct {
+				Assigned []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+		}
+		if err := json.Unmarshal(body, &created); err != nil {
+			t.Fatalf("decode create pr: %v", err)
+		}
+		return created.PR.Assigned
+	}
+	load := make(map[string]int)
+	for i := 1; i <= 3; i++ {
+		for _, id := range create(fmt.Sprintf("pr-load-%d", i)) {
+			load[id]++
+		}
+	}
+	if load["u2"] != 2 || load["u3"] != 2 || load["u4"] != 2 {
+		t.Fatalf("expected open reviews spread evenly, got %v", load)
+	}
+
+	// с лимитом 2 у u2 и u3 нет места, остаётся один u4
+	doRequest(t, ts, http.MethodPost, "/users/setMaxOpenReviews", `{"user_id":"u2","max_open_reviews":2}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/users/setMaxOpenReviews", `{"user_id":"u3","max_open_reviews":2}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/users/setMaxOpenReviews", `{"user_id":"u4","max_open_reviews":-1}`, http.StatusBadRequest)
+	doRequest(t, ts, http.MethodPost, "/users/setMaxOpenReviews", `{"user_id":"nobody","max_open_reviews":1}`, http.StatusNotFound)
+	if got := create("pr-load-4"); len(got) != 1 || got[0] != "u4" {
+		t.Fatalf("capped reviewers must be skipped, got %v", got)
+	}
+
+	// смерженные PR не считаются в нагрузку
+	for i := 1; i <= 3; i++ {
+		doRequest(t, ts, http.MethodPost, "/pullRequest/merge",
+			fmt.Sprintf(`{"pull_request_id":"pr-load-%d"}`, i), http.StatusOK)
+	}
+	got := create("pr-load-5")
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u3" {
+		t.Fatalf("expected the freed u2 and u3, got %v", got)
+	}
+}
+
+func TestIntegration_ReviewerCountPolicies(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"docs",
+        "members":[
+            {"user_id":"d1","username":"Alice","is_active":true},
+            {"user_id":"d2","username":"Bob","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"ops",
+        "members":[
+            {"user_id":"o1","username":"Oscar","is_active":true},
+            {"user_id":"o2","username":"Olga","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"docs","reviewer_count":2,"min_reviewers":3}`, http.StatusBadRequest)
+
+	type createResponse struct {
+		PR struct {
+			Assigned     []string `json:"assigned_reviewers"`
+			Understaffed bool     `json:"understaffed"`
+		} `json:"pr"`
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	create := func(id string, want int) createResponse {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+			fmt.Sprintf(`{"pull_request_id":%q,"pull_request_name":"docs","author_id":"d1"}`, id), want)
+		var res createResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decode create pr: %v", err)
+		}
+		return res
+	}
+
+	// в docs, кроме автора, один кандидат, а нужно минимум два
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"docs","reviewer_count":3,"min_reviewers":2,"understaffed_policy":"reject"}`, http.StatusOK)
+	if res := create("pr-docs-1", http.StatusConflict); res.Error.Code != "NOT_ENOUGH_REVIEWERS" {
+		t.Fatalf("reject policy: got code %q", res.Error.Code)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"docs","understaffed_policy":"understaffed"}`, http.StatusOK)
+	res := create("pr-docs-1", http.StatusCreated)
+	if !res.PR.Understaffed || len(res.PR.Assigned) != 1 || res.PR.Assigned[0] != "d2" {
+		t.Fatalf("understaffed policy: got %+v", res.PR)
+	}
+
+	// fallback добирает недостающих из других команд
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"docs","understaffed_policy":"fallback"}`, http.StatusOK)
+	res = create("pr-docs-2", http.StatusCreated)
+	if res.PR.Understaffed || len(res.PR.Assigned) != 3 {
+		t.Fatalf("fallback policy: got %+v", res.PR)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"docs","reviewer_count":1,"min_reviewers":1}`, http.StatusOK)
+	res = create("pr-docs-3", http.StatusCreated)
+	if res.PR.Understaffed || len(res.PR.Assigned) != 1 || res.PR.Assigned[0] != "d2" {
+		t.Fatalf("reviewer_count 1: got %+v", res.PR)
+	}
+}
+
+func TestIntegration_FallbackTeams(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":false}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"platform",
+        "members":[
+            {"user_id":"p1","username":"Pat","is_active":true},
+            {"user_id":"p2","username":"Peg","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"infra",
+        "members":[
+            {"user_id":"i1","username":"Ivan","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","fallback_teams":["backend"]}`, http.StatusBadRequest)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","fallback_teams":["platform","platform"]}`, http.StatusBadRequest)
+
+	type createResponse struct {
+		PR struct {
+			Understaffed bool `json:"understaffed"`
+			Reviewers    []struct {
+				UserID   string `json:"user_id"`
+				Fallback bool   `json:"fallback"`
+			} `json:"reviewers"`
+		} `json:"pr"`
+	}
+	create := func(id string) createResponse {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+			fmt.Sprintf(`{"pull_request_id":%q,"pull_request_name":"fallback","author_id":"u1"}`, id), http.StatusCreated)
+		var res createResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decode create pr: %v", err)
+		}
+		return res
+	}
+
+	// запасные команды обходятся по порядку: до infra очередь не доходит
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","reviewer_count":2,"fallback_teams":["platform","infra"]}`, http.StatusOK)
+	res := create("pr-fb-1")
+	if len(res.PR.Reviewers) != 2 {
+		t.Fatalf("expected two reviewers, got %+v", res.PR.Reviewers)
+	}
+	for _, r := range res.PR.Reviewers {
+		if !r.Fallback || (r.UserID != "p1" && r.UserID != "p2") {
+			t.Fatalf("expected fallback reviewers from platform, got %+v", res.PR.Reviewers)
+		}
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","reviewer_count":3}`, http.StatusOK)
+	if res = create("pr-fb-2"); len(res.PR.Reviewers) != 3 || res.PR.Understaffed {
+		t.Fatalf("expected platform and infra reviewers, got %+v", res.PR)
+	}
+
+	// замена ищется в команде снятого ревьювера
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","reviewer_count":1}`, http.StatusOK)
+	res = create("pr-fb-3")
+	first := res.PR.Reviewers[0].UserID
+	body := doRequest(t, ts, http.MethodPost, "/pullRequest/reassign",
+		fmt.Sprintf(`{"pull_request_id":"pr-fb-3","old_user_id":%q}`, first), http.StatusOK)
+	var reassigned struct {
+		ReplacedBy string `json:"replaced_by"`
+	}
+	if err := json.Unmarshal(body, &reassigned); err != nil {
+		t.Fatalf("decode reassign: %v", err)
+	}
+	if reassigned.ReplacedBy == first || (reassigned.ReplacedBy != "p1" && reassigned.ReplacedBy != "p2") {
+		t.Fatalf("expected the other platform member, got %q", reassigned.ReplacedBy)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","fallback_teams":[]}`, http.StatusOK)
+	if res = create("pr-fb-4"); len(res.PR.Reviewers) != 0 || !res.PR.Understaffed {
+		t.Fatalf("expected no reviewers without fallback teams, got %+v", res.PR)
+	}
+}
+
+func TestIntegration_ReviewLifecycle(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+		`{"pull_request_id":"pr-rv-1","pull_request_name":"review","author_id":"u1"}`, http.StatusCreated)
+
+	review := func(userID, state string, want int) {
+		doRequest(t, ts, http.MethodPost, "/pullRequest/review",
+			fmt.Sprintf(`{"pull_request_id":"pr-rv-1","user_id":%q,"state":%q}`, userID, state), want)
+	}
+	review("u2", "PENDING", http.StatusBadRequest)
+	review("u1", "APPROVED", http.StatusConflict)
+	review("u2", "APPROVED", http.StatusOK)
+	review("u3", "CHANGES_REQUESTED", http.StatusOK)
+
+	body := doRequest(t, ts, http.MethodPost, "/pullRequest/dismissReview",
+		`{"pull_request_id":"pr-rv-1","user_id":"u3"}`, http.StatusOK)
+	var dismissed struct {
+		PR struct {
+			Reviewers []struct {
+				UserID string `json:"user_id"`
+				State  string `json:"state"`
+			} `json:"reviewers"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(body, &dismissed); err != nil {
+		t.Fatalf("decode dismiss: %v", err)
+	}
+	states := make(map[string]string)
+	for _, r := range dismissed.PR.Reviewers {
+		states[r.UserID] = r.State
+	}
+	if states["u2"] != "APPROVED" || states["u3"] != "DISMISSED" {
+		t.Fatalf("unexpected review states %v", states)
+	}
+
+	reviews := func(query string) []string {
+		body := doRequest(t, ts, http.MethodGet, "/users/getReview?"+query, "", http.StatusOK)
+		var res struct {
+			PullRequests []struct {
+				ReviewState string     `json:"review_state"`
+				ReviewedAt  *time.Time `json:"reviewedAt"`
+			} `json:"pull_requests"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decode reviews: %v", err)
+		}
+		var got []string
+		for _, pr := range res.PullRequests {
+			if (pr.ReviewState == "PENDING") != (pr.ReviewedAt == nil) {
+				t.Fatalf("reviewedAt must be set once the review leaves PENDING: %s", body)
+			}
+			got = append(got, pr.ReviewState)
+		}
+		return got
+	}
+	if got := reviews("user_id=u2&state=APPROVED"); len(got) != 1 {
+		t.Fatalf("approved filter: got %v", got)
+	}
+	if got := reviews("user_id=u2&state=PENDING,CHANGES_REQUESTED"); len(got) != 0 {
+		t.Fatalf("pending filter must skip finished reviews, got %v", got)
+	}
+	if got := reviews("user_id=u3"); len(got) != 1 || got[0] != "DISMISSED" {
+		t.Fatalf("unfiltered reviews: got %v", got)
+	}
+	doRequest(t, ts, http.MethodGet, "/users/getReview?user_id=u2&state=DONE", "", http.StatusBadRequest)
+
+	doRequest(t, ts, http.MethodPost, "/pullRequest/merge", `{"pull_request_id":"pr-rv-1"}`, http.StatusOK)
+	review("u3", "APPROVED", http.StatusConflict)
+}
+
+func TestIntegration_PRHistory(t *testing.T) {
+	ts, db := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true},
+            {"user_id":"u4","username":"Dave","is_active":true}
+        ]}`, http.StatusCreated)
+	body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+		`{"pull_request_id":"pr-hist-1","pull_request_name":"history","author_id":"u1"}`, http.StatusCreated)
+	var created struct {
+		PR struct {
+			Assigned []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode create pr: %v", err)
+	}
+	old := created.PR.Assigned[0]
+	body = doRequest(t, ts, http.MethodPost, "/pullRequest/reassign",
+		fmt.Sprintf(`{"pull_request_id":"pr-hist-1","old_user_id":%q}`, old), http.StatusOK)
+	var reassigned struct {
+		ReplacedBy string `json:"replaced_by"`
+	}
+	if err := json.Unmarshal(body, &reassigned); err != nil {
+		t.Fatalf("decode reassign: %v", err)
+	}
+	doRequest(t, ts, http.MethodPost, "/pullRequest/review",
+		fmt.Sprintf(`{"pull_request_id":"pr-hist-1","user_id":%q,"state":"APPROVED"}`, reassigned.ReplacedBy), http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/merge", `{"pull_request_id":"pr-hist-1"}`, http.StatusOK)
+
+	body = doRequest(t, ts, http.MethodGet, "/pullRequest/history?pull_request_id=pr-hist-1", "", http.StatusOK)
+	var history struct {
+		Events []struct {
+			Type    string         `json:"event_type"`
+			UserID  string         `json:"user_id"`
+			Payload map[string]any `json:"payload"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	want := []string{"created", "reviewer_assigned", "reviewer_assigned", "reassigned", "review_submitted", "merged"}
+	if len(history.Events) != len(want) {
+		t.Fatalf("history: got %s", body)
+	}
+	for i, ev := range history.Events {
+		if ev.Type != want[i] {
+			t.Fatalf("event %d: got %q, want %q", i, ev.Type, want[i])
+		}
+	}
+	if ev := history.Events[3]; ev.UserID != reassigned.ReplacedBy || ev.Payload["old_user_id"] != old {
+		t.Fatalf("reassigned event: got %+v", ev)
+	}
+	doRequest(t, ts, http.MethodGet, "/pullRequest/history?pull_request_id=missing", "", http.StatusNotFound)
+
+	if _, err := db.Exec(`UPDATE pr_events SET user_id = NULL WHERE pull_request_id = 'pr-hist-1'`); err == nil {
+		t.Fatalf("pr_events must reject updates")
+	}
+	if _, err := db.Exec(`DELETE FROM pr_events WHERE pull_request_id = 'pr-hist-1'`); err == nil {
+		t.Fatalf("pr_events must reject deletes")
+	}
+}
+
+func TestIntegration_AvailabilityWindows(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true},
+            {"user_id":"u4","username":"Dave","is_active":true}
+        ]}`, http.StatusCreated)
+
+	now := time.Now().UTC()
+	window := func(userID string, from, to time.Time) string {
+		return fmt.Sprintf(`{"user_id":%q,"startsAt":%q,"endsAt":%q,"reason":"vacation"}`,
+			userID, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	doRequest(t, ts, http.MethodPost, "/users/availability", window("u2", now, now.Add(-time.Hour)), http.StatusBadRequest)
+	body := doRequest(t, ts, http.MethodPost, "/users/availability",
+		window("u2", now.Add(-time.Hour), now.Add(24*time.Hour)), http.StatusCreated)
+	var away struct {
+		Availability struct {
+			ID int64 `json:"id"`
+		} `json:"availability"`
+	}
+	if err := json.Unmarshal(body, &away); err != nil {
+		t.Fatalf("decode availability: %v", err)
+	}
+	// будущий отпуск u3 на выбор сейчас не влияет
+	doRequest(t, ts, http.MethodPost, "/users/availability",
+		window("u3", now.Add(48*time.Hour), now.Add(72*time.Hour)), http.StatusCreated)
+
+	create := func(id string) []string {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+			fmt.Sprintf(`{"pull_request_id":%q,"pull_request_name":"away","author_id":"u1"}`, id), http.StatusCreated)
+		var created struct {
+			PR struct {
+				Assigned []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+		}
+		if err := json.Unmarshal(body, &created); err != nil {
+			t.Fatalf("decode create pr: %v", err)
+		}
+		sort.Strings(created.PR.Assigned)
+		return created.PR.Assigned
+	}
+	if got := create("pr-away-1"); len(got) != 2 || got[0] != "u3" || got[1] != "u4" {
+		t.Fatalf("absent u2 must be skipped, got %v", got)
+	}
+
+	// закончившийся период не мешает назначению и не попадает в список
+	doRequest(t, ts, http.MethodPut, "/users/availability",
+		fmt.Sprintf(`{"id":%d,"startsAt":%q,"endsAt":%q}`, away.Availability.ID,
+			now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339)), http.StatusOK)
+	body = doRequest(t, ts, http.MethodGet, "/users/availability?user_id=u2", "", http.StatusOK)
+	var listed struct {
+		Availability []json.RawMessage `json:"availability"`
+	}
+	if err := json.Unmarshal(body, &listed); err != nil {
+		t.Fatalf("decode availability list: %v", err)
+	}
+	if len(listed.Availability) != 0 {
+		t.Fatalf("past windows must not be listed, got %s", body)
+	}
+	if got := create("pr-away-2"); len(got) != 2 || got[0] != "u2" {
+		t.Fatalf("u2 must be a candidate again, got %v", got)
+	}
+
+	path := fmt.Sprintf("/users/availability?id=%d", away.Availability.ID)
+	doRequest(t, ts, http.MethodDelete, path, "", http.StatusNoContent)
+	doRequest(t, ts, http.MethodDelete, path, "", http.StatusNotFound)
+}
+
+func TestIntegration_TeamMembershipChanges(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"platform",
+        "members":[{"user_id":"p1","username":"Pat","is_active":true}]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"tmp",
+        "members":[{"user_id":"t1","username":"Tom","is_active":true}]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings",
+		`{"team_name":"backend","reviewer_count":1,"selection_strategy":"round_robin","fallback_teams":["tmp"]}`, http.StatusOK)
+	body := doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+		`{"pull_request_id":"pr-team-1","pull_request_name":"team","author_id":"u1"}`, http.StatusCreated)
+	var created struct {
+		PR struct {
+			Assigned []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode create pr: %v", err)
+	}
+	reviewer := created.PR.Assigned[0]
+
+	doRequest(t, ts, http.MethodPost, "/team/addMembers", `{"team_name":"backend","members":[]}`, http.StatusBadRequest)
+	doRequest(t, ts, http.MethodPost, "/team/addMembers", `{"team_name":"missing","members":[
+        {"user_id":"u9","username":"Nobody","is_active":true}]}`, http.StatusNotFound)
+	doRequest(t, ts, http.MethodPost, "/team/addMembers", `{"team_name":"backend","members":[
+        {"user_id":"u4","username":"Dave","is_active":true}]}`, http.StatusOK)
+
+	// переименование переносит участников и настройки
+	doRequest(t, ts, http.MethodPost, "/team/rename", `{"team_name":"backend","new_team_name":"backend"}`, http.StatusBadRequest)
+	doRequest(t, ts, http.MethodPost, "/team/rename", `{"team_name":"backend","new_team_name":"platform"}`, http.StatusConflict)
+	doRequest(t, ts, http.MethodPost, "/team/rename", `{"team_name":"backend","new_team_name":"core"}`, http.StatusOK)
+	doRequest(t, ts, http.MethodGet, "/team/get?team_name=backend", "", http.StatusNotFound)
+	body = doRequest(t, ts, http.MethodGet, "/team/get?team_name=core", "", http.StatusOK)
+	var team struct {
+		Members []struct {
+			UserID string `json:"user_id"`
+		} `json:"members"`
+	}
+	if err := json.Unmarshal(body, &team); err != nil {
+		t.Fatalf("decode team: %v", err)
+	}
+	if len(team.Members) != 4 {
+		t.Fatalf("renamed team must keep members, got %s", body)
+	}
+	body = doRequest(t, ts, http.MethodGet, "/team/getSettings?team_name=core", "", http.StatusOK)
+	var settings struct {
+		Settings struct {
+			SelectionStrategy string   `json:"selection_strategy"`
+			FallbackTeams     []string `json:"fallback_teams"`
+		} `json:"settings"`
+	}
+	if err := json.Unmarshal(body, &settings); err != nil {
+		t.Fatalf("decode settings: %v", err)
+	}
+	if settings.Settings.SelectionStrategy != "round_robin" || len(settings.Settings.FallbackTeams) != 1 {
+		t.Fatalf("renamed team must keep settings, got %s", body)
+	}
+
+	// перевод с reassign_open_reviews отдаёт ревью оставшимся участникам
+	body = doRequest(t, ts, http.MethodPost, "/team/moveMember",
+		fmt.Sprintf(`{"user_id":%q,"team_name":"platform","reassign_open_reviews":true}`, reviewer), http.StatusOK)
+	var moved struct {
+		User struct {
+			TeamName string `json:"team_name"`
+		} `json:"user"`
+		Reassigned int `json:"reassigned"`
+	}
+	if err := json.Unmarshal(body, &moved); err != nil {
+		t.Fatalf("decode move: %v", err)
+	}
+	if moved.User.TeamName != "platform" || moved.Reassigned != 1 {
+		t.Fatalf("move: got %s", body)
+	}
+	doRequest(t, ts, http.MethodPost, "/team/moveMember",
+		fmt.Sprintf(`{"user_id":%q,"team_name":"platform"}`, reviewer), http.StatusConflict)
+	body = doRequest(t, ts, http.MethodGet, "/users/getReview?user_id="+reviewer, "", http.StatusOK)
+	var reviews struct {
+		PullRequests []json.RawMessage `json:"pull_requests"`
+	}
+	if err := json.Unmarshal(body, &reviews); err != nil {
+		t.Fatalf("decode reviews: %v", err)
+	}
+	if len(reviews.PullRequests) != 0 {
+		t.Fatalf("moved reviewer must lose the review, got %s", body)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/removeMember",
+		fmt.Sprintf(`{"team_name":"platform","user_id":%q}`, reviewer), http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/team/removeMember",
+		fmt.Sprintf(`{"team_name":"platform","user_id":%q}`, reviewer), http.StatusNotFound)
+
+	// удалить можно только пустую команду, которая не служит запасной
+	doRequest(t, ts, http.MethodPost, "/team/delete", `{"team_name":"tmp"}`, http.StatusConflict)
+	doRequest(t, ts, http.MethodPost, "/team/removeMember", `{"team_name":"tmp","user_id":"t1"}`, http.StatusOK)
+	body = doRequest(t, ts, http.MethodPost, "/team/delete", `{"team_name":"tmp"}`, http.StatusConflict)
+	var inUse struct {
+		Error struct {
+			Code    string   `json:"code"`
+			Details []string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &inUse); err != nil {
+		t.Fatalf("decode delete: %v", err)
+	}
+	if inUse.Error.Code != "TEAM_IN_USE" || len(inUse.Error.Details) != 1 || inUse.Error.Details[0] != "core" {
+		t.Fatalf("delete of a fallback team: got %s", body)
+	}
+	doRequest(t, ts, http.MethodPost, "/team/setSettings", `{"team_name":"core","fallback_teams":[]}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/team/delete", `{"team_name":"tmp"}`, http.StatusNoContent)
+	doRequest(t, ts, http.MethodGet, "/team/get?team_name=tmp", "", http.StatusNotFound)
+}
+
+func TestIntegration_MultiTeamMembership(t *testing.T) {
+	ts, db := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"frontend",
+        "members":[{"user_id":"f1","username":"Fay","is_active":true}]}`, http.StatusCreated)
+
+	// уже заведённый пользователь входит во вторую команду, его данные не перезаписываются
+	doRequest(t, ts, http.MethodPost, "/team/addMembers", `{"team_name":"frontend","members":[
+        {"user_id":"u1","username":"Renamed","is_active":false,"review_weight":3}]}`, http.StatusOK)
+	body := doRequest(t, ts, http.MethodPost, "/team/addMembers", `{"team_name":"frontend","members":[
+        {"user_id":"u1"}]}`, http.StatusConflict)
+	var exists struct {
+		Error struct {
+			Code    string   `json:"code"`
+			Details []string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &exists); err != nil {
+		t.Fatalf("decode add members: %v", err)
+	}
+	if exists.Error.Code != "ALREADY_MEMBER" || len(exists.Error.Details) != 1 || exists.Error.Details[0] != "u1" {
+		t.Fatalf("repeated add: got %s", body)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/team/setMemberWeight",
+		`{"team_name":"frontend","user_id":"u1","review_weight":0}`, http.StatusBadRequest)
+	doRequest(t, ts, http.MethodPost, "/team/setMemberWeight",
+		`{"team_name":"frontend","user_id":"u2","review_weight":2}`, http.StatusNotFound)
+	body = doRequest(t, ts, http.MethodPost, "/team/setMemberWeight",
+		`{"team_name":"frontend","user_id":"u1","review_weight":5}`, http.StatusOK)
+	var team struct {
+		Team struct {
+			Members []struct {
+				UserID       string `json:"user_id"`
+				Username     string `json:"username"`
+				IsActive     bool   `json:"is_active"`
+				ReviewWeight int    `json:"review_weight"`
+				Primary      bool   `json:"primary"`
+			} `json:"members"`
+		} `json:"team"`
+	}
+	if err := json.Unmarshal(body, &team); err != nil {
+		t.Fatalf("decode team: %v", err)
+	}
+	if len(team.Team.Members) != 2 {
+		t.Fatalf("frontend members: got %s", body)
+	}
+	for _, m := range team.Team.Members {
+		if m.UserID == "u1" && (m.Username != "Alice" || !m.IsActive || m.ReviewWeight != 5 || m.Primary) {
+			t.Fatalf("u1 in frontend: got %+v", m)
+		}
+	}
+
+	type createResponse struct {
+		PR struct {
+			TeamName string   `json:"team_name"`
+			Assigned []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	create := func(payload string) createResponse {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/create", payload, http.StatusCreated)
+		var res createResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decode create pr: %v", err)
+		}
+		return res
+	}
+	// без team_name PR идёт от основной команды автора
+	res := create(`{"pull_request_id":"pr-multi-1","pull_request_name":"multi","author_id":"u1"}`)
+	if res.PR.TeamName != "backend" || len(res.PR.Assigned) != 1 || res.PR.Assigned[0] != "u2" {
+		t.Fatalf("primary team PR: got %+v", res.PR)
+	}
+	res = create(`{"pull_request_id":"pr-multi-2","pull_request_name":"multi","author_id":"u1","team_name":"frontend"}`)
+	if res.PR.TeamName != "frontend" || len(res.PR.Assigned) != 1 || res.PR.Assigned[0] != "f1" {
+		t.Fatalf("frontend PR: got %+v", res.PR)
+	}
+	doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+		`{"pull_request_id":"pr-multi-3","pull_request_name":"multi","author_id":"f1","team_name":"backend"}`, http.StatusBadRequest)
+	// участник нескольких команд ревьюит в каждой из них
+	res = create(`{"pull_request_id":"pr-multi-4","pull_request_name":"multi","author_id":"f1"}`)
+	if len(res.PR.Assigned) != 1 || res.PR.Assigned[0] != "u1" {
+		t.Fatalf("u1 must review for frontend, got %v", res.PR.Assigned)
+	}
+
+	// команда PR переименовывается вместе с командой
+	doRequest(t, ts, http.MethodPost, "/team/rename", `{"team_name":"backend","new_team_name":"core"}`, http.StatusOK)
+	var prTeam string
+	if err := db.QueryRow(`SELECT team_name FROM pull_requests WHERE pull_request_id = 'pr-multi-1'`).Scan(&prTeam); err != nil {
+		t.Fatalf("read pr team: %v", err)
+	}
+	if prTeam != "core" {
+		t.Fatalf("pr team_name after rename: got %q", prTeam)
+	}
+
+	// выход из второй команды не трогает основную
+	doRequest(t, ts, http.MethodPost, "/team/removeMember", `{"team_name":"frontend","user_id":"u1"}`, http.StatusOK)
+	body = doRequest(t, ts, http.MethodGet, "/team/get?team_name=core", "", http.StatusOK)
+	if !bytes.Contains(body, []byte(`"u1"`)) {
+		t.Fatalf("u1 must stay in core, got %s", body)
+	}
+}
+
+func TestIntegration_MergeRules(t *testing.T) {
+	ts, db := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true}
+        ]}`, http.StatusCreated)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings", `{"team_name":"backend","required_approvals":1}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+		`{"pull_request_id":"pr-merge-1","pull_request_name":"merge","author_id":"u1"}`, http.StatusCreated)
+
+	var blocked struct {
+		Error struct {
+			Code    string   `json:"code"`
+			Details []string `json:"details"`
+		} `json:"error"`
+	}
+	merge := func(payload string, want int, header ...string) []byte {
+		body := doRequest(t, ts, http.MethodPost, "/pullRequest/merge", payload, want, header...)
+		if want == http.StatusConflict {
+			if err := json.Unmarshal(body, &blocked); err != nil {
+				t.Fatalf("decode merge: %v", err)
+			}
+		}
+		return body
+	}
+	merge(`{"pull_request_id":"pr-merge-1"}`, http.StatusConflict)
+	if blocked.Error.Code != "MERGE_BLOCKED" || len(blocked.Error.Details) != 1 {
+		t.Fatalf("expected one unmet approval, got %+v", blocked.Error)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/pullRequest/review",
+		`{"pull_request_id":"pr-merge-1","user_id":"u2","state":"APPROVED"}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/review",
+		`{"pull_request_id":"pr-merge-1","user_id":"u3","state":"CHANGES_REQUESTED"}`, http.StatusOK)
+	merge(`{"pull_request_id":"pr-merge-1"}`, http.StatusConflict)
+	if len(blocked.Error.Details) != 1 || blocked.Error.Details[0] != "changes requested by: u3" {
+		t.Fatalf("expected changes requested by u3, got %+v", blocked.Error)
+	}
+
+	force := `{"pull_request_id":"pr-merge-1","force":true,"actor":"lead","reason":"hotfix"}`
+	merge(force, http.StatusForbidden)
+	body := merge(force, http.StatusOK, "X-Admin-Token", testAdminToken)
+	var merged struct {
+		PR struct {
+			Status string `json:"status"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(body, &merged); err != nil {
+		t.Fatalf("decode merge: %v", err)
+	}
+	if merged.PR.Status != "MERGED" {
+		t.Fatalf("expected MERGED, got %s", merged.PR.Status)
+	}
+	// повторный мерж идемпотентен и второй обход не записывает
+	merge(`{"pull_request_id":"pr-merge-1"}`, http.StatusOK)
+
+	var overrides int
+	var actor string
+	var unmet []string
+	if err := db.QueryRow(`
+        SELECT COUNT(*) OVER (), actor, unmet_conditions FROM merge_overrides WHERE pull_request_id = 'pr-merge-1'
+    `).Scan(&overrides, &actor, pq.Array(&unmet)); err != nil {
+		t.Fatalf("read override: %v", err)
+	}
+	if overrides != 1 || actor != "lead" || len(unmet) != 1 {
+		t.Fatalf("override: count %d, actor %q, unmet %v", overrides, actor, unmet)
+	}
+}
+
+func TestIntegration_ChatWebhookURLRequiresAdmin(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[{"user_id":"u1","username":"Alice","is_active":true}]}`, http.StatusCreated)
+
+	set := `{"team_name":"backend","chat_webhook_url":"https://hooks.example.com/T1"}`
+	doRequest(t, ts, http.MethodPost, "/team/setSettings", set, http.StatusForbidden)
+	doRequest(t, ts, http.MethodPost, "/team/setSettings", set, http.StatusOK, "X-Admin-Token", testAdminToken)
+	// остальные настройки меняются без токена, пока URL не трогают
+	doRequest(t, ts, http.MethodPost, "/team/setSettings", `{"team_name":"backend","required_approvals":1}`, http.StatusOK)
+
+	body := doRequest(t, ts, http.MethodGet, "/team/getSettings?team_name=backend", "", http.StatusOK)
+	if bytes.Contains(body, []byte("hooks.example.com")) {
+		t.Fatalf("chat_webhook_url leaked: %s", body)
+	}
+	var got struct {
+		Settings struct {
+			ChatWebhookSet    bool `json:"chat_webhook_set"`
+			RequiredApprovals int  `json:"required_approvals"`
+		} `json:"settings"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode settings: %v", err)
+	}
+	if !got.Settings.ChatWebhookSet || got.Settings.RequiredApprovals != 1 {
+		t.Fatalf("unexpected settings %s", body)
+	}
+}
+
+func TestIntegration_ReviewAfterMerge(t *testing.T) {
+	ts, db := newTestServer(t)
+
+	doRequest(t, ts, http.MethodPost, "/team/add", `{
+        "team_name":"backend",
+        "members":[
+            {"user_id":"u1","username":"Alice","is_active":true},
+            {"user_id":"u2","username":"Bob","is_active":true},
+            {"user_id":"u3","username":"Carol","is_active":true}
+        ]}`, http.StatusCreated)
+	for _, id := range []string{"pr-late-1", "pr-late-2"} {
+		doRequest(t, ts, http.MethodPost, "/pullRequest/create",
+			fmt.Sprintf(`{"pull_request_id":%q,"pull_request_name":"late","author_id":"u1"}`, id), http.StatusCreated)
+	}
+
+	doRequest(t, ts, http.MethodPost, "/pullRequest/merge", `{"pull_request_id":"pr-late-1"}`, http.StatusOK)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/review",
+		`{"pull_request_id":"pr-late-1","user_id":"u2","state":"APPROVED"}`, http.StatusConflict)
+	doRequest(t, ts, http.MethodPost, "/pullRequest/reassign",
+		`{"pull_request_id":"pr-late-1","old_user_id":"u2"}`, http.StatusConflict)
+
+	// мерж держит строку PR, ревью успевает прочитать её ещё открытой и ждёт блокировку
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	prs := repository.NewPRsRepo(db).WithTx(tx)
+	if _, err := prs.GetForUpdate(ctx, "pr-late-2"); err != nil {
+		t.Fatalf("lock pr: %v", err)
+	}
+	if _, err := prs.Merge(ctx, "pr-late-2", nil); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Post(ts.URL+"/pullRequest/review", "application/json",
+			bytes.NewBufferString(`{"pull_request_id":"pr-late-2","user_id":"u2","state":"APPROVED"}`))
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+	select {
+	case code := <-status:
+		t.Fatalf("review must wait for the merge, got status %d", code)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit merge: %v", err)
+	}
+	if code := <-status; code != http.StatusConflict {
+		t.Fatalf("review of a PR merged under it: got status %d", code)
+	}
+
+	var state string
+	if err := db.QueryRow(`
+        SELECT state FROM pull_request_reviewers WHERE pull_request_id = 'pr-late-2' AND user_id = 'u2'
+    `).Scan(&state); err != nil {
+		t.Fatalf("read review state: %v", err)
+	}
+	if state != "PENDING" {
+		t.Fatalf("merged PR review state changed to %s", state)
+	}
+}
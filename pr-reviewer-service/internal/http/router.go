@@ -11,12 +11,50 @@ func NewRouter(h *Handler) http.Handler {
 	mux.HandleFunc("/team/add", h.AddTeam)
 	mux.HandleFunc("/team/get", h.GetTeam)
 	mux.HandleFunc("/team/deactivate", h.DeactivateTeam)
+	mux.HandleFunc("/team/addMembers", h.AddTeamMembers)
+	mux.HandleFunc("/team/removeMember", h.RemoveTeamMember)
+	mux.HandleFunc("/team/moveMember", h.MoveTeamMember)
+	mux.HandleFunc("/team/setMemberWeight", h.SetTeamMemberWeight)
+	mux.HandleFunc("/team/rename", h.RenameTeam)
+	mux.HandleFunc("/team/delete", h.DeleteTeam)
+	mux.HandleFunc("/team/setParent", h.SetTeamParent)
+	mux.HandleFunc("/team/getSettings", h.GetTeamSettings)
+	mux.HandleFunc("/team/setSettings", h.SetTeamSettings)
+	mux.HandleFunc("/codeowners/get", h.GetCodeowners)
+	mux.HandleFunc("/codeowners/set", h.SetCodeowners)
 	mux.HandleFunc("/users/setIsActive", h.SetUserActive)
+	mux.HandleFunc("/users/setMaxOpenReviews", h.SetUserMaxOpenReviews)
+	mux.HandleFunc("/users/setChatHandle", h.SetUserChatHandle)
+	mux.HandleFunc("/users/setEmail", h.SetUserEmail)
+	mux.HandleFunc("/users/getNotificationPreferences", h.GetNotificationPreferences)
+	mux.HandleFunc("/users/setNotificationPreferences", h.SetNotificationPreferences)
+	mux.HandleFunc("/users/setWorkSchedule", h.SetUserWorkSchedule)
+	mux.HandleFunc("/users/setLevel", h.SetUserLevel)
+	mux.HandleFunc("/users/availability", h.UserAvailability)
+	mux.HandleFunc("/users/list", h.ListUsers)
+	mux.HandleFunc("/users/tags", h.UserTags)
+	mux.HandleFunc("/users/linkAccount", h.LinkUserAccount)
 	mux.HandleFunc("/pullRequest/create", h.CreatePR)
 	mux.HandleFunc("/pullRequest/merge", h.MergePR)
+	mux.HandleFunc("/pullRequest/ready", h.ReadyPR)
+	mux.HandleFunc("/pullRequest/convertToDraft", h.ConvertPRToDraft)
+	mux.HandleFunc("/pullRequest/close", h.ClosePR)
+	mux.HandleFunc("/pullRequest/reopen", h.ReopenPR)
 	mux.HandleFunc("/pullRequest/reassign", h.Reassign)
+	mux.HandleFunc("/pullRequest/review", h.SubmitReview)
+	mux.HandleFunc("/pullRequest/dismissReview", h.DismissReview)
+	mux.HandleFunc("/pullRequest/history", h.GetPRHistory)
 	mux.HandleFunc("/users/getReview", h.GetReviews)
 	mux.HandleFunc("/stats/reviewerAssignments", h.ReviewerStats)
+	mux.HandleFunc("/stats/escalations", h.ReviewEscalations)
+	mux.HandleFunc("/stats/pairs", h.ReviewerPairs)
+	mux.HandleFunc("/webhooks/github", h.GitHubWebhook)
+	mux.HandleFunc("/webhooks/gitlab", h.GitLabWebhook)
+	mux.HandleFunc("/webhooks/subscriptions", h.WebhookSubscriptions)
+	mux.HandleFunc("/webhooks/deliveries", h.WebhookDeliveries)
+	mux.HandleFunc("/admin/import", h.ImportOrg)
+	mux.HandleFunc("/admin/export", h.ExportOrg)
+	mux.HandleFunc("/admin/directorySync", h.SyncDirectory)
 
 	return mux
 }
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+	"pr-reviewer-service/internal/service"
+	"pr-reviewer-service/internal/webhook"
+)
+
+// maxWebhookBody — предел размера тела вебхука (GitHub присылает до 25 МБ).
+const maxWebhookBody = 25 << 20
+
+// GitHubWebhook принимает события pull_request от GitHub и создаёт/переводит PR через сервис.
+// Запрос должен быть подписан секретом вебхука (X-Hub-Signature-256).
+func (h *Handler) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if h.forge == nil || h.githubSecret == "" {
+		writeError(w, http.StatusServiceUnavailable, CodeForbidden, "github webhook is not configured")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "cannot read body")
+		return
+	}
+	if !webhook.VerifyGitHubSignature(h.githubSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, CodeInvalidSignature, "invalid webhook signature")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "ping" {
+		writeJSON(w, http.StatusOK, map[string]any{"result": "pong"})
+		return
+	}
+	ev, err := webhook.ParseGitHub(event, body)
+	if err != nil {
+		if err == webhook.ErrUnsupported {
+			writeJSON(w, http.StatusAccepted, map[string]any{"result": "ignored", "reason": "unsupported event"})
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad payload")
+		return
+	}
+	h.applyForgeEvent(w, r, ev)
+}
+
+// GitLabWebhook принимает Merge Request Hook от GitLab; запрос должен нести токен вебхука (X-Gitlab-Token).
+func (h *Handler) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if h.forge == nil || h.gitlabToken == "" {
+		writeError(w, http.StatusServiceUnavailable, CodeForbidden, "gitlab webhook is not configured")
+		return
+	}
+	if !webhook.VerifyGitLabToken(h.gitlabToken, r.Header.Get("X-Gitlab-Token")) {
+		writeError(w, http.StatusUnauthorized, CodeInvalidSignature, "invalid webhook token")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, "cannot read body")
+		return
+	}
+	ev, err := webhook.ParseGitLab(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		if err == webhook.ErrUnsupported {
+			writeJSON(w, http.StatusAccepted, map[string]any{"result": "ignored", "reason": "unsupported event"})
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeNotFound, "bad payload")
+		return
+	}
+	h.applyForgeEvent(w, r, ev)
+}
+
+func (h *Handler) applyForgeEvent(w http.ResponseWriter, r *http.Request, ev model.ForgeEvent) {
+	outcome, pr, err := h.forge.Apply(r.Context(), ev)
+	if err != nil {
+		var transition *service.InvalidTransitionError
+		switch {
+		case err == service.ErrUnknownAccount:
+			writeJSON(w, http.StatusAccepted, map[string]any{
+				"result": "ignored",
+				"reason": "author " + ev.AuthorLogin + " is not linked to a user",
+			})
+		case err == repository.ErrNotFound:
+			writeJSON(w, http.StatusAccepted, map[string]any{
+				"result": "ignored",
+				"reason": "unknown pull request " + ev.PullRequestID(),
+			})
+		case errors.As(err, &transition):
+			writeError(w, http.StatusConflict, CodeInvalidTransition, transition.Error())
+		case err == repository.ErrStatusChanged:
+			writeError(w, http.StatusConflict, CodeInvalidTransition, "PR status changed concurrently, retry")
+		case err == service.ErrNotEnoughReviewers:
+			writeError(w, http.StatusConflict, CodeNotEnoughReviewers, "not enough active reviewers for team policy")
+		default:
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": outcome, "pr": pr})
+}
+
+// WebhookSubscriptions управляет подписками на исходящие события: GET — список, POST — создание,
+// DELETE ?id= — удаление. Доступно только администратору (X-Admin-Token).
+func (h *Handler) WebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if h.subs == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeForbidden, "outgoing webhooks are not configured")
+		return
+	}
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "admin token required")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		subs, err := h.subs.List(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subs})
+	case http.MethodPost:
+		var req model.WebhookSubscription
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad json")
+			return
+		}
+		sub, err := h.subs.Create(r.Context(), req)
+		if err != nil {
+			if err == service.ErrInvalidSubscription {
+				writeError(w, http.StatusBadRequest, CodeNotFound, "url (http/https), secret and known topics are required")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"subscription": sub})
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "id is required")
+			return
+		}
+		if err := h.subs.Delete(r.Context(), id); err != nil {
+			if err == repository.ErrNotFound {
+				writeError(w, http.StatusNotFound, CodeNotFound, "resource not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+	}
+}
+
+// WebhookDeliveries возвращает журнал доставок исходящих вебхуков (?subscription_id=, ?status=).
+func (h *Handler) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeNotFound, "method not allowed")
+		return
+	}
+	if h.subs == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeForbidden, "outgoing webhooks are not configured")
+		return
+	}
+	if !h.isAdmin(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "admin token required")
+		return
+	}
+	var subID int64
+	if raw := r.URL.Query().Get("subscription_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, "bad subscription_id")
+			return
+		}
+		subID = id
+	}
+	status := model.DeliveryStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed:
+	default:
+		writeError(w, http.StatusBadRequest, CodeNotFound, "unknown delivery status")
+		return
+	}
+	deliveries, err := h.subs.Deliveries(r.Context(), subID, status)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
@@ -1,9 +1,17 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type Team struct {
-	TeamName string       `json:"team_name"`
+	TeamName string `json:"team_name"`
+	// ParentTeam — родительская команда (отдел); пустая — команда верхнего уровня.
+	ParentTeam string `json:"parent_team,omitempty"`
+	// Subteams — прямые подкоманды (только для чтения).
+	Subteams []string     `json:"subteams,omitempty"`
 	Members  []TeamMember `json:"members"`
 }
 
@@ -11,34 +19,590 @@ type TeamMember struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	IsActive bool   `json:"is_active"`
+	// ReviewWeight используется стратегией weighted; 0 означает вес по умолчанию (1).
+	// Для уже заведённого пользователя — его вес в этой команде.
+	ReviewWeight int `json:"review_weight,omitempty"`
+	// Primary — команда для участника основная (только для чтения).
+	Primary bool `json:"primary,omitempty"`
+	// TeamName — подкоманда, в которой состоит участник (только в выборке с подкомандами).
+	TeamName string `json:"team_name,omitempty"`
+	// MaxOpenReviews ограничивает число одновременных открытых ревью; nil — без ограничения.
+	MaxOpenReviews *int `json:"max_open_reviews,omitempty"`
+	// Tags — навыки участника (только для чтения, меняются через /users/tags).
+	Tags []string `json:"tags,omitempty"`
+	// Level — уровень участника (только для чтения, меняется через /users/setLevel).
+	Level Level `json:"level,omitempty"`
 }
 
 type User struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	// TeamName — основная команда пользователя; Teams — все команды, где он ревьюит (включая основную).
+	TeamName       string   `json:"team_name"`
+	Teams          []string `json:"teams,omitempty"`
+	IsActive       bool     `json:"is_active"`
+	MaxOpenReviews *int     `json:"max_open_reviews,omitempty"`
+	// ChatHandle — идентификатор в чате для упоминаний: member ID Slack (U…) или логин Mattermost.
+	ChatHandle *string `json:"chat_handle,omitempty"`
+	Email      *string `json:"email,omitempty"`
+	// WorkSchedule — рабочие часы; nil — пользователь доступен в любое время.
+	WorkSchedule *WorkSchedule `json:"work_schedule,omitempty"`
+	// Tags — навыки пользователя, по ним покрываются обязательные теги PR.
+	Tags []string `json:"tags,omitempty"`
+	// Level — уровень пользователя; пустой — не задан.
+	Level Level `json:"level,omitempty"`
+}
+
+// Level — уровень пользователя для правил команды о составе ревьюверов.
+type Level string
+
+const (
+	LevelJunior Level = "junior"
+	LevelMiddle Level = "middle"
+	LevelSenior Level = "senior"
+	LevelLead   Level = "lead"
+)
+
+func (l Level) Valid() bool {
+	switch l {
+	case LevelJunior, LevelMiddle, LevelSenior, LevelLead:
+		return true
+	}
+	return false
+}
+
+// Senior сообщает, засчитывается ли уровень в min_senior_reviewers (senior и lead).
+func (l Level) Senior() bool {
+	return l == LevelSenior || l == LevelLead
+}
+
+// NotificationPreferences — какие email-уведомления получает пользователь.
+type NotificationPreferences struct {
+	UserID string `json:"user_id"`
+	// EmailAssigned — письмо о назначении ревьювером.
+	EmailAssigned bool `json:"email_assigned"`
+	// EmailReplaced — письмо о замене на другого ревьювера.
+	EmailReplaced bool `json:"email_replaced"`
+	// EmailReminder — напоминание о ждущем ревью.
+	EmailReminder bool `json:"email_reminder"`
+}
+
+// DefaultNotificationPreferences — настройки пользователя, который их не менял.
+func DefaultNotificationPreferences(userID string) NotificationPreferences {
+	return NotificationPreferences{UserID: userID, EmailAssigned: true, EmailReplaced: true, EmailReminder: true}
 }
 
 type PRStatus string
 
 const (
+	PRStatusDraft  PRStatus = "DRAFT"
 	PRStatusOpen   PRStatus = "OPEN"
+	PRStatusClosed PRStatus = "CLOSED"
 	PRStatusMerged PRStatus = "MERGED"
 )
 
+// ReviewState — состояние ревью конкретного ревьювера.
+type ReviewState string
+
+const (
+	ReviewPending          ReviewState = "PENDING"
+	ReviewApproved         ReviewState = "APPROVED"
+	ReviewChangesRequested ReviewState = "CHANGES_REQUESTED"
+	ReviewDismissed        ReviewState = "DISMISSED"
+)
+
+func (s ReviewState) Valid() bool {
+	switch s {
+	case ReviewPending, ReviewApproved, ReviewChangesRequested, ReviewDismissed:
+		return true
+	}
+	return false
+}
+
+// ReviewerAssignment — назначение конкретного ревьювера на PR.
+type ReviewerAssignment struct {
+	UserID string `json:"user_id"`
+	// Fallback — ревьювер взят не из команды, а из запасного пула.
+	Fallback   bool        `json:"fallback"`
+	State      ReviewState `json:"state"`
+	AssignedAt time.Time   `json:"assignedAt"`
+	// ReviewedAt — время последнего изменения состояния ревью; nil, пока ревью в PENDING.
+	ReviewedAt *time.Time `json:"reviewedAt"`
+	// Reason объясняет, почему выбран ревьювер; MatchedPaths — изменённые файлы, которыми он владеет,
+	// MatchedTags — обязательные теги PR, ради которых он выбран.
+	Reason       SelectionReason `json:"selection_reason"`
+	MatchedPaths []string        `json:"matched_paths,omitempty"`
+	MatchedTags  []string        `json:"matched_tags,omitempty"`
+}
+
+// SelectionReason — почему ревьювер был выбран.
+type SelectionReason string
+
+const (
+	// ReasonCodeowner — владелец изменённых путей по правилам CODEOWNERS (пути — в MatchedPaths).
+	ReasonCodeowner SelectionReason = "codeowner"
+	// ReasonSkill — выбран из команды или запасной команды, чтобы покрыть обязательные теги PR (в MatchedTags).
+	ReasonSkill SelectionReason = "skill"
+	// ReasonSeniority — выбран из команды или запасной команды, чтобы выполнить правила уровня
+	// (min_senior_reviewers, forbid_junior_only).
+	ReasonSeniority SelectionReason = "seniority"
+	// ReasonTeam — выбран стратегией команды из её пула.
+	ReasonTeam SelectionReason = "team"
+	// ReasonFallbackTeam — взят из запасной команды, когда в своей кандидаты закончились.
+	ReasonFallbackTeam SelectionReason = "fallback_team"
+	// ReasonOutsideTeam — взят из любой другой команды по политике understaffed_policy=fallback.
+	ReasonOutsideTeam SelectionReason = "outside_team"
+)
+
+// AssignedReview — PR, где пользователь назначен ревьювером, вместе с состоянием его ревью.
+type AssignedReview struct {
+	PullRequest PullRequest
+	Review      ReviewerAssignment
+}
+
 type PullRequest struct {
-	ID                string     `json:"pull_request_id"`
-	Name              string     `json:"pull_request_name"`
-	AuthorID          string     `json:"author_id"`
-	Status            PRStatus   `json:"status"`
-	AssignedReviewers []string   `json:"assigned_reviewers"`
-	CreatedAt         time.Time  `json:"createdAt"`
-	MergedAt          *time.Time `json:"mergedAt"`
+	ID                string               `json:"pull_request_id"`
+	Name              string               `json:"pull_request_name"`
+	AuthorID          string               `json:"author_id"`
+	Status            PRStatus             `json:"status"`
+	AssignedReviewers []string             `json:"assigned_reviewers"`
+	Reviewers         []ReviewerAssignment `json:"reviewers"`
+	CreatedAt         time.Time            `json:"createdAt"`
+	MergedAt          *time.Time           `json:"mergedAt"`
+	ClosedAt          *time.Time           `json:"closedAt"`
+	// Understaffed — у PR меньше ревьюверов, чем требует min_reviewers команды.
+	Understaffed bool `json:"understaffed"`
+	// TeamName — команда, от имени которой создан PR (по умолчанию основная команда автора):
+	// её пул и настройки используются при подборе ревьюверов.
+	TeamName string `json:"team_name,omitempty"`
+	// Source — откуда пришёл PR: manual (API) или внешняя система (github, gitlab).
+	// Для внешних PR заданы проект и номер в ней (GitHub number / GitLab IID).
+	Source          string `json:"source"`
+	ExternalProject string `json:"external_project,omitempty"`
+	ExternalIID     int    `json:"external_iid,omitempty"`
+	// Repository — репозиторий PR для правил CODEOWNERS (для внешних PR — их проект);
+	// ChangedFiles — изменённые файлы, по ним ищутся владельцы кода.
+	Repository   string   `json:"repository,omitempty"`
+	ChangedFiles []string `json:"changed_files,omitempty"`
+	// RequiredTags — навыки, которые должны быть хотя бы у одного ревьювера;
+	// UncoveredTags — те из них, для которых подходящего ревьювера не нашлось.
+	RequiredTags  []string `json:"required_tags,omitempty"`
+	UncoveredTags []string `json:"uncovered_tags,omitempty"`
 }
 
+// SourceManual — PR создан вручную через API.
+const SourceManual = "manual"
+
 // ReviewerStat описывает количество назначений ревьюверов.
 type ReviewerStat struct {
 	UserID        string `json:"user_id"`
 	AssignedCount int    `json:"assigned_count"`
+	// OpenCount — назначения в PR со статусом OPEN, по ним считается нагрузка.
+	OpenCount int `json:"open_count"`
+}
+
+// SelectionStrategy определяет, как из пула кандидатов выбираются ревьюверы.
+type SelectionStrategy string
+
+const (
+	StrategyRandom      SelectionStrategy = "random"
+	StrategyRoundRobin  SelectionStrategy = "round_robin"
+	StrategyLeastLoaded SelectionStrategy = "least_loaded"
+	StrategyWeighted    SelectionStrategy = "weighted"
+)
+
+func (s SelectionStrategy) Valid() bool {
+	switch s {
+	case StrategyRandom, StrategyRoundRobin, StrategyLeastLoaded, StrategyWeighted:
+		return true
+	}
+	return false
+}
+
+// UnderstaffedPolicy определяет поведение, когда для PR не набирается MinReviewers ревьюверов.
+type UnderstaffedPolicy string
+
+const (
+	// PolicyReject — PR не создаётся.
+	PolicyReject UnderstaffedPolicy = "reject"
+	// PolicyFallback — недостающие ревьюверы добираются из других команд.
+	PolicyFallback UnderstaffedPolicy = "fallback"
+	// PolicyUnderstaffed — PR создаётся с тем, что есть, и помечается understaffed.
+	PolicyUnderstaffed UnderstaffedPolicy = "understaffed"
+)
+
+func (p UnderstaffedPolicy) Valid() bool {
+	switch p {
+	case PolicyReject, PolicyFallback, PolicyUnderstaffed:
+		return true
+	}
+	return false
+}
+
+// TeamSettings — настройки команды, влияющие на назначение ревьюверов.
+type TeamSettings struct {
+	TeamName          string            `json:"team_name"`
+	SelectionStrategy SelectionStrategy `json:"selection_strategy"`
+	// ReviewerCount — желаемое число ревьюверов на PR.
+	ReviewerCount int `json:"reviewer_count"`
+	// MinReviewers — минимально допустимое число ревьюверов.
+	MinReviewers       int                `json:"min_reviewers"`
+	UnderstaffedPolicy UnderstaffedPolicy `json:"understaffed_policy"`
+	// FallbackTeams — упорядоченный список команд, из которых добираются ревьюверы,
+	// когда в своей команде кандидаты закончились.
+	FallbackTeams []string `json:"fallback_teams"`
+	// RequiredApprovals — сколько APPROVED нужно для мержа.
+	RequiredApprovals int `json:"required_approvals"`
+	// BlockOnChangesRequested запрещает мерж, пока хотя бы одно ревью в CHANGES_REQUESTED.
+	BlockOnChangesRequested bool `json:"block_on_changes_requested"`
+	// ChatWebhookURL — incoming webhook канала команды в Slack/Mattermost; пусто — без уведомлений в чат.
+	ChatWebhookURL string `json:"chat_webhook_url"`
+	// ReminderAfterHours — через сколько часов без ревью ревьюверу напоминают (и повторяют с тем же интервалом);
+	// EscalateAfterHours — через сколько часов ревьювер заменяется другим. 0 отключает шаг.
+	ReminderAfterHours int `json:"reminder_after_hours"`
+	EscalateAfterHours int `json:"escalate_after_hours"`
+	// PairWindowDays — за сколько дней учитываются прошлые ревью автора: каждое добавляется к нагрузке
+	// кандидата как одно открытое ревью. 0 отключает учёт.
+	PairWindowDays int `json:"pair_window_days"`
+	// MinSeniorReviewers — сколько ревьюверов PR должны быть senior или lead;
+	// ForbidJuniorOnly запрещает PR, у которого все ревьюверы — junior.
+	MinSeniorReviewers int  `json:"min_senior_reviewers"`
+	ForbidJuniorOnly   bool `json:"forbid_junior_only"`
+}
+
+// DefaultTeamSettings возвращает настройки для команды, у которой нет своей записи.
+func DefaultTeamSettings(team string) TeamSettings {
+	return TeamSettings{
+		TeamName:                team,
+		SelectionStrategy:       StrategyLeastLoaded,
+		ReviewerCount:           2,
+		MinReviewers:            1,
+		UnderstaffedPolicy:      PolicyUnderstaffed,
+		FallbackTeams:           []string{},
+		BlockOnChangesRequested: true,
+		ReminderAfterHours:      24,
+		EscalateAfterHours:      0,
+		PairWindowDays:          30,
+	}
+}
+
+// ReviewCandidate — активный пользователь, которого можно назначить ревьювером.
+type ReviewCandidate struct {
+	UserID string
+	// Load — количество открытых (OPEN) PR, где пользователь назначен ревьювером.
+	Load   int
+	Weight int
+	// Pairs — сколько раз кандидат ревьюил автора PR за окно pair_window_days.
+	// Каждая стратегия отодвигает кандидатов с недавними парами назад.
+	Pairs int
+	// Schedule — рабочие часы кандидата; nil — доступен в любое время.
+	Schedule *WorkSchedule
+	Tags     []string
+	Level    Level
+}
+
+// ReviewerPair — сколько раз reviewer был назначен на PR автора author.
+type ReviewerPair struct {
+	AuthorID   string `json:"author_id"`
+	ReviewerID string `json:"reviewer_id"`
+	Count      int    `json:"count"`
+}
+
+// PairStats — распределение пар автор→ревьювер за период с Since.
+type PairStats struct {
+	Since time.Time      `json:"since"`
+	Pairs []ReviewerPair `json:"pairs"`
+	// Matrix — те же данные в виде author_id → reviewer_id → count.
+	Matrix map[string]map[string]int `json:"matrix"`
+}
+
+// MergeOverride — запись о принудительном мерже в обход правил команды.
+type MergeOverride struct {
+	PullRequestID string    `json:"pull_request_id"`
+	Actor         string    `json:"actor"`
+	Reason        string    `json:"reason"`
+	Unmet         []string  `json:"unmet_conditions"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// PREventType — тип события в журнале PR.
+type PREventType string
+
+const (
+	EventCreated          PREventType = "created"
+	EventReviewerAssigned PREventType = "reviewer_assigned"
+	EventReviewerRemoved  PREventType = "reviewer_removed"
+	EventReassigned       PREventType = "reassigned"
+	EventStatusChanged    PREventType = "status_changed"
+	EventMerged           PREventType = "merged"
+	EventReviewSubmitted  PREventType = "review_submitted"
+	EventTeamDeactivated  PREventType = "team_deactivated"
+	// EventUsersDeactivated — ревьюверы деактивированы синхронизацией с каталогом.
+	EventUsersDeactivated PREventType = "users_deactivated"
+	// EventEscalated — ревьювер не ответил за escalate_after_hours и был заменён (или замены не нашлось).
+	EventEscalated PREventType = "escalated"
+)
+
+// PREvent — запись журнала изменений PR. UserID — пользователь, которого касается событие
+// (назначенный или снятый ревьювер, автор ревью), подробности — в Payload.
+type PREvent struct {
+	ID            int64          `json:"id"`
+	PullRequestID string         `json:"pull_request_id"`
+	Type          PREventType    `json:"event_type"`
+	UserID        string         `json:"user_id,omitempty"`
+	Payload       map[string]any `json:"payload"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// OverdueReview — ревью в PENDING по открытому PR, для которого наступил срок напоминания или эскалации.
+// SLA берётся из настроек команды PR.
+type OverdueReview struct {
+	PullRequestID      string
+	UserID             string
+	TeamName           string
+	AssignedAt         time.Time
+	RemindedAt         *time.Time
+	EscalatedAt        *time.Time
+	ReminderAfterHours int
+	EscalateAfterHours int
+}
+
+// Escalation — запись об эскалации просроченного ревью. ToUserID == nil — замены не нашлось.
+type Escalation struct {
+	ID            int64     `json:"id"`
+	PullRequestID string    `json:"pull_request_id"`
+	TeamName      string    `json:"team_name"`
+	FromUserID    string    `json:"from_user_id"`
+	ToUserID      *string   `json:"to_user_id"`
+	AssignedAt    time.Time `json:"assignedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Availability — период [StartsAt, EndsAt), когда пользователь недоступен для ревью (отпуск, больничный).
+type Availability struct {
+	ID       int64     `json:"id"`
+	UserID   string    `json:"user_id"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+	Reason   string    `json:"reason"`
+	// AutoReassign — с началом периода PENDING-ревью пользователя в OPEN PR переназначаются.
+	AutoReassign bool       `json:"auto_reassign"`
+	ReassignedAt *time.Time `json:"reassignedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// CodeownersScope — к чему относятся правила CODEOWNERS: к команде автора или к репозиторию PR.
+type CodeownersScope string
+
+const (
+	CodeownersTeam       CodeownersScope = "team"
+	CodeownersRepository CodeownersScope = "repository"
+)
+
+// CodeownersRule — строка CODEOWNERS: шаблон пути и его владельцы (user_id).
+// Для файла действует последнее подходящее правило; правило без владельцев снимает владение.
+type CodeownersRule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+// ExternalAccount связывает логин во внешней системе (provider) с пользователем сервиса.
+type ExternalAccount struct {
+	Provider string `json:"provider"`
+	Login    string `json:"login"`
+	UserID   string `json:"user_id"`
+}
+
+// ForgeAction — нормализованное действие над PR во внешней системе (GitHub и т.п.).
+type ForgeAction string
+
+const (
+	ForgeOpened   ForgeAction = "opened"
+	ForgeReady    ForgeAction = "ready_for_review"
+	ForgeClosed   ForgeAction = "closed"
+	ForgeMerged   ForgeAction = "merged"
+	ForgeReopened ForgeAction = "reopened"
+	// ForgeConvertedToDraft — PR снова стал черновиком.
+	ForgeConvertedToDraft ForgeAction = "converted_to_draft"
+)
+
+// ForgeEvent — событие PR из внешней системы, приведённое к общему виду.
+type ForgeEvent struct {
+	Provider string
+	Action   ForgeAction
+	// Repo и Number — проект и номер PR в нём (GitHub owner/repo и number, GitLab path и IID).
+	Repo        string
+	Number      int
+	Title       string
+	AuthorLogin string
+	// ActorLogin — кто выполнил действие (например, смержил PR).
+	ActorLogin string
+	Draft      bool
+}
+
+// PullRequestID — идентификатор PR сервиса для внешнего PR: "<provider>:<repo>#<number>".
+func (e ForgeEvent) PullRequestID() string {
+	return fmt.Sprintf("%s:%s#%d", e.Provider, e.Repo, e.Number)
+}
+
+// EventTopic — тип исходящего события для подписчиков.
+type EventTopic string
+
+const (
+	TopicPRCreated          EventTopic = "pr.created"
+	TopicReviewerAssigned   EventTopic = "reviewer.assigned"
+	TopicReviewerReassigned EventTopic = "reviewer.reassigned"
+	TopicPRMerged           EventTopic = "pr.merged"
+	TopicTeamDeactivated    EventTopic = "team.deactivated"
+	// TopicUsersDeactivated — пользователи, пропавшие из каталога, деактивированы.
+	TopicUsersDeactivated EventTopic = "users.deactivated"
+	// TopicReviewReminder — напоминание ревьюверу о ревью, которое давно ждёт его.
+	TopicReviewReminder EventTopic = "review.reminder"
+	// TopicReviewEscalated — ревью просрочено и эскалировано.
+	TopicReviewEscalated EventTopic = "review.escalated"
+)
+
+var eventTopics = []EventTopic{
+	TopicPRCreated, TopicReviewerAssigned, TopicReviewerReassigned, TopicPRMerged, TopicTeamDeactivated,
+	TopicReviewReminder, TopicReviewEscalated, TopicUsersDeactivated,
+}
+
+func (t EventTopic) Valid() bool {
+	for _, known := range eventTopics {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxMessage — событие, записанное в outbox в транзакции изменения и ожидающее рассылки.
+type OutboxMessage struct {
+	ID        int64           `json:"id"`
+	Topic     EventTopic      `json:"event"`
+	Payload   json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// WebhookSubscription — подписчик на исходящие события. Пустой Topics означает все события.
+type WebhookSubscription struct {
+	ID        int64        `json:"id"`
+	URL       string       `json:"url"`
+	Secret    string       `json:"secret,omitempty"`
+	Topics    []EventTopic `json:"topics"`
+	IsActive  bool         `json:"is_active"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// DeliveryStatus — состояние доставки события получателю.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliveryDelivered DeliveryStatus = "DELIVERED"
+	DeliveryFailed    DeliveryStatus = "FAILED"
+)
+
+// DeliveryTarget — получатель события в канале доставки (например, подписка вебхука).
+type DeliveryTarget struct {
+	Channel string
+	Target  string
+}
+
+// Delivery — доставка одного события одному получателю; заодно журнал попыток.
+type Delivery struct {
+	ID             int64          `json:"id"`
+	OutboxID       int64          `json:"event_id"`
+	Topic          EventTopic     `json:"event"`
+	Channel        string         `json:"channel"`
+	Target         string         `json:"target"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"nextAttemptAt"`
+	LastStatusCode *int           `json:"last_status_code,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// OrgRow — строка оргструктуры для импорта и выгрузки: участие пользователя UserID в команде TeamName
+// или, при пустом UserID, команда без участников. Line — строка исходного файла (для ошибок импорта).
+type OrgRow struct {
+	TeamName   string `json:"team_name"`
+	ParentTeam string `json:"parent_team,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Username   string `json:"username,omitempty"`
+	IsActive   bool   `json:"is_active,omitempty"`
+	// Primary — команда для пользователя основная.
+	Primary bool `json:"primary,omitempty"`
+	// ReviewWeight — вес в основной команде общий для пользователя, в остальных — вес в этой команде;
+	// 0 — по умолчанию.
+	ReviewWeight int   `json:"review_weight,omitempty"`
+	Level        Level `json:"level,omitempty"`
+	Line         int   `json:"-"`
+}
+
+// ImportRowError — ошибка в строке Line файла импорта; 0 — ошибка не привязана к строке.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ImportRowError) String() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportResult — что импорт оргструктуры создал и изменил (при DryRun — создал бы и изменил бы).
+type ImportResult struct {
+	DryRun             bool `json:"dry_run"`
+	TeamsCreated       int  `json:"teams_created"`
+	TeamsUpdated       int  `json:"teams_updated"`
+	UsersCreated       int  `json:"users_created"`
+	UsersUpdated       int  `json:"users_updated"`
+	MembershipsAdded   int  `json:"memberships_added"`
+	MembershipsUpdated int  `json:"memberships_updated"`
+}
+
+// DirectoryGroup — группа внешнего каталога (LDAP), задающая состав команды TeamName.
+type DirectoryGroup struct {
+	TeamName string
+	Members  []DirectoryUser
+}
+
+// DirectoryUser — пользователь каталога.
+type DirectoryUser struct {
+	UserID   string
+	Username string
+}
+
+// SyncMembership — участие пользователя в команде, добавленное или снятое синхронизацией.
+type SyncMembership struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+}
+
+// SyncMove — смена основной команды пользователя синхронизацией.
+type SyncMove struct {
+	UserID   string `json:"user_id"`
+	FromTeam string `json:"from_team"`
+	ToTeam   string `json:"to_team"`
+}
+
+// SyncReport — изменения одного прохода синхронизации с каталогом; при DryRun — план без изменений.
+// Reassigned и UnassignedLeft — ревью ушедших и покинувших команды пользователей (только без DryRun).
+type SyncReport struct {
+	DryRun         bool             `json:"dry_run"`
+	CreatedTeams   []string         `json:"created_teams"`
+	Created        []SyncMembership `json:"created"`
+	Renamed        []string         `json:"renamed_user_ids"`
+	Reactivated    []string         `json:"reactivated_user_ids"`
+	Joined         []SyncMembership `json:"joined"`
+	Moved          []SyncMove       `json:"moved"`
+	Left           []SyncMembership `json:"left"`
+	Deactivated    []string         `json:"deactivated_user_ids"`
+	Reassigned     int              `json:"reassigned"`
+	UnassignedLeft int              `json:"unassigned_left"`
 }
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"time"
+	// Часовые пояса встроены в бинарник, чтобы не зависеть от tzdata в образе.
+	_ "time/tzdata"
+)
+
+// WorkSchedule — рабочее время пользователя в его часовом поясе.
+type WorkSchedule struct {
+	// Timezone — имя из базы IANA, например Europe/Moscow или Asia/Novosibirsk.
+	Timezone string `json:"timezone"`
+	// Start и End — начало и конец рабочего дня, "HH:MM". Если End раньше Start, смена переходит через полночь.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Days — рабочие дни по ISO 8601: 1 — понедельник, 7 — воскресенье. Для ночной смены — день её начала.
+	Days []int `json:"days"`
+}
+
+// DefaultWorkDays — понедельник–пятница.
+var DefaultWorkDays = []int{1, 2, 3, 4, 5}
+
+func (w WorkSchedule) Valid() bool {
+	if _, err := time.LoadLocation(w.Timezone); err != nil || w.Timezone == "" {
+		return false
+	}
+	start, ok1 := parseClock(w.Start)
+	end, ok2 := parseClock(w.End)
+	if !ok1 || !ok2 || start == end {
+		return false
+	}
+	seen := make(map[int]bool)
+	for _, d := range w.Days {
+		if d < 1 || d > 7 || seen[d] {
+			return false
+		}
+		seen[d] = true
+	}
+	return len(w.Days) > 0
+}
+
+// InHours сообщает, рабочее ли у пользователя время в момент t. Некорректное расписание считается
+// круглосуточным, чтобы не исключать пользователя из выбора.
+func (w WorkSchedule) InHours(t time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	start, ok1 := parseClock(w.Start)
+	end, ok2 := parseClock(w.End)
+	if err != nil || !ok1 || !ok2 {
+		return true
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := isoWeekday(local.Weekday())
+
+	if start < end {
+		return minute >= start && minute < end && w.worksOn(today)
+	}
+	// Ночная смена: вечер дня начала или утро следующего дня.
+	yesterday := today - 1
+	if yesterday == 0 {
+		yesterday = 7
+	}
+	return (minute >= start && w.worksOn(today)) || (minute < end && w.worksOn(yesterday))
+}
+
+func (w WorkSchedule) worksOn(day int) bool {
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func isoWeekday(d time.Weekday) int {
+	if d == time.Sunday {
+		return 7
+	}
+	return int(d)
+}
+
+// parseClock разбирает "HH:MM" в минуты от полуночи.
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkSchedule_InHours(t *testing.T) {
+	moscow := WorkSchedule{Timezone: "Europe/Moscow", Start: "10:00", End: "19:00", Days: DefaultWorkDays}
+	novosibirsk := WorkSchedule{Timezone: "Asia/Novosibirsk", Start: "10:00", End: "19:00", Days: DefaultWorkDays}
+	night := WorkSchedule{Timezone: "UTC", Start: "22:00", End: "06:00", Days: []int{5}}
+
+	// Пятница, 2025-10-24.
+	at := func(h, m int) time.Time { return time.Date(2025, 10, 24, h, m, 0, 0, time.UTC) }
+	cases := []struct {
+		name string
+		s    WorkSchedule
+		t    time.Time
+		want bool
+	}{
+		{"moscow morning", moscow, at(7, 0), true},             // 10:00 MSK
+		{"moscow before start", moscow, at(6, 59), false},      // 09:59 MSK
+		{"moscow end is exclusive", moscow, at(16, 0), false},  // 19:00 MSK
+		{"novosibirsk evening", novosibirsk, at(12, 0), false}, // 19:00 NOVT
+		{"novosibirsk 2am", novosibirsk, at(19, 0), false},
+		{"saturday", moscow, at(7, 0).AddDate(0, 0, 1), false},
+		{"night shift evening", night, at(23, 0), true},
+		{"night shift saturday morning", night, at(5, 0).AddDate(0, 0, 1), true},
+		{"night shift friday morning", night, at(5, 0), false},
+	}
+	for _, c := range cases {
+		if got := c.s.InHours(c.t); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWorkSchedule_Valid(t *testing.T) {
+	ok := WorkSchedule{Timezone: "Asia/Novosibirsk", Start: "09:30", End: "18:00", Days: []int{1, 7}}
+	if !ok.Valid() {
+		t.Fatal("expected valid schedule")
+	}
+	bad := []WorkSchedule{
+		{Timezone: "Mars/Olympus", Start: "09:00", End: "18:00", Days: DefaultWorkDays},
+		{Timezone: "", Start: "09:00", End: "18:00", Days: DefaultWorkDays},
+		{Timezone: "UTC", Start: "9am", End: "18:00", Days: DefaultWorkDays},
+		{Timezone: "UTC", Start: "09:00", End: "09:00", Days: DefaultWorkDays},
+		{Timezone: "UTC", Start: "09:00", End: "18:00", Days: []int{0}},
+		{Timezone: "UTC", Start: "09:00", End: "18:00", Days: []int{1, 1}},
+		{Timezone: "UTC", Start: "09:00", End: "18:00"},
+	}
+	for i, s := range bad {
+		if s.Valid() {
+			t.Errorf("case %d: expected invalid: %+v", i, s)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+const ChannelChat = "chat"
+
+// ChatDirectory — то, что чат-уведомлениям нужно знать о пользователях и командах.
+type ChatDirectory interface {
+	// TeamOf возвращает команду пользователя.
+	TeamOf(ctx context.Context, userID string) (string, error)
+	// TeamChatURL возвращает incoming webhook канала команды или "", если он не задан.
+	TeamChatURL(ctx context.Context, team string) (string, error)
+	// ChatHandles возвращает идентификаторы в чате для тех из userIDs, у кого они заданы.
+	ChatHandles(ctx context.Context, userIDs []string) (map[string]string, error)
+}
+
+// RepoChatDirectory — ChatDirectory поверх репозиториев.
+type RepoChatDirectory struct {
+	Users *repository.UsersRepo
+	Teams *repository.TeamsRepo
+}
+
+func (d RepoChatDirectory) TeamOf(ctx context.Context, userID string) (string, error) {
+	u, err := d.Users.GetUser(ctx, userID)
+	return u.TeamName, err
+}
+
+func (d RepoChatDirectory) TeamChatURL(ctx context.Context, team string) (string, error) {
+	s, err := d.Teams.GetSettings(ctx, team)
+	return s.ChatWebhookURL, err
+}
+
+func (d RepoChatDirectory) ChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	return d.Users.ChatHandles(ctx, userIDs)
+}
+
+// ChatMessage — тело incoming webhook в формате Slack (Mattermost принимает тот же формат).
+type ChatMessage struct {
+	Text string `json:"text"`
+}
+
+// prEvent — поля событий outbox о PR, нужные для уведомлений в чат и на почту.
+type prEvent struct {
+	PullRequest struct {
+		ID       string `json:"pull_request_id"`
+		Name     string `json:"pull_request_name"`
+		AuthorID string `json:"author_id"`
+	} `json:"pull_request"`
+	UserID     string     `json:"user_id"`
+	OldUserID  string     `json:"old_user_id"`
+	NewUserID  string     `json:"new_user_id"`
+	AssignedAt *time.Time `json:"assigned_at"`
+}
+
+// recipients возвращает ревьюверов, которых касается событие; для остальных событий — nil.
+func (e prEvent) recipients(topic model.EventTopic) []string {
+	switch topic {
+	case model.TopicReviewerAssigned, model.TopicReviewReminder:
+		return []string{e.UserID}
+	case model.TopicReviewerReassigned:
+		return []string{e.NewUserID, e.OldUserID}
+	}
+	return nil
+}
+
+// ChatChannel отправляет сообщения о назначениях, заменах и напоминаниях в канал команды ревьювера.
+type ChatChannel struct {
+	dir    ChatDirectory
+	client *http.Client
+}
+
+func NewChatChannel(dir ChatDirectory, client *http.Client) *ChatChannel {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ChatChannel{dir: dir, client: client}
+}
+
+func (c *ChatChannel) Name() string { return ChannelChat }
+
+// Route возвращает команды ревьюверов события, у которых настроен канал. Получатель доставки — команда:
+// её URL читается в момент отправки, так что смена канала действует и на ещё не отправленные сообщения.
+func (c *ChatChannel) Route(ctx context.Context, msg model.OutboxMessage) ([]string, error) {
+	var ev prEvent
+	if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var teams []string
+	for _, uid := range ev.recipients(msg.Topic) {
+		if uid == "" {
+			continue
+		}
+		team, err := c.dir.TeamOf(ctx, uid)
+		if err == repository.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[team] {
+			continue
+		}
+		seen[team] = true
+		url, err := c.dir.TeamChatURL(ctx, team)
+		if err != nil {
+			return nil, err
+		}
+		if url != "" {
+			teams = append(teams, team)
+		}
+	}
+	return teams, nil
+}
+
+func (c *ChatChannel) Send(ctx context.Context, team string, msg model.OutboxMessage) (int, error) {
+	url, err := c.dir.TeamChatURL(ctx, team)
+	if err != nil {
+		return 0, err
+	}
+	if url == "" {
+		return 0, permanent(fmt.Errorf("team %s has no chat channel", team))
+	}
+	var ev prEvent
+	if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+		return 0, permanent(err)
+	}
+	handles, err := c.dir.ChatHandles(ctx, append(ev.recipients(msg.Topic), ev.PullRequest.AuthorID))
+	if err != nil {
+		return 0, err
+	}
+	text, ok := formatChatText(msg.Topic, ev, handles)
+	if !ok {
+		return 0, permanent(fmt.Errorf("no chat message for %s", msg.Topic))
+	}
+	body, err := json.Marshal(ChatMessage{Text: text})
+	if err != nil {
+		return 0, permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// slackMemberID — member ID Slack (U…/W…): упоминается как <@ID>, остальные handle — как @handle.
+var slackMemberID = regexp.MustCompile(`^[UW][A-Z0-9]{6,}$`)
+
+func mention(userID string, handles map[string]string) string {
+	h, ok := handles[userID]
+	if !ok {
+		return userID
+	}
+	if slackMemberID.MatchString(h) {
+		return "<@" + h + ">"
+	}
+	return "@" + h
+}
+
+// formatChatText формирует текст сообщения в разметке Slack (mrkdwn).
+func formatChatText(topic model.EventTopic, ev prEvent, handles map[string]string) (string, bool) {
+	pr := fmt.Sprintf("*%s* (`%s`)", ev.PullRequest.Name, ev.PullRequest.ID)
+	author := mention(ev.PullRequest.AuthorID, handles)
+	switch topic {
+	case model.TopicReviewerAssigned:
+		return fmt.Sprintf(":eyes: %s, you were assigned to review %s by %s", mention(ev.UserID, handles), pr, author), true
+	case model.TopicReviewerReassigned:
+		return fmt.Sprintf(":arrows_counterclockwise: %s now reviews %s by %s instead of %s",
+			mention(ev.NewUserID, handles), pr, author, mention(ev.OldUserID, handles)), true
+	case model.TopicReviewReminder:
+		text := fmt.Sprintf(":alarm_clock: %s, %s by %s is waiting for your review", mention(ev.UserID, handles), pr, author)
+		if ev.AssignedAt != nil {
+			text += " since " + ev.AssignedAt.UTC().Format("2006-01-02 15:04 MST")
+		}
+		return text, true
+	}
+	return "", false
+}
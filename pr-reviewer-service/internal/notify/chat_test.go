@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+type fakeDirectory struct {
+	teams   map[string]string
+	urls    map[string]string
+	handles map[string]string
+}
+
+func (d fakeDirectory) TeamOf(_ context.Context, userID string) (string, error) {
+	team, ok := d.teams[userID]
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	return team, nil
+}
+
+func (d fakeDirectory) TeamChatURL(_ context.Context, team string) (string, error) {
+	return d.urls[team], nil
+}
+
+func (d fakeDirectory) ChatHandles(_ context.Context, ids []string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, id := range ids {
+		if h, ok := d.handles[id]; ok {
+			res[id] = h
+		}
+	}
+	return res, nil
+}
+
+// chatStandIn — локальная замена incoming webhook: запоминает полученные сообщения.
+func chatStandIn(t *testing.T, status int) (*httptest.Server, *[]ChatMessage) {
+	t.Helper()
+	var got []ChatMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m ChatMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		got = append(got, m)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func prMessage(topic model.EventTopic, data string) model.OutboxMessage {
+	return model.OutboxMessage{ID: 1, Topic: topic, Payload: json.RawMessage(
+		`{"pull_request":{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1"},` + data + `}`)}
+}
+
+func TestChatChannel_AssignmentToReviewerTeam(t *testing.T) {
+	srv, got := chatStandIn(t, http.StatusOK)
+	dir := fakeDirectory{
+		teams:   map[string]string{"u1": "backend", "u2": "backend", "u3": "frontend"},
+		urls:    map[string]string{"backend": srv.URL},
+		handles: map[string]string{"u1": "alice", "u2": "U024BE7LH"},
+	}
+	ch := NewChatChannel(dir, srv.Client())
+	msg := prMessage(model.TopicReviewerAssigned, `"user_id":"u2"`)
+
+	teams, err := ch.Route(context.Background(), msg)
+	if err != nil || len(teams) != 1 || teams[0] != "backend" {
+		t.Fatalf("route: %v %v", teams, err)
+	}
+	if _, err := ch.Send(context.Background(), "backend", msg); err != nil {
+		t.Fatal(err)
+	}
+	want := ":eyes: <@U024BE7LH>, you were assigned to review *Add search* (`pr-1`) by @alice"
+	if len(*got) != 1 || (*got)[0].Text != want {
+		t.Fatalf("got %+v", *got)
+	}
+
+	// у команды frontend канала нет — сообщение не маршрутизируется
+	teams, _ = ch.Route(context.Background(), prMessage(model.TopicReviewerAssigned, `"user_id":"u3"`))
+	if len(teams) != 0 {
+		t.Fatalf("expected no route, got %v", teams)
+	}
+}
+
+func TestChatChannel_ReassignmentNotifiesBothTeams(t *testing.T) {
+	srv, got := chatStandIn(t, http.StatusOK)
+	dir := fakeDirectory{
+		teams: map[string]string{"u2": "backend", "u5": "platform"},
+		urls:  map[string]string{"backend": srv.URL, "platform": srv.URL},
+	}
+	ch := NewChatChannel(dir, srv.Client())
+	msg := prMessage(model.TopicReviewerReassigned, `"old_user_id":"u2","new_user_id":"u5"`)
+
+	teams, err := ch.Route(context.Background(), msg)
+	if err != nil || len(teams) != 2 || teams[0] != "platform" || teams[1] != "backend" {
+		t.Fatalf("route: %v %v", teams, err)
+	}
+	if _, err := ch.Send(context.Background(), "platform", msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains((*got)[0].Text, "u5 now reviews *Add search*") || !strings.Contains((*got)[0].Text, "instead of u2") {
+		t.Fatalf("got %q", (*got)[0].Text)
+	}
+}
+
+func TestChatChannel_Reminder(t *testing.T) {
+	srv, got := chatStandIn(t, http.StatusOK)
+	dir := fakeDirectory{teams: map[string]string{"u2": "backend"}, urls: map[string]string{"backend": srv.URL}}
+	ch := NewChatChannel(dir, srv.Client())
+	msg := prMessage(model.TopicReviewReminder, `"user_id":"u2","assigned_at":"2025-10-24T12:00:00Z"`)
+
+	if _, err := ch.Send(context.Background(), "backend", msg); err != nil {
+		t.Fatal(err)
+	}
+	want := ":alarm_clock: u2, *Add search* (`pr-1`) by u1 is waiting for your review since 2025-10-24 12:00 UTC"
+	if (*got)[0].Text != want {
+		t.Fatalf("got %q", (*got)[0].Text)
+	}
+}
+
+func TestChatChannel_Errors(t *testing.T) {
+	srv, _ := chatStandIn(t, http.StatusInternalServerError)
+	dir := fakeDirectory{teams: map[string]string{"u2": "backend"}, urls: map[string]string{"backend": srv.URL}}
+	ch := NewChatChannel(dir, srv.Client())
+
+	code, err := ch.Send(context.Background(), "backend", prMessage(model.TopicReviewerAssigned, `"user_id":"u2"`))
+	if err == nil || code != http.StatusInternalServerError || isPermanent(err) {
+		t.Fatalf("server error must be retried: %d %v", code, err)
+	}
+	if _, err := ch.Send(context.Background(), "nochannel", prMessage(model.TopicReviewerAssigned, `"user_id":"u2"`)); !isPermanent(err) {
+		t.Fatalf("missing channel must be permanent: %v", err)
+	}
+	if teams, _ := ch.Route(context.Background(), prMessage(model.TopicPRMerged, `"forced":false`)); len(teams) != 0 {
+		t.Fatalf("merge events are not sent to chat, got %v", teams)
+	}
+}
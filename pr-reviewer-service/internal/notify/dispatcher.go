@@ -0,0 +1,148 @@
+// Package notify рассылает события из outbox получателям: подписчикам вебхуков и другим каналам.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+// Channel — канал доставки событий. Route решает, кому в этом канале нужно событие,
+// Send доставляет его одному получателю и возвращает HTTP-код ответа (если он есть).
+type Channel interface {
+	Name() string
+	Route(ctx context.Context, msg model.OutboxMessage) ([]string, error)
+	Send(ctx context.Context, target string, msg model.OutboxMessage) (int, error)
+}
+
+var errUnknownChannel = permanent(errors.New("unknown delivery channel"))
+
+// permanentError — ошибка, повтор после которой бессмыслен.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error { return permanentError{err} }
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Backoff задаёт задержку перед повторной попыткой доставки.
+type Backoff struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+var DefaultBackoff = Backoff{Base: 30 * time.Second, Max: time.Hour, MaxAttempts: 8}
+
+// Delay возвращает задержку после attempts неудачных попыток и false, если попытки исчерпаны.
+func (b Backoff) Delay(attempts int) (time.Duration, bool) {
+	if attempts >= b.MaxAttempts {
+		return 0, false
+	}
+	d := b.Base
+	for i := 1; i < attempts && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d, true
+}
+
+// Dispatcher периодически раскладывает новые события outbox по каналам и доставляет их с повторами.
+type Dispatcher struct {
+	outbox   *repository.OutboxRepo
+	channels map[string]Channel
+	backoff  Backoff
+	interval time.Duration
+	batch    int
+	now      func() time.Time
+}
+
+func NewDispatcher(outbox *repository.OutboxRepo, channels ...Channel) *Dispatcher {
+	d := &Dispatcher{
+		outbox:   outbox,
+		channels: make(map[string]Channel),
+		backoff:  DefaultBackoff,
+		interval: 2 * time.Second,
+		batch:    100,
+		now:      time.Now,
+	}
+	for _, ch := range channels {
+		d.channels[ch.Name()] = ch
+	}
+	return d
+}
+
+// Run работает до отмены ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("notify: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick выполняет один проход: раскладку новых событий и отправку наступивших доставок.
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	if _, err := d.outbox.FanOut(ctx, d.batch, d.route); err != nil {
+		return err
+	}
+	deliveries, msgs, err := d.outbox.ClaimDue(ctx, d.batch, time.Minute)
+	if err != nil {
+		return err
+	}
+	for i, dl := range deliveries {
+		if err := d.deliver(ctx, dl, msgs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) route(ctx context.Context, msg model.OutboxMessage) ([]model.DeliveryTarget, error) {
+	var targets []model.DeliveryTarget
+	for name, ch := range d.channels {
+		ids, err := ch.Route(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			targets = append(targets, model.DeliveryTarget{Channel: name, Target: id})
+		}
+	}
+	return targets, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl model.Delivery, msg model.OutboxMessage) error {
+	attempt := repository.DeliveryAttempt{}
+	ch, ok := d.channels[dl.Channel]
+	if !ok {
+		attempt.Err = errUnknownChannel
+	} else {
+		attempt.StatusCode, attempt.Err = ch.Send(ctx, dl.Target, msg)
+	}
+	if attempt.Err != nil && ok && !isPermanent(attempt.Err) {
+		if delay, retry := d.backoff.Delay(dl.Attempts + 1); retry {
+			next := d.now().Add(delay)
+			attempt.Next = &next
+		}
+	}
+	return d.outbox.RecordAttempt(ctx, dl.ID, attempt)
+}
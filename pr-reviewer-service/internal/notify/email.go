@@ -0,0 +1,317 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+const ChannelEmail = "email"
+
+// Mailer отправляет письмо с текстовой и HTML-версией.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, text, html string) error
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер. Без Username работает без аутентификации.
+// Вся SMTP-сессия ограничена дедлайном ctx и Timeout (по умолчанию 10 секунд).
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+func (m SMTPMailer) Send(ctx context.Context, to, subject, text, html string) error {
+	msg, err := buildMessage(m.From, to, subject, text, html)
+	if err != nil {
+		return permanent(err)
+	}
+	err = m.send(ctx, to, msg)
+	// 5xx — отказ сервера (неверный адрес и т.п.), повтор не поможет.
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+		return permanent(err)
+	}
+	return err
+}
+
+// send проводит SMTP-сессию вручную, как smtp.SendMail, но с таймаутами и отменой по ctx.
+func (m SMTPMailer) send(ctx context.Context, to string, msg []byte) error {
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// Отмена ctx обрывает зависшее чтение или запись.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return permanent(errors.New("smtp server does not support AUTH"))
+		}
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage собирает письмо multipart/alternative (text/plain + text/html).
+func buildMessage(from, to, subject, text, html string) ([]byte, error) {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return nil, errors.New("invalid address")
+	}
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	boundary := "b-" + hex.EncodeToString(raw)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ typ, body string }{{"text/plain", text}, {"text/html", html}} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.typ)
+		b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		b.WriteString(strings.ReplaceAll(part.body, "\n", "\r\n"))
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// EmailDirectory — то, что email-уведомлениям нужно знать о пользователях.
+type EmailDirectory interface {
+	GetUser(ctx context.Context, userID string) (model.User, error)
+	GetNotificationPreferences(ctx context.Context, userID string) (model.NotificationPreferences, error)
+}
+
+var _ EmailDirectory = (*repository.UsersRepo)(nil)
+
+// emailKind — вид письма.
+type emailKind string
+
+const (
+	emailAssigned emailKind = "assigned"
+	emailReplaced emailKind = "replaced"
+	emailReminder emailKind = "reminder"
+)
+
+func (k emailKind) enabled(p model.NotificationPreferences) bool {
+	switch k {
+	case emailAssigned:
+		return p.EmailAssigned
+	case emailReplaced:
+		return p.EmailReplaced
+	case emailReminder:
+		return p.EmailReminder
+	}
+	return false
+}
+
+// emailRecipients возвращает адресатов события и вид письма для каждого.
+func emailRecipients(topic model.EventTopic, ev prEvent) map[string]emailKind {
+	switch topic {
+	case model.TopicReviewerAssigned:
+		return map[string]emailKind{ev.UserID: emailAssigned}
+	case model.TopicReviewerReassigned:
+		return map[string]emailKind{ev.NewUserID: emailAssigned, ev.OldUserID: emailReplaced}
+	case model.TopicReviewReminder:
+		return map[string]emailKind{ev.UserID: emailReminder}
+	}
+	return nil
+}
+
+// EmailChannel отправляет ревьюверам письма о назначении, замене и ждущем ревью.
+// Получатель доставки — user_id; адрес и настройки читаются в момент отправки.
+type EmailChannel struct {
+	dir    EmailDirectory
+	mailer Mailer
+}
+
+func NewEmailChannel(dir EmailDirectory, mailer Mailer) *EmailChannel {
+	return &EmailChannel{dir: dir, mailer: mailer}
+}
+
+func (c *EmailChannel) Name() string { return ChannelEmail }
+
+func (c *EmailChannel) Route(ctx context.Context, msg model.OutboxMessage) ([]string, error) {
+	var ev prEvent
+	if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+		return nil, err
+	}
+	var targets []string
+	for uid, kind := range emailRecipients(msg.Topic, ev) {
+		if uid == "" {
+			continue
+		}
+		ok, err := c.wants(ctx, uid, kind)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			targets = append(targets, uid)
+		}
+	}
+	return targets, nil
+}
+
+func (c *EmailChannel) wants(ctx context.Context, userID string, kind emailKind) (bool, error) {
+	u, err := c.dir.GetUser(ctx, userID)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if u.Email == nil || *u.Email == "" {
+		return false, nil
+	}
+	prefs, err := c.dir.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return kind.enabled(prefs), nil
+}
+
+func (c *EmailChannel) Send(ctx context.Context, userID string, msg model.OutboxMessage) (int, error) {
+	var ev prEvent
+	if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+		return 0, permanent(err)
+	}
+	kind, ok := emailRecipients(msg.Topic, ev)[userID]
+	if !ok {
+		return 0, permanent(fmt.Errorf("user %s is not a recipient of %s", userID, msg.Topic))
+	}
+	u, err := c.dir.GetUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	prefs, err := c.dir.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if u.Email == nil || *u.Email == "" || !kind.enabled(prefs) {
+		return 0, permanent(errors.New("recipient has no email or opted out"))
+	}
+	subject, text, html, err := renderEmail(kind, emailData{Recipient: u.Username, Event: ev})
+	if err != nil {
+		return 0, permanent(err)
+	}
+	return 0, c.mailer.Send(ctx, *u.Email, subject, text, html)
+}
+
+type emailData struct {
+	Recipient string
+	Event     prEvent
+}
+
+// Шаблоны писем: тема, текстовая и HTML-версия для каждого вида.
+var (
+	emailSubjects = map[emailKind]*texttemplate.Template{
+		emailAssigned: texttemplate.Must(texttemplate.New("s").Parse(`Review requested: {{.Event.PullRequest.Name}}`)),
+		emailReplaced: texttemplate.Must(texttemplate.New("s").Parse(`You were replaced on {{.Event.PullRequest.Name}}`)),
+		emailReminder: texttemplate.Must(texttemplate.New("s").Parse(`{{.Event.PullRequest.Name}} is waiting on you`)),
+	}
+	emailTexts = map[emailKind]*texttemplate.Template{
+		emailAssigned: texttemplate.Must(texttemplate.New("t").Parse(`Hi {{.Recipient}},
+
+you were assigned to review "{{.Event.PullRequest.Name}}" ({{.Event.PullRequest.ID}}) by {{.Event.PullRequest.AuthorID}}.
+`)),
+		emailReplaced: texttemplate.Must(texttemplate.New("t").Parse(`Hi {{.Recipient}},
+
+you are no longer a reviewer of "{{.Event.PullRequest.Name}}" ({{.Event.PullRequest.ID}}); {{.Event.NewUserID}} took over.
+`)),
+		emailReminder: texttemplate.Must(texttemplate.New("t").Parse(`Hi {{.Recipient}},
+
+"{{.Event.PullRequest.Name}}" ({{.Event.PullRequest.ID}}) by {{.Event.PullRequest.AuthorID}} is waiting for your review{{with .Event.AssignedAt}} since {{.UTC.Format "2006-01-02 15:04 MST"}}{{end}}.
+`)),
+	}
+	emailHTMLs = map[emailKind]*htmltemplate.Template{
+		emailAssigned: htmltemplate.Must(htmltemplate.New("h").Parse(`<p>Hi {{.Recipient}},</p>
+<p>you were assigned to review <b>{{.Event.PullRequest.Name}}</b> (<code>{{.Event.PullRequest.ID}}</code>) by {{.Event.PullRequest.AuthorID}}.</p>`)),
+		emailReplaced: htmltemplate.Must(htmltemplate.New("h").Parse(`<p>Hi {{.Recipient}},</p>
+<p>you are no longer a reviewer of <b>{{.Event.PullRequest.Name}}</b> (<code>{{.Event.PullRequest.ID}}</code>); {{.Event.NewUserID}} took over.</p>`)),
+		emailReminder: htmltemplate.Must(htmltemplate.New("h").Parse(`<p>Hi {{.Recipient}},</p>
+<p><b>{{.Event.PullRequest.Name}}</b> (<code>{{.Event.PullRequest.ID}}</code>) by {{.Event.PullRequest.AuthorID}} is waiting for your review{{with .Event.AssignedAt}} since {{.UTC.Format "2006-01-02 15:04 MST"}}{{end}}.</p>`)),
+	}
+)
+
+func renderEmail(kind emailKind, data emailData) (subject, text, html string, err error) {
+	var s, t, h bytes.Buffer
+	if err = emailSubjects[kind].Execute(&s, data); err != nil {
+		return
+	}
+	if err = emailTexts[kind].Execute(&t, data); err != nil {
+		return
+	}
+	if err = emailHTMLs[kind].Execute(&h, data); err != nil {
+		return
+	}
+	return s.String(), t.String(), h.String(), nil
+}
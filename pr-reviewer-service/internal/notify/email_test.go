@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+// smtpStandIn — минимальный SMTP-сервер для тестов: принимает письма и складывает их в inbox.
+type smtpStandIn struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	inbox  []sentMail
+	reject bool // отвечать 550 на RCPT
+	silent bool // принять соединение и молчать
+}
+
+type sentMail struct {
+	from, to string
+	data     string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if s.silent {
+		io.Copy(io.Discard, r)
+		return
+	}
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 stand-in ESMTP")
+	var m sentMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 stand-in")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m = sentMail{from: strings.Trim(strings.TrimSpace(line)[10:], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.reject {
+				reply("550 no such user")
+				continue
+			}
+			m.to = strings.Trim(strings.TrimSpace(line)[8:], "<>")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			m.data = b.String()
+			s.mu.Lock()
+			s.inbox = append(s.inbox, m)
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpStandIn) mails() []sentMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentMail(nil), s.inbox...)
+}
+
+type fakeUsers struct {
+	users map[string]model.User
+	prefs map[string]model.NotificationPreferences
+}
+
+func (f fakeUsers) GetUser(_ context.Context, id string) (model.User, error) {
+	u, ok := f.users[id]
+	if !ok {
+		return model.User{}, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (f fakeUsers) GetNotificationPreferences(_ context.Context, id string) (model.NotificationPreferences, error) {
+	if p, ok := f.prefs[id]; ok {
+		return p, nil
+	}
+	return model.DefaultNotificationPreferences(id), nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestEmailChannel_ReassignmentOverSMTP(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	dir := fakeUsers{users: map[string]model.User{
+		"u2": {UserID: "u2", Username: "Bob", Email: strPtr("bob@example.com")},
+		"u5": {UserID: "u5", Username: "Eve", Email: strPtr("eve@example.com")},
+	}}
+	ch := NewEmailChannel(dir, SMTPMailer{Addr: srv.ln.Addr().String(), From: "reviewers@example.com"})
+	msg := prMessage(model.TopicReviewerReassigned, `"old_user_id":"u2","new_user_id":"u5"`)
+
+	targets, err := ch.Route(context.Background(), msg)
+	if err != nil || len(targets) != 2 {
+		t.Fatalf("route: %v %v", targets, err)
+	}
+	for _, uid := range []string{"u2", "u5"} {
+		if _, err := ch.Send(context.Background(), uid, msg); err != nil {
+			t.Fatalf("%s: %v", uid, err)
+		}
+	}
+
+	got := map[string]*mail.Message{}
+	for _, m := range srv.mails() {
+		if m.from != "reviewers@example.com" {
+			t.Errorf("from %q", m.from)
+		}
+		parsed, err := mail.ReadMessage(strings.NewReader(m.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[m.to] = parsed
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 mails, got %d", len(got))
+	}
+	if s := got["bob@example.com"].Header.Get("Subject"); s != "You were replaced on Add search" {
+		t.Errorf("bob subject %q", s)
+	}
+	if s := got["eve@example.com"].Header.Get("Subject"); s != "Review requested: Add search" {
+		t.Errorf("eve subject %q", s)
+	}
+	body, _ := io.ReadAll(got["bob@example.com"].Body)
+	for _, want := range []string{"Content-Type: text/plain", "Content-Type: text/html", "u5 took over", "<b>Add search</b>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("bob mail lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestEmailChannel_RespectsPreferencesAndMissingEmail(t *testing.T) {
+	dir := fakeUsers{
+		users: map[string]model.User{
+			"u2": {UserID: "u2", Email: strPtr("bob@example.com")},
+			"u3": {UserID: "u3"},
+		},
+		prefs: map[string]model.NotificationPreferences{
+			"u2": {UserID: "u2", EmailAssigned: false, EmailReplaced: true, EmailReminder: true},
+		},
+	}
+	ch := NewEmailChannel(dir, SMTPMailer{})
+	for _, uid := range []string{"u2", "u3", "unknown"} {
+		targets, err := ch.Route(context.Background(), prMessage(model.TopicReviewerAssigned, `"user_id":"`+uid+`"`))
+		if err != nil || len(targets) != 0 {
+			t.Errorf("%s: expected no recipients, got %v %v", uid, targets, err)
+		}
+	}
+	targets, _ := ch.Route(context.Background(), prMessage(model.TopicReviewReminder, `"user_id":"u2"`))
+	if len(targets) != 1 {
+		t.Errorf("reminder must still be sent, got %v", targets)
+	}
+}
+
+func TestSMTPMailer_RejectedRecipientIsPermanent(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	srv.reject = true
+	err := SMTPMailer{Addr: srv.ln.Addr().String(), From: "reviewers@example.com"}.
+		Send(context.Background(), "nobody@example.com", "s", "t", "<p>h</p>")
+	if err == nil || !isPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestSMTPMailer_SilentServerTimesOut(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	srv.silent = true
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := SMTPMailer{Addr: srv.ln.Addr().String(), From: "reviewers@example.com"}.
+		Send(ctx, "bob@example.com", "s", "t", "<p>h</p>")
+	if err == nil || isPermanent(err) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("send must give up with ctx, took %v", d)
+	}
+}
+
+func TestRenderEmail_EscapesHTML(t *testing.T) {
+	var ev prEvent
+	ev.PullRequest.Name = "<script>x</script>"
+	_, text, html, err := renderEmail(emailAssigned, emailData{Recipient: "Bob", Event: ev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "<script>") || !strings.Contains(text, "<script>") {
+		t.Fatalf("html must be escaped, text must not:\n%s\n%s", html, text)
+	}
+}
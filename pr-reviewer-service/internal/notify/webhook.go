@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+const ChannelWebhook = "webhook"
+
+// Envelope — тело исходящего вебхука.
+type Envelope struct {
+	ID        int64            `json:"id"`
+	Event     model.EventTopic `json:"event"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      json.RawMessage  `json:"data"`
+}
+
+// Sign возвращает значение заголовка X-Signature-256: "sha256=<hex>" — HMAC-SHA256 тела на секрете подписки.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookChannel доставляет события подписчикам из webhook_subscriptions.
+type WebhookChannel struct {
+	subs   *repository.SubscriptionsRepo
+	client *http.Client
+}
+
+func NewWebhookChannel(subs *repository.SubscriptionsRepo, client *http.Client) *WebhookChannel {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookChannel{subs: subs, client: client}
+}
+
+func (c *WebhookChannel) Name() string { return ChannelWebhook }
+
+func (c *WebhookChannel) Route(ctx context.Context, msg model.OutboxMessage) ([]string, error) {
+	subs, err := c.subs.ListForTopic(ctx, msg.Topic)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(subs))
+	for _, s := range subs {
+		ids = append(ids, strconv.FormatInt(s.ID, 10))
+	}
+	return ids, nil
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, target string, msg model.OutboxMessage) (int, error) {
+	id, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return 0, permanent(err)
+	}
+	sub, err := c.subs.Get(ctx, id)
+	if err == repository.ErrNotFound {
+		return 0, permanent(errors.New("subscription deleted"))
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !sub.IsActive {
+		return 0, permanent(errors.New("subscription disabled"))
+	}
+	return Post(ctx, c.client, sub.URL, sub.Secret, msg)
+}
+
+// Post отправляет событие на url, подписав тело секретом. Ответ не из 2xx считается ошибкой.
+func Post(ctx context.Context, client *http.Client, url, secret string, msg model.OutboxMessage) (int, error) {
+	body, err := json.Marshal(Envelope{ID: msg.ID, Event: msg.Topic, CreatedAt: msg.CreatedAt, Data: msg.Payload})
+	if err != nil {
+		return 0, permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", string(msg.Topic))
+	req.Header.Set("X-Event-Id", strconv.FormatInt(msg.ID, 10))
+	req.Header.Set("X-Signature-256", Sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestPost_SignsEnvelope(t *testing.T) {
+	msg := model.OutboxMessage{
+		ID:        7,
+		Topic:     model.TopicReviewerAssigned,
+		Payload:   json.RawMessage(`{"user_id":"u2"}`),
+		CreatedAt: time.Date(2025, 10, 24, 12, 0, 0, 0, time.UTC),
+	}
+	var got Envelope
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Signature-256") != Sign("s3cret", body) {
+			t.Errorf("bad signature %q", r.Header.Get("X-Signature-256"))
+		}
+		if r.Header.Get("X-Event") != "reviewer.assigned" || r.Header.Get("X-Event-Id") != "7" {
+			t.Errorf("bad headers %v", r.Header)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	code, err := Post(context.Background(), srv.Client(), srv.URL, "s3cret", msg)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("code %d, err %v", code, err)
+	}
+	if got.ID != 7 || got.Event != model.TopicReviewerAssigned || string(got.Data) != `{"user_id":"u2"}` {
+		t.Fatalf("unexpected envelope %+v", got)
+	}
+}
+
+func TestPost_Non2xxIsRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	code, err := Post(context.Background(), srv.Client(), srv.URL, "s", model.OutboxMessage{Payload: json.RawMessage(`{}`)})
+	if err == nil || code != http.StatusBadGateway || isPermanent(err) {
+		t.Fatalf("code %d, err %v", code, err)
+	}
+}
+
+func TestWebhookChannel_BadTargetIsPermanent(t *testing.T) {
+	_, err := NewWebhookChannel(nil, nil).Send(context.Background(), "not-a-number", model.OutboxMessage{})
+	if !isPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 5 * time.Second, MaxAttempts: 5}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if d, ok := b.Delay(i + 1); !ok || d != w {
+			t.Fatalf("attempt %d: got %v %v, want %v", i+1, d, ok, w)
+		}
+	}
+	if _, ok := b.Delay(5); ok {
+		t.Fatal("expected attempts to be exhausted")
+	}
+}
@@ -0,0 +1,334 @@
+// Package orgfile читает и пишет оргструктуру — команды, их родителей и участников — в CSV и YAML.
+//
+// CSV — одна строка на участие пользователя в команде с заголовком из имён колонок
+// (team_name, parent_team, user_id, username, is_active, primary, review_weight, level);
+// обязательна только team_name, строка без user_id задаёт команду без участников.
+// YAML — список teams, у каждой команды team_name, parent_team и members с теми же полями.
+package orgfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// Format — формат файла оргструктуры.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	YAML Format = "yaml"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ParseFormat разбирает имя формата: csv, yaml или yml.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return CSV, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType возвращает MIME-тип формата.
+func (f Format) ContentType() string {
+	if f == YAML {
+		return "application/yaml"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+var columns = []string{"team_name", "parent_team", "user_id", "username", "is_active", "primary", "review_weight", "level"}
+
+// Read разбирает файл формата f. Ошибки в отдельных строках (синтаксис, неизвестные поля,
+// некорректные значения) возвращаются списком, а строки с ними пропускаются; error — только
+// ошибки чтения и неизвестный формат. Не заданный is_active считается true.
+func Read(r io.Reader, f Format) ([]model.OrgRow, []model.ImportRowError, error) {
+	switch f {
+	case CSV:
+		return readCSV(r)
+	case YAML:
+		return readYAML(r)
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+func readCSV(r io.Reader) ([]model.OrgRow, []model.ImportRowError, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return nil, []model.ImportRowError{{Line: perr.Line, Message: perr.Err.Error()}}, nil
+		}
+		return nil, nil, err
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if !known(name) {
+			return nil, []model.ImportRowError{{Line: 1, Message: fmt.Sprintf("unknown column %q", name)}}, nil
+		}
+		if _, dup := index[name]; dup {
+			return nil, []model.ImportRowError{{Line: 1, Message: fmt.Sprintf("duplicate column %q", name)}}, nil
+		}
+		index[name] = i
+	}
+	if _, ok := index["team_name"]; !ok {
+		return nil, []model.ImportRowError{{Line: 1, Message: "team_name column is required"}}, nil
+	}
+
+	var rows []model.OrgRow
+	var rowErrs []model.ImportRowError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, nil, err
+			}
+			rowErrs = append(rowErrs, model.ImportRowError{Line: perr.Line, Message: perr.Err.Error()})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := index[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := model.OrgRow{
+			TeamName:   field("team_name"),
+			ParentTeam: field("parent_team"),
+			UserID:     field("user_id"),
+			Username:   field("username"),
+			IsActive:   true,
+			Level:      model.Level(field("level")),
+			Line:       line,
+		}
+		var bad []string
+		if v := field("is_active"); v != "" {
+			if row.IsActive, err = strconv.ParseBool(v); err != nil {
+				bad = append(bad, "is_active must be a boolean")
+			}
+		}
+		if v := field("primary"); v != "" {
+			if row.Primary, err = strconv.ParseBool(v); err != nil {
+				bad = append(bad, "primary must be a boolean")
+			}
+		}
+		if v := field("review_weight"); v != "" {
+			if row.ReviewWeight, err = strconv.Atoi(v); err != nil {
+				bad = append(bad, "review_weight must be an integer")
+			}
+		}
+		if len(bad) > 0 {
+			for _, msg := range bad {
+				rowErrs = append(rowErrs, model.ImportRowError{Line: line, Message: msg})
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrs, nil
+}
+
+func known(column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+type yamlDoc struct {
+	Teams []yamlTeam `yaml:"teams"`
+}
+
+type yamlTeam struct {
+	TeamName   string       `yaml:"team_name"`
+	ParentTeam string       `yaml:"parent_team,omitempty"`
+	Members    []yamlMember `yaml:"members,omitempty"`
+}
+
+type yamlMember struct {
+	UserID       string      `yaml:"user_id"`
+	Username     string      `yaml:"username"`
+	IsActive     *bool       `yaml:"is_active,omitempty"`
+	Primary      bool        `yaml:"primary,omitempty"`
+	ReviewWeight int         `yaml:"review_weight,omitempty"`
+	Level        model.Level `yaml:"level,omitempty"`
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func readYAML(r io.Reader) ([]model.OrgRow, []model.ImportRowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var doc yamlDoc
+	if err := dec.Decode(&doc); err != nil && err != io.EOF {
+		// Ошибки типов yaml собирает по всему документу, остальные — останавливают разбор.
+		var terr *yaml.TypeError
+		if !errors.As(err, &terr) {
+			return nil, []model.ImportRowError{yamlError(err.Error())}, nil
+		}
+		var rowErrs []model.ImportRowError
+		for _, msg := range terr.Errors {
+			rowErrs = append(rowErrs, yamlError(msg))
+		}
+		return nil, rowErrs, nil
+	}
+	// Номера строк команд и участников берутся из дерева документа: структура уже проверена.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+	teamNodes := seqItems(mapValue(docNode(&root), "teams"))
+
+	var rows []model.OrgRow
+	for i, t := range doc.Teams {
+		teamNode := teamNodes[i]
+		if len(t.Members) == 0 {
+			rows = append(rows, model.OrgRow{TeamName: t.TeamName, ParentTeam: t.ParentTeam, IsActive: true, Line: teamNode.Line})
+			continue
+		}
+		memberNodes := seqItems(mapValue(teamNode, "members"))
+		for j, m := range t.Members {
+			active := m.IsActive == nil || *m.IsActive
+			rows = append(rows, model.OrgRow{
+				TeamName:     t.TeamName,
+				ParentTeam:   t.ParentTeam,
+				UserID:       m.UserID,
+				Username:     m.Username,
+				IsActive:     active,
+				Primary:      m.Primary,
+				ReviewWeight: m.ReviewWeight,
+				Level:        m.Level,
+				Line:         memberNodes[j].Line,
+			})
+		}
+	}
+	return rows, nil, nil
+}
+
+func docNode(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return n.Content[0]
+	}
+	return n
+}
+
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func seqItems(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// yamlError выделяет номер строки из сообщения yaml ("line 3: ...").
+func yamlError(msg string) model.ImportRowError {
+	if m := yamlLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return model.ImportRowError{Line: line, Message: m[2]}
+	}
+	return model.ImportRowError{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// Write записывает строки оргструктуры в формате f. Строки одной команды должны идти подряд.
+func Write(w io.Writer, f Format, rows []model.OrgRow) error {
+	switch f {
+	case CSV:
+		return writeCSV(w, rows)
+	case YAML:
+		return writeYAML(w, rows)
+	}
+	return ErrUnknownFormat
+}
+
+func writeCSV(w io.Writer, rows []model.OrgRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{r.TeamName, r.ParentTeam, r.UserID, r.Username, "", "", "", string(r.Level)}
+		if r.UserID != "" {
+			record[4] = strconv.FormatBool(r.IsActive)
+			if r.Primary {
+				record[5] = "true"
+			}
+			if r.ReviewWeight > 0 {
+				record[6] = strconv.Itoa(r.ReviewWeight)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeYAML(w io.Writer, rows []model.OrgRow) error {
+	var doc yamlDoc
+	for _, r := range rows {
+		if n := len(doc.Teams); n == 0 || doc.Teams[n-1].TeamName != r.TeamName {
+			doc.Teams = append(doc.Teams, yamlTeam{TeamName: r.TeamName, ParentTeam: r.ParentTeam})
+		}
+		if r.UserID == "" {
+			continue
+		}
+		active := r.IsActive
+		t := &doc.Teams[len(doc.Teams)-1]
+		t.Members = append(t.Members, yamlMember{
+			UserID:       r.UserID,
+			Username:     r.Username,
+			IsActive:     &active,
+			Primary:      r.Primary,
+			ReviewWeight: r.ReviewWeight,
+			Level:        r.Level,
+		})
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,131 @@
+package orgfile
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+var sample = []model.OrgRow{
+	{TeamName: "backend", ParentTeam: "platform", UserID: "u1", Username: "Alice", IsActive: true, Primary: true, ReviewWeight: 2, Level: model.LevelSenior},
+	{TeamName: "backend", ParentTeam: "platform", UserID: "u2", Username: "Bob", IsActive: false},
+	{TeamName: "platform", IsActive: true},
+}
+
+// withoutLines убирает номера строк, которых нет в исходных данных.
+func withoutLines(rows []model.OrgRow) []model.OrgRow {
+	res := append([]model.OrgRow(nil), rows...)
+	for i := range res {
+		res[i].Line = 0
+	}
+	return res
+}
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	for _, f := range []Format{CSV, YAML} {
+		var buf bytes.Buffer
+		if err := Write(&buf, f, sample); err != nil {
+			t.Fatalf("%s: write: %v", f, err)
+		}
+		rows, rowErrs, err := Read(&buf, f)
+		if err != nil || len(rowErrs) > 0 {
+			t.Fatalf("%s: read: %v %v", f, err, rowErrs)
+		}
+		if got := withoutLines(rows); !reflect.DeepEqual(got, sample) {
+			t.Fatalf("%s: got %+v, want %+v", f, got, sample)
+		}
+	}
+}
+
+func TestReadCSV_Lines(t *testing.T) {
+	text := "team_name,user_id,username,is_active,review_weight\n" +
+		"backend,u1,Alice,,\n" +
+		"backend,u2,Bob,maybe,x\n" +
+		"backend,u3\n" +
+		"frontend,u4,Carol,false,3\n"
+	rows, rowErrs, err := Read(strings.NewReader(text), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || !rows[0].IsActive || rows[1].Line != 5 || rows[1].IsActive || rows[1].ReviewWeight != 3 {
+		t.Fatalf("rows: %+v", rows)
+	}
+	var lines []int
+	for _, e := range rowErrs {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 3, 4}) {
+		t.Fatalf("errors: %+v", rowErrs)
+	}
+}
+
+func TestReadCSV_BadHeader(t *testing.T) {
+	for _, text := range []string{"user_id,username\nu1,Alice\n", "team_name,email\nbackend,a@b\n"} {
+		_, rowErrs, err := Read(strings.NewReader(text), CSV)
+		if err != nil || len(rowErrs) != 1 || rowErrs[0].Line != 1 {
+			t.Fatalf("%q: %v %+v", text, err, rowErrs)
+		}
+	}
+}
+
+func TestReadYAML_Lines(t *testing.T) {
+	text := `teams:
+  - team_name: platform
+  - team_name: backend
+    parent_team: platform
+    members:
+      - user_id: u1
+        username: Alice
+      - user_id: u2
+        username: Bob
+        is_active: false
+`
+	rows, rowErrs, err := Read(strings.NewReader(text), YAML)
+	if err != nil || len(rowErrs) > 0 {
+		t.Fatalf("%v %+v", err, rowErrs)
+	}
+	want := []model.OrgRow{
+		{TeamName: "platform", IsActive: true, Line: 2},
+		{TeamName: "backend", ParentTeam: "platform", UserID: "u1", Username: "Alice", IsActive: true, Line: 6},
+		{TeamName: "backend", ParentTeam: "platform", UserID: "u2", Username: "Bob", Line: 8},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("got %+v", rows)
+	}
+}
+
+func TestReadYAML_Errors(t *testing.T) {
+	text := `teams:
+  - team_name: backend
+    members:
+      - user_id: u1
+        username: Alice
+        email: a@example.com
+      - user_id: u2
+        review_weight: heavy
+`
+	_, rowErrs, err := Read(strings.NewReader(text), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrs) != 2 || rowErrs[0].Line != 6 || rowErrs[1].Line != 8 {
+		t.Fatalf("errors: %+v", rowErrs)
+	}
+
+	_, rowErrs, _ = Read(strings.NewReader("teams: [\n"), YAML)
+	if len(rowErrs) != 1 {
+		t.Fatalf("syntax error must be reported, got %+v", rowErrs)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("YML"); err != nil || f != YAML {
+		t.Fatalf("got %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err != ErrUnknownFormat {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+const availabilityColumns = `id, user_id, starts_at, ends_at, reason, auto_reassign, reassigned_at, created_at`
+
+func scanAvailability(row interface{ Scan(...any) error }) (model.Availability, error) {
+	var a model.Availability
+	err := row.Scan(&a.ID, &a.UserID, &a.StartsAt, &a.EndsAt, &a.Reason, &a.AutoReassign, &a.ReassignedAt, &a.CreatedAt)
+	return a, err
+}
+
+// CreateAvailability добавляет период недоступности. Если пользователя нет, возвращается ErrNotFound.
+func (r *UsersRepo) CreateAvailability(ctx context.Context, a model.Availability) (model.Availability, error) {
+	created, err := scanAvailability(r.db.QueryRowContext(ctx, `
+        INSERT INTO user_availability (user_id, starts_at, ends_at, reason, auto_reassign)
+        VALUES ($1,$2,$3,$4,$5)
+        RETURNING `+availabilityColumns,
+		a.UserID, a.StartsAt, a.EndsAt, a.Reason, a.AutoReassign))
+	if isForeignKeyViolation(err) {
+		return model.Availability{}, ErrNotFound
+	}
+	return created, err
+}
+
+// UpdateAvailability меняет период. Если период сдвинут на будущее, автопереназначение выполнится заново.
+func (r *UsersRepo) UpdateAvailability(ctx context.Context, a model.Availability) (model.Availability, error) {
+	updated, err := scanAvailability(r.db.QueryRowContext(ctx, `
+        UPDATE user_availability
+        SET starts_at=$2, ends_at=$3, reason=$4, auto_reassign=$5,
+            reassigned_at = CASE WHEN $2 > now() THEN NULL ELSE reassigned_at END
+        WHERE id=$1
+        RETURNING `+availabilityColumns,
+		a.ID, a.StartsAt, a.EndsAt, a.Reason, a.AutoReassign))
+	if err == sql.ErrNoRows {
+		return model.Availability{}, ErrNotFound
+	}
+	return updated, err
+}
+
+func (r *UsersRepo) GetAvailability(ctx context.Context, id int64) (model.Availability, error) {
+	a, err := scanAvailability(r.db.QueryRowContext(ctx, `
+        SELECT `+availabilityColumns+` FROM user_availability WHERE id=$1`, id))
+	if err == sql.ErrNoRows {
+		return model.Availability{}, ErrNotFound
+	}
+	return a, err
+}
+
+func (r *UsersRepo) DeleteAvailability(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_availability WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListAvailability возвращает ещё не закончившиеся периоды пользователя по порядку начала.
+func (r *UsersRepo) ListAvailability(ctx context.Context, userID string) ([]model.Availability, error) {
+	if _, err := r.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return r.listAvailability(ctx, `user_id=$1 AND ends_at > now()`, userID)
+}
+
+// ListStartedAutoReassign возвращает идущие к моменту now периоды с auto_reassign,
+// по которым ревью ещё не переназначались.
+func (r *UsersRepo) ListStartedAutoReassign(ctx context.Context, now time.Time) ([]model.Availability, error) {
+	return r.listAvailability(ctx, `auto_reassign AND reassigned_at IS NULL AND starts_at <= $1 AND ends_at > $1`, now)
+}
+
+// MarkReassigned отмечает, что ревью по периоду переназначены.
+func (r *UsersRepo) MarkReassigned(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_availability SET reassigned_at=$2 WHERE id=$1`, id, at)
+	return err
+}
+
+func (r *UsersRepo) listAvailability(ctx context.Context, where string, arg any) ([]model.Availability, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+availabilityColumns+`
+        FROM user_availability
+        WHERE `+where+`
+        ORDER BY starts_at, id
+    `, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.Availability{}
+	for rows.Next() {
+		a, err := scanAvailability(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
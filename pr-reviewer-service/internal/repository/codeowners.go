@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// UnknownUsersError — в правилах упомянуты пользователи, которых нет в сервисе.
+type UnknownUsersError struct {
+	IDs []string
+}
+
+func (e *UnknownUsersError) Error() string {
+	return "unknown users: " + strings.Join(e.IDs, ", ")
+}
+
+// ReplaceCodeowners заменяет правила CODEOWNERS команды или репозитория. Для команды, которой нет,
+// возвращается ErrNotFound, для неизвестных владельцев — *UnknownUsersError.
+func (r *TeamsRepo) ReplaceCodeowners(ctx context.Context, scope model.CodeownersScope, name string, rules []model.CodeownersRule) error {
+	if scope == model.CodeownersTeam {
+		if err := r.ensureTeam(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	var owners []string
+	for _, rule := range rules {
+		owners = append(owners, rule.Owners...)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT DISTINCT o FROM unnest($1::text[]) AS o
+        WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.user_id = o)
+        ORDER BY o
+    `, pq.Array(owners))
+	if err != nil {
+		return err
+	}
+	var unknown []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		unknown = append(unknown, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return &UnknownUsersError{IDs: unknown}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM codeowners_rules WHERE scope=$1 AND scope_name=$2
+    `, scope, name); err != nil {
+		return err
+	}
+	for i, rule := range rules {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO codeowners_rules (scope, scope_name, position, pattern, owners)
+            VALUES ($1,$2,$3,$4,$5)
+        `, scope, name, i, rule.Pattern, pq.Array(rule.Owners)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListCodeowners возвращает правила команды или репозитория в порядке строк.
+func (r *TeamsRepo) ListCodeowners(ctx context.Context, scope model.CodeownersScope, name string) ([]model.CodeownersRule, error) {
+	if scope == model.CodeownersTeam {
+		if err := r.ensureTeam(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return r.listCodeowners(ctx, `scope=$1 AND scope_name=$2`, scope, name)
+}
+
+// CodeownersFor возвращает правила для PR команды team в репозитории repo: сначала правила команды,
+// затем репозитория. Так как действует последнее подходящее правило, правила репозитория важнее.
+func (r *TeamsRepo) CodeownersFor(ctx context.Context, team, repo string) ([]model.CodeownersRule, error) {
+	return r.listCodeowners(ctx, `(scope='team' AND scope_name=$1) OR (scope='repository' AND scope_name=$2)`, team, repo)
+}
+
+func (r *TeamsRepo) listCodeowners(ctx context.Context, where string, args ...any) ([]model.CodeownersRule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT pattern, owners FROM codeowners_rules
+        WHERE `+where+`
+        ORDER BY scope = 'repository', position
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.CodeownersRule{}
+	for rows.Next() {
+		var rule model.CodeownersRule
+		if err := rows.Scan(&rule.Pattern, pq.Array(&rule.Owners)); err != nil {
+			return nil, err
+		}
+		res = append(res, rule)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// appendEvent добавляет запись в журнал PR. Вызывается в транзакции самого изменения,
+// чтобы журнал и данные не расходились.
+func appendEvent(ctx context.Context, q DBTX, prID string, typ model.PREventType, userID string, payload map[string]any) error {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `
+        INSERT INTO pr_events (pull_request_id, event_type, user_id, payload)
+        VALUES ($1,$2,$3,$4)
+    `, prID, typ, sql.NullString{String: userID, Valid: userID != ""}, raw)
+	return err
+}
+
+// ListEvents возвращает журнал PR в порядке записи.
+func (r *PRsRepo) ListEvents(ctx context.Context, prID string) ([]model.PREvent, error) {
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT id, pull_request_id, event_type, user_id, payload, created_at
+        FROM pr_events WHERE pull_request_id=$1
+        ORDER BY id
+    `, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.PREvent{}
+	for rows.Next() {
+		var (
+			e      model.PREvent
+			userID sql.NullString
+			raw    []byte
+		)
+		if err := rows.Scan(&e.ID, &e.PullRequestID, &e.Type, &userID, &raw, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.UserID = userID.String
+		if err := json.Unmarshal(raw, &e.Payload); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// AppendEvent записывает в журнал PR событие, не привязанное к конкретному изменению репозитория
+// (например, итог массовой операции).
+func (r *PRsRepo) AppendEvent(ctx context.Context, prID string, typ model.PREventType, userID string, payload map[string]any) error {
+	return appendEvent(ctx, r.q(), prID, typ, userID, payload)
+}
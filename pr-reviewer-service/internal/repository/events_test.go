@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// recordingDB запоминает аргументы последнего ExecContext.
+type recordingDB struct {
+	DBTX
+	args []any
+}
+
+func (r *recordingDB) ExecContext(_ context.Context, _ string, args ...any) (sql.Result, error) {
+	r.args = args
+	return nil, nil
+}
+
+func TestAppendEvent_Args(t *testing.T) {
+	db := &recordingDB{}
+	if err := appendEvent(context.Background(), db, "pr-1", model.EventMerged, "", nil); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if user := db.args[2].(sql.NullString); user.Valid {
+		t.Fatalf("empty user_id must be stored as NULL, got %+v", user)
+	}
+	if payload := string(db.args[3].([]byte)); payload != "{}" {
+		t.Fatalf("nil payload must be stored as {}, got %s", payload)
+	}
+
+	payload := map[string]any{"old_user_id": "u2"}
+	if err := appendEvent(context.Background(), db, "pr-1", model.EventReassigned, "u3", payload); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if db.args[1] != model.EventReassigned || db.args[2] != (sql.NullString{String: "u3", Valid: true}) {
+		t.Fatalf("unexpected args %v", db.args)
+	}
+	if got := string(db.args[3].([]byte)); got != `{"old_user_id":"u2"}` {
+		t.Fatalf("payload: got %s", got)
+	}
+}
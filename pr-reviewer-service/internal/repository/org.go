@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// ExportOrg возвращает оргструктуру по строке на участие пользователя в команде, команды без
+// участников — отдельной строкой с пустым user_id. Порядок — по команде, затем по пользователю.
+// review_weight в основной команде — общий вес пользователя, в остальных — вес в команде, если задан.
+func (r *TeamsRepo) ExportOrg(ctx context.Context) ([]model.OrgRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT t.team_name, COALESCE(t.parent_team, ''),
+               COALESCE(u.user_id, ''), COALESCE(u.username, ''), COALESCE(u.is_active, FALSE),
+               COALESCE(u.team_name = t.team_name, FALSE),
+               CASE WHEN u.team_name = t.team_name THEN u.review_weight ELSE COALESCE(m.review_weight, 0) END,
+               COALESCE(u.level, '')
+        FROM teams t
+        LEFT JOIN team_memberships m ON m.team_name = t.team_name
+        LEFT JOIN users u ON u.user_id = m.user_id
+        ORDER BY t.team_name, u.user_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []model.OrgRow
+	for rows.Next() {
+		var row model.OrgRow
+		if err := rows.Scan(&row.TeamName, &row.ParentTeam, &row.UserID, &row.Username, &row.IsActive,
+			&row.Primary, &row.ReviewWeight, &row.Level); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// ImportOrg применяет оргструктуру одной транзакцией, ничего не удаляя: заводит недостающие команды,
+// пользователей и участия, задаёт непустые parent_team, обновляет имя и непустой уровень пользователей,
+// основную команду из строки с primary и веса. Пользователь без primary, у которого нет основной
+// команды (в том числе новый), получает основной команду своей первой строки; прежние участия
+// и активность уже заведённых пользователей не меняются.
+// Строки должны быть проверены заранее. Если родителя нет, возвращается ErrNotFound, если родители
+// образуют цикл — ErrTeamCycle. При dryRun транзакция откатывается, а результат показывает,
+// что было бы изменено.
+func (r *TeamsRepo) ImportOrg(ctx context.Context, rows []model.OrgRow, dryRun bool) (model.ImportResult, error) {
+	res := model.ImportResult{DryRun: dryRun}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	// Как и в SetParent, блокировка не даёт параллельным изменениям дерева образовать цикл.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE teams IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return res, err
+	}
+
+	// Сначала все команды, затем родители: родитель может идти в файле позже подкоманды.
+	created := make(map[string]bool)
+	for _, row := range rows {
+		if _, seen := created[row.TeamName]; seen {
+			continue
+		}
+		n, err := affected(tx.ExecContext(ctx, `
+            INSERT INTO teams(team_name) VALUES ($1) ON CONFLICT (team_name) DO NOTHING
+        `, row.TeamName))
+		if err != nil {
+			return res, err
+		}
+		created[row.TeamName] = n > 0
+		if n > 0 {
+			res.TeamsCreated++
+		}
+	}
+	parentSet := make(map[string]bool)
+	for _, row := range rows {
+		if row.ParentTeam == "" || parentSet[row.TeamName] {
+			continue
+		}
+		parentSet[row.TeamName] = true
+		n, err := affected(tx.ExecContext(ctx, `
+            UPDATE teams SET parent_team=$2 WHERE team_name=$1 AND parent_team IS DISTINCT FROM $2
+        `, row.TeamName, row.ParentTeam))
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return res, ErrNotFound
+			}
+			return res, err
+		}
+		if n > 0 && !created[row.TeamName] {
+			res.TeamsUpdated++
+		}
+	}
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, `
+        WITH RECURSIVE up(start, team_name) AS (
+            SELECT team_name, parent_team FROM teams WHERE parent_team IS NOT NULL
+            UNION
+            SELECT up.start, t.parent_team FROM up JOIN teams t ON t.team_name = up.team_name
+            WHERE t.parent_team IS NOT NULL
+        )
+        SELECT EXISTS (SELECT 1 FROM up WHERE team_name = start)
+    `).Scan(&cycle); err != nil {
+		return res, err
+	}
+	if cycle {
+		return res, ErrTeamCycle
+	}
+
+	// Пользователи: основная команда — из строки с primary, для новых — из первой строки.
+	first := make(map[string]model.OrgRow)
+	var order []string
+	for _, row := range rows {
+		if row.UserID == "" {
+			continue
+		}
+		prev, seen := first[row.UserID]
+		if !seen {
+			order = append(order, row.UserID)
+		}
+		if !seen || (row.Primary && !prev.Primary) {
+			first[row.UserID] = row
+		}
+	}
+	homeTeam := make(map[string]string, len(order))
+	createdUsers := make(map[string]bool)
+	updatedUsers := make(map[string]bool)
+	for _, id := range order {
+		row := first[id]
+		n, err := affected(tx.ExecContext(ctx, `
+            INSERT INTO users (user_id, username, team_name, is_active, level)
+            VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+            ON CONFLICT (user_id) DO NOTHING
+        `, id, row.Username, row.TeamName, row.IsActive, string(row.Level)))
+		if err != nil {
+			return res, err
+		}
+		if n > 0 {
+			createdUsers[id] = true
+			homeTeam[id] = row.TeamName
+			continue
+		}
+		var team string
+		if err := tx.QueryRowContext(ctx, `
+            SELECT COALESCE(team_name, '') FROM users WHERE user_id = $1 FOR UPDATE
+        `, id).Scan(&team); err != nil {
+			return res, err
+		}
+		if row.Primary || team == "" {
+			team = row.TeamName
+		}
+		homeTeam[id] = team
+		n, err = affected(tx.ExecContext(ctx, `
+            UPDATE users SET username = $2, level = COALESCE(NULLIF($3, ''), level), team_name = $4
+            WHERE user_id = $1 AND (username IS DISTINCT FROM $2
+                OR level IS DISTINCT FROM COALESCE(NULLIF($3, ''), level)
+                OR team_name IS DISTINCT FROM $4)
+        `, id, row.Username, string(row.Level), team))
+		if err != nil {
+			return res, err
+		}
+		if n > 0 {
+			updatedUsers[id] = true
+		}
+	}
+
+	// Участия. Вес в основной команде — общий вес пользователя, в остальных — вес в команде.
+	for _, row := range rows {
+		if row.UserID == "" {
+			continue
+		}
+		isHome := homeTeam[row.UserID] == row.TeamName
+		if isHome && row.ReviewWeight > 0 {
+			n, err := affected(tx.ExecContext(ctx, `
+                UPDATE users SET review_weight=$2 WHERE user_id=$1 AND review_weight <> $2
+            `, row.UserID, row.ReviewWeight))
+			if err != nil {
+				return res, err
+			}
+			if n > 0 && !createdUsers[row.UserID] {
+				updatedUsers[row.UserID] = true
+			}
+		}
+		var weight *int
+		if !isHome && row.ReviewWeight > 0 {
+			weight = &row.ReviewWeight
+		}
+		n, err := affected(tx.ExecContext(ctx, `
+            INSERT INTO team_memberships (team_name, user_id, review_weight) VALUES ($1, $2, $3)
+            ON CONFLICT (team_name, user_id) DO NOTHING
+        `, row.TeamName, row.UserID, weight))
+		if err != nil {
+			return res, err
+		}
+		if n > 0 {
+			res.MembershipsAdded++
+			continue
+		}
+		if weight != nil {
+			n, err := affected(tx.ExecContext(ctx, `
+                UPDATE team_memberships SET review_weight=$3
+                WHERE team_name=$1 AND user_id=$2 AND review_weight IS DISTINCT FROM $3
+            `, row.TeamName, row.UserID, *weight))
+			if err != nil {
+				return res, err
+			}
+			if n > 0 {
+				res.MembershipsUpdated++
+			}
+		}
+	}
+	res.UsersCreated = len(createdUsers)
+	res.UsersUpdated = len(updatedUsers)
+
+	if dryRun {
+		return res, nil
+	}
+	return res, tx.Commit()
+}
+
+// affected возвращает число строк, изменённых запросом.
+func affected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// Enqueue записывает исходящее событие в outbox. q — транзакция изменения, к которому относится событие:
+// если она откатится, событие не будет разослано.
+func Enqueue(ctx context.Context, q DBTX, topic model.EventTopic, data map[string]any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO outbox (topic, payload) VALUES ($1,$2)`, topic, raw)
+	return err
+}
+
+// enqueuePR записывает событие о PR; в data добавляется краткое описание PR (pull_request).
+func enqueuePR(ctx context.Context, q DBTX, topic model.EventTopic, prID string, data map[string]any) error {
+	if data == nil {
+		data = map[string]any{}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `
+        INSERT INTO outbox (topic, payload)
+        SELECT $1, jsonb_build_object('pull_request', jsonb_build_object(
+                   'pull_request_id', pull_request_id,
+                   'pull_request_name', pull_request_name,
+                   'author_id', author_id,
+                   'status', status,
+                   'source', source)) || $3::jsonb
+        FROM pull_requests WHERE pull_request_id=$2
+    `, topic, prID, raw)
+	return err
+}
+
+type OutboxRepo struct{ db *sql.DB }
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo { return &OutboxRepo{db: db} }
+
+// FanOut забирает до limit ещё не разосланных событий, создаёт по ним доставки получателям,
+// которых вернул route, и помечает события разосланными. Всё — одной транзакцией; параллельные
+// вызовы не берут одни и те же события.
+func (r *OutboxRepo) FanOut(ctx context.Context, limit int, route func(context.Context, model.OutboxMessage) ([]model.DeliveryTarget, error)) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, topic, payload, created_at
+        FROM outbox
+        WHERE dispatched_at IS NULL
+        ORDER BY id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `, limit)
+	if err != nil {
+		return 0, err
+	}
+	var msgs []model.OutboxMessage
+	for rows.Next() {
+		var m model.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, m := range msgs {
+		targets, err := route(ctx, m)
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range targets {
+			if _, err := tx.ExecContext(ctx, `
+                INSERT INTO deliveries (outbox_id, channel, target)
+                VALUES ($1,$2,$3)
+                ON CONFLICT DO NOTHING
+            `, m.ID, t.Channel, t.Target); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox SET dispatched_at=now() WHERE id=$1`, m.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(msgs), tx.Commit()
+}
+
+// ClaimDue забирает до limit доставок, время попытки которых наступило, и откладывает их на lease,
+// чтобы другой обработчик не отправил их одновременно.
+func (r *OutboxRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]model.Delivery, []model.OutboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        WITH due AS (
+            SELECT id FROM deliveries
+            WHERE status='PENDING' AND next_attempt_at <= now()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE deliveries d
+        SET next_attempt_at = now() + make_interval(secs => $2)
+        FROM due, outbox o
+        WHERE d.id = due.id AND o.id = d.outbox_id
+        RETURNING d.id, d.outbox_id, o.topic, d.channel, d.target, d.status, d.attempts, d.next_attempt_at,
+                  d.last_status_code, d.last_error, d.delivered_at, d.created_at, o.payload, o.created_at
+    `, limit, lease.Seconds())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		deliveries []model.Delivery
+		msgs       []model.OutboxMessage
+	)
+	for rows.Next() {
+		var (
+			d model.Delivery
+			m model.OutboxMessage
+		)
+		if err := rows.Scan(&d.ID, &d.OutboxID, &d.Topic, &d.Channel, &d.Target, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		m.ID, m.Topic = d.OutboxID, d.Topic
+		deliveries = append(deliveries, d)
+		msgs = append(msgs, m)
+	}
+	return deliveries, msgs, rows.Err()
+}
+
+// DeliveryAttempt — итог одной попытки доставки. Next == nil вместе с ошибкой означает,
+// что попытки исчерпаны.
+type DeliveryAttempt struct {
+	StatusCode int
+	Err        error
+	Next       *time.Time
+}
+
+// RecordAttempt сохраняет итог попытки доставки.
+func (r *OutboxRepo) RecordAttempt(ctx context.Context, id int64, a DeliveryAttempt) error {
+	status := model.DeliveryDelivered
+	var lastErr *string
+	if a.Err != nil {
+		msg := a.Err.Error()
+		lastErr = &msg
+		status = model.DeliveryFailed
+		if a.Next != nil {
+			status = model.DeliveryPending
+		}
+	}
+	var code *int
+	if a.StatusCode != 0 {
+		code = &a.StatusCode
+	}
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE deliveries
+        SET status=$2,
+            attempts = attempts + 1,
+            last_status_code=$3,
+            last_error=$4,
+            next_attempt_at = COALESCE($5, next_attempt_at),
+            delivered_at = CASE WHEN $2 = 'DELIVERED' THEN now() END
+        WHERE id=$1
+    `, id, status, code, lastErr, a.Next)
+	return err
+}
+
+// DeliveryFilter — фильтр журнала доставок; пустые поля не ограничивают выборку.
+type DeliveryFilter struct {
+	Channel string
+	Target  string
+	Status  model.DeliveryStatus
+	Limit   int
+}
+
+// ListDeliveries возвращает журнал доставок, новые сначала.
+func (r *OutboxRepo) ListDeliveries(ctx context.Context, f DeliveryFilter) ([]model.Delivery, error) {
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT d.id, d.outbox_id, o.topic, d.channel, d.target, d.status, d.attempts, d.next_attempt_at,
+               d.last_status_code, d.last_error, d.delivered_at, d.created_at
+        FROM deliveries d
+        JOIN outbox o ON o.id = d.outbox_id
+        WHERE ($1 = '' OR d.channel = $1)
+          AND ($2 = '' OR d.target = $2)
+          AND ($3 = '' OR d.status = $3)
+        ORDER BY d.id DESC
+        LIMIT $4
+    `, f.Channel, f.Target, f.Status, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.Delivery{}
+	for rows.Next() {
+		var d model.Delivery
+		if err := rows.Scan(&d.ID, &d.OutboxID, &d.Topic, &d.Channel, &d.Target, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+type SubscriptionsRepo struct{ db *sql.DB }
+
+func NewSubscriptionsRepo(db *sql.DB) *SubscriptionsRepo { return &SubscriptionsRepo{db: db} }
+
+func (r *SubscriptionsRepo) Create(ctx context.Context, s model.WebhookSubscription) (model.WebhookSubscription, error) {
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO webhook_subscriptions (url, secret, topics)
+        VALUES ($1,$2,$3)
+        RETURNING id, is_active, created_at
+    `, s.URL, s.Secret, pq.Array(topicStrings(s.Topics))).Scan(&s.ID, &s.IsActive, &s.CreatedAt)
+	return s, err
+}
+
+func (r *SubscriptionsRepo) Get(ctx context.Context, id int64) (model.WebhookSubscription, error) {
+	rows, err := r.list(ctx, `WHERE id=$1`, id)
+	if err != nil {
+		return model.WebhookSubscription{}, err
+	}
+	if len(rows) == 0 {
+		return model.WebhookSubscription{}, ErrNotFound
+	}
+	return rows[0], nil
+}
+
+func (r *SubscriptionsRepo) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	return r.list(ctx, ``)
+}
+
+// ListForTopic возвращает активные подписки, получающие события topic.
+func (r *SubscriptionsRepo) ListForTopic(ctx context.Context, topic model.EventTopic) ([]model.WebhookSubscription, error) {
+	return r.list(ctx, `WHERE is_active AND (cardinality(topics) = 0 OR $1 = ANY(topics))`, topic)
+}
+
+func (r *SubscriptionsRepo) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SubscriptionsRepo) list(ctx context.Context, where string, args ...any) ([]model.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, url, secret, topics, is_active, created_at
+        FROM webhook_subscriptions `+where+`
+        ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.WebhookSubscription{}
+	for rows.Next() {
+		var (
+			s      model.WebhookSubscription
+			topics []string
+		)
+		if err := rows.Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&topics), &s.IsActive, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Topics = make([]model.EventTopic, 0, len(topics))
+		for _, t := range topics {
+			s.Topics = append(s.Topics, model.EventTopic(t))
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+func topicStrings(topics []model.EventTopic) []string {
+	res := make([]string, 0, len(topics))
+	for _, t := range topics {
+		res = append(res, string(t))
+	}
+	return res
+}
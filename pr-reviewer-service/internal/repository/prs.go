@@ -4,18 +4,38 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
+
+	"github.com/lib/pq"
 
 	"pr-reviewer-service/internal/model"
 )
 
-type PRsRepo struct{ db *sql.DB }
+type PRsRepo struct {
+	db *sql.DB
+	tx *sql.Tx
+}
 
 func NewPRsRepo(db *sql.DB) *PRsRepo { return &PRsRepo{db: db} }
 
-var ErrPRExists = errors.New("pr exists")
+// WithTx возвращает копию репозитория, работающую внутри внешней транзакции tx.
+func (r *PRsRepo) WithTx(tx *sql.Tx) *PRsRepo { return &PRsRepo{db: r.db, tx: tx} }
+
+func (r *PRsRepo) q() DBTX {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+var (
+	ErrPRExists = errors.New("pr exists")
+	// ErrStatusChanged — статус PR изменился между чтением и обновлением.
+	ErrStatusChanged = errors.New("pr status changed")
+)
 
 func (r *PRsRepo) CreateWithReviewers(ctx context.Context, pr model.PullRequest) (model.PullRequest, error) {
-	tx, err := r.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, r.db, r.tx)
 	if err != nil {
 		return model.PullRequest{}, err
 	}
@@ -31,23 +51,70 @@ func (r *PRsRepo) CreateWithReviewers(ctx context.Context, pr model.PullRequest)
 		return model.PullRequest{}, err
 	}
 
+	if pr.Status == "" {
+		pr.Status = model.PRStatusOpen
+	}
+	if pr.Source == "" {
+		pr.Source = model.SourceManual
+	}
 	err = tx.QueryRowContext(ctx, `
-        INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status)
-        VALUES ($1,$2,$3,'OPEN')
+        INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, understaffed,
+                                   source, external_project, external_iid, repository, changed_files,
+                                   required_tags, uncovered_tags, team_name)
+        VALUES ($1,$2,$3,$4,$5,$6,NULLIF($7,''),NULLIF($8,0),NULLIF($9,''),$10,$11,$12,NULLIF($13,''))
         RETURNING created_at
-    `, pr.ID, pr.Name, pr.AuthorID).Scan(&pr.CreatedAt)
+    `, pr.ID, pr.Name, pr.AuthorID, pr.Status, pr.Understaffed,
+		pr.Source, pr.ExternalProject, pr.ExternalIID, pr.Repository, pq.Array(nonNil(pr.ChangedFiles)),
+		pq.Array(nonNil(pr.RequiredTags)), pq.Array(nonNil(pr.UncoveredTags)), pr.TeamName).Scan(&pr.CreatedAt)
+	if isUniqueViolation(err) {
+		return model.PullRequest{}, ErrPRExists
+	}
 	if err != nil {
 		return model.PullRequest{}, err
 	}
-	pr.Status = model.PRStatusOpen
 
-	for _, rid := range pr.AssignedReviewers {
-		if _, err := tx.ExecContext(ctx, `
-           INSERT INTO pull_request_reviewers (pull_request_id, user_id)
-           VALUES ($1,$2)
-        `, pr.ID, rid); err != nil {
+	if err := appendEvent(ctx, tx, pr.ID, model.EventCreated, pr.AuthorID, map[string]any{
+		"status":       pr.Status,
+		"understaffed": pr.Understaffed,
+		"source":       pr.Source,
+	}); err != nil {
+		return model.PullRequest{}, err
+	}
+
+	pr.AssignedReviewers = nil
+	for i, rv := range pr.Reviewers {
+		if err := tx.QueryRowContext(ctx, `
+           INSERT INTO pull_request_reviewers (pull_request_id, user_id, is_fallback, selection_reason, matched_paths, matched_tags)
+           VALUES ($1,$2,$3,$4,$5,$6)
+           RETURNING state, assigned_at
+        `, pr.ID, rv.UserID, rv.Fallback, selectionReason(rv), pq.Array(nonNil(rv.MatchedPaths)), pq.Array(nonNil(rv.MatchedTags))).
+			Scan(&pr.Reviewers[i].State, &pr.Reviewers[i].AssignedAt); err != nil {
 			return model.PullRequest{}, err
 		}
+		pr.Reviewers[i].Reason = selectionReason(rv)
+		if err := appendEvent(ctx, tx, pr.ID, model.EventReviewerAssigned, rv.UserID, map[string]any{
+			"fallback":         rv.Fallback,
+			"reason":           "created",
+			"selection_reason": selectionReason(rv),
+			"matched_paths":    nonNil(rv.MatchedPaths),
+			"matched_tags":     nonNil(rv.MatchedTags),
+		}); err != nil {
+			return model.PullRequest{}, err
+		}
+		if err := enqueuePR(ctx, tx, model.TopicReviewerAssigned, pr.ID, map[string]any{
+			"user_id":  rv.UserID,
+			"fallback": rv.Fallback,
+			"reason":   "created",
+		}); err != nil {
+			return model.PullRequest{}, err
+		}
+		pr.AssignedReviewers = append(pr.AssignedReviewers, rv.UserID)
+	}
+	if err := enqueuePR(ctx, tx, model.TopicPRCreated, pr.ID, map[string]any{
+		"reviewers":    nonNil(pr.AssignedReviewers),
+		"understaffed": pr.Understaffed,
+	}); err != nil {
+		return model.PullRequest{}, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -56,12 +123,53 @@ func (r *PRsRepo) CreateWithReviewers(ctx context.Context, pr model.PullRequest)
 	return pr, nil
 }
 
+// GetForUpdate блокирует строку PR до конца внешней транзакции (WithTx) и возвращает PR с ревьюверами,
+// прочитанными уже после блокировки. Изменения ревьюверов (они берут ту же строку FOR SHARE) ждут её конца.
+func (r *PRsRepo) GetForUpdate(ctx context.Context, id string) (model.PullRequest, error) {
+	if err := lockPR(ctx, r.q(), id, "FOR UPDATE"); err != nil {
+		return model.PullRequest{}, err
+	}
+	return r.GetWithReviewers(ctx, id)
+}
+
+// lockPR блокирует строку PR в режиме mode (FOR UPDATE или FOR SHARE). Если PR нет, возвращается ErrNotFound.
+func lockPR(ctx context.Context, q DBTX, id, mode string) error {
+	var tmp string
+	err := q.QueryRowContext(ctx, `SELECT pull_request_id FROM pull_requests WHERE pull_request_id=$1 `+mode, id).Scan(&tmp)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// lockOpenPR блокирует строку PR на чтение (FOR SHARE) и проверяет статус уже под блокировкой:
+// смена статуса, закоммиченная до неё, видна, а начатая позже ждёт конца транзакции.
+// Если PR нет, возвращается ErrNotFound, если он не OPEN — ErrStatusChanged.
+func lockOpenPR(ctx context.Context, q DBTX, id string) error {
+	var status model.PRStatus
+	err := q.QueryRowContext(ctx, `SELECT status FROM pull_requests WHERE pull_request_id=$1 FOR SHARE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != model.PRStatusOpen {
+		return ErrStatusChanged
+	}
+	return nil
+}
+
 func (r *PRsRepo) GetWithReviewers(ctx context.Context, id string) (model.PullRequest, error) {
 	var pr model.PullRequest
-	err := r.db.QueryRowContext(ctx, `
-        SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+	err := r.q().QueryRowContext(ctx, `
+        SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at, closed_at, understaffed,
+               source, COALESCE(external_project, ''), COALESCE(external_iid, 0),
+               COALESCE(repository, ''), changed_files, required_tags, uncovered_tags, COALESCE(team_name, '')
         FROM pull_requests WHERE pull_request_id=$1`, id).
-		Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt)
+		Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt, &pr.ClosedAt, &pr.Understaffed,
+			&pr.Source, &pr.ExternalProject, &pr.ExternalIID, &pr.Repository, pq.Array(&pr.ChangedFiles),
+			pq.Array(&pr.RequiredTags), pq.Array(&pr.UncoveredTags), &pr.TeamName)
 	if err == sql.ErrNoRows {
 		return model.PullRequest{}, ErrNotFound
 	}
@@ -69,19 +177,23 @@ func (r *PRsRepo) GetWithReviewers(ctx context.Context, id string) (model.PullRe
 		return model.PullRequest{}, err
 	}
 
-	rows, err := r.db.QueryContext(ctx, `
-        SELECT user_id FROM pull_request_reviewers WHERE pull_request_id=$1`, id)
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT user_id, is_fallback, state, assigned_at, reviewed_at, selection_reason, matched_paths, matched_tags
+        FROM pull_request_reviewers WHERE pull_request_id=$1
+        ORDER BY assigned_at, user_id`, id)
 	if err != nil {
 		return model.PullRequest{}, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var uid string
-		if err := rows.Scan(&uid); err != nil {
+		var rv model.ReviewerAssignment
+		if err := rows.Scan(&rv.UserID, &rv.Fallback, &rv.State, &rv.AssignedAt, &rv.ReviewedAt,
+			&rv.Reason, pq.Array(&rv.MatchedPaths), pq.Array(&rv.MatchedTags)); err != nil {
 			return model.PullRequest{}, err
 		}
-		pr.AssignedReviewers = append(pr.AssignedReviewers, uid)
+		pr.AssignedReviewers = append(pr.AssignedReviewers, rv.UserID)
+		pr.Reviewers = append(pr.Reviewers, rv)
 	}
 	if err := rows.Err(); err != nil {
 		return model.PullRequest{}, err
@@ -89,8 +201,17 @@ func (r *PRsRepo) GetWithReviewers(ctx context.Context, id string) (model.PullRe
 	return pr, nil
 }
 
-func (r *PRsRepo) Merge(ctx context.Context, id string) (model.PullRequest, error) {
-	_, err := r.db.ExecContext(ctx, `
+// Merge переводит OPEN PR в MERGED. Если передан override, в той же транзакции
+// записывается факт мержа в обход правил команды. Условия мержа проверяются вызывающим
+// в той же транзакции после GetForUpdate.
+func (r *PRsRepo) Merge(ctx context.Context, id string, override *model.MergeOverride) (model.PullRequest, error) {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
         UPDATE pull_requests
         SET status='MERGED', merged_at = COALESCE(merged_at, now())
         WHERE pull_request_id=$1 AND status='OPEN'
@@ -98,59 +219,382 @@ func (r *PRsRepo) Merge(ctx context.Context, id string) (model.PullRequest, erro
 	if err != nil {
 		return model.PullRequest{}, err
 	}
+	merged, err := res.RowsAffected()
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if merged > 0 {
+		payload := map[string]any{"forced": override != nil}
+		if override != nil {
+			if _, err := tx.ExecContext(ctx, `
+                INSERT INTO merge_overrides (pull_request_id, actor, reason, unmet_conditions)
+                VALUES ($1,$2,$3,$4)
+            `, id, override.Actor, override.Reason, pq.Array(override.Unmet)); err != nil {
+				return model.PullRequest{}, err
+			}
+			payload["actor"] = override.Actor
+			payload["reason"] = override.Reason
+			payload["unmet_conditions"] = override.Unmet
+		}
+		if err := appendEvent(ctx, tx, id, model.EventMerged, "", payload); err != nil {
+			return model.PullRequest{}, err
+		}
+		if err := enqueuePR(ctx, tx, model.TopicPRMerged, id, payload); err != nil {
+			return model.PullRequest{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.PullRequest{}, err
+	}
 	return r.GetWithReviewers(ctx, id)
 }
 
-func (r *PRsRepo) ListForReviewer(ctx context.Context, userID string) ([]model.PullRequest, error) {
-	rows, err := r.db.QueryContext(ctx, `
-        SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+// Transition меняет статус PR с from на to. Если статус успел измениться, возвращается ErrStatusChanged.
+func (r *PRsRepo) Transition(ctx context.Context, id string, from, to model.PRStatus) (model.PullRequest, error) {
+	return r.transition(ctx, id, from, to, nil)
+}
+
+// OpenWithReviewers переводит PR из from в OPEN и в той же транзакции назначает ревьюверов.
+// uncoveredTags — обязательные теги PR, которые не покрыл ни один ревьювер.
+func (r *PRsRepo) OpenWithReviewers(ctx context.Context, id string, from model.PRStatus, reviewers []model.ReviewerAssignment, understaffed bool, uncoveredTags []string) (model.PullRequest, error) {
+	return r.transition(ctx, id, from, model.PRStatusOpen, &staffing{reviewers: reviewers, understaffed: understaffed, uncoveredTags: uncoveredTags})
+}
+
+type staffing struct {
+	reviewers     []model.ReviewerAssignment
+	understaffed  bool
+	uncoveredTags []string
+}
+
+func (r *PRsRepo) transition(ctx context.Context, id string, from, to model.PRStatus, st *staffing) (model.PullRequest, error) {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE pull_requests
+        SET status=$3,
+            closed_at = CASE WHEN $3 = 'CLOSED' THEN now() END
+        WHERE pull_request_id=$1 AND status=$2
+    `, id, from, to)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if n == 0 {
+		return model.PullRequest{}, ErrStatusChanged
+	}
+	if err := appendEvent(ctx, tx, id, model.EventStatusChanged, "", map[string]any{"from": from, "to": to}); err != nil {
+		return model.PullRequest{}, err
+	}
+
+	if st != nil {
+		for _, rv := range st.reviewers {
+			if err := insertReviewer(ctx, tx, id, rv, "opened"); err != nil {
+				return model.PullRequest{}, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE pull_requests SET understaffed=$2, uncovered_tags=$3 WHERE pull_request_id=$1
+        `, id, st.understaffed, pq.Array(nonNil(st.uncoveredTags))); err != nil {
+			return model.PullRequest{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.PullRequest{}, err
+	}
+	return r.GetWithReviewers(ctx, id)
+}
+
+// ListForReviewer возвращает PR, где пользователь назначен ревьювером.
+// Если states не пуст, возвращаются только ревью в указанных состояниях.
+func (r *PRsRepo) ListForReviewer(ctx context.Context, userID string, states []model.ReviewState) ([]model.AssignedReview, error) {
+	filter := make([]string, 0, len(states))
+	for _, st := range states {
+		filter = append(filter, string(st))
+	}
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, COALESCE(pr.team_name, ''),
+               r.user_id, r.is_fallback, r.state, r.assigned_at, r.reviewed_at, r.selection_reason, r.matched_paths,
+               r.matched_tags
         FROM pull_requests pr
         INNER JOIN pull_request_reviewers r ON pr.pull_request_id = r.pull_request_id
         WHERE r.user_id=$1
+          AND (cardinality($2::text[]) = 0 OR r.state = ANY($2::text[]))
         ORDER BY pr.pull_request_id
-    `, userID)
+    `, userID, pq.Array(filter))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var prs []model.PullRequest
+	var res []model.AssignedReview
 	for rows.Next() {
-		var pr model.PullRequest
-		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status); err != nil {
+		var a model.AssignedReview
+		if err := rows.Scan(&a.PullRequest.ID, &a.PullRequest.Name, &a.PullRequest.AuthorID, &a.PullRequest.Status,
+			&a.PullRequest.TeamName, &a.Review.UserID, &a.Review.Fallback, &a.Review.State, &a.Review.AssignedAt, &a.Review.ReviewedAt,
+			&a.Review.Reason, pq.Array(&a.Review.MatchedPaths), pq.Array(&a.Review.MatchedTags)); err != nil {
 			return nil, err
 		}
-		prs = append(prs, pr)
+		res = append(res, a)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return prs, nil
+	return res, nil
 }
 
-func (r *PRsRepo) RemoveReviewer(ctx context.Context, prID, userID string) error {
-	_, err := r.db.ExecContext(ctx, `
+// SetReviewState меняет состояние ревью назначенного ревьювера открытого PR.
+// Если пользователь не назначен на PR, возвращается ErrNotFound, если PR уже не OPEN — ErrStatusChanged.
+func (r *PRsRepo) SetReviewState(ctx context.Context, prID, userID string, state model.ReviewState) error {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+        UPDATE pull_request_reviewers
+        SET state=$3, reviewed_at=now()
+        WHERE pull_request_id=$1 AND user_id=$2
+    `, prID, userID, state)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	if err := appendEvent(ctx, tx, prID, model.EventReviewSubmitted, userID, map[string]any{"state": state}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceReviewer снимает oldUserID с PR и назначает вместо него rv одной транзакцией; reason попадает в журнал.
+// Если PR уже не OPEN, возвращается ErrStatusChanged.
+func (r *PRsRepo) ReplaceReviewer(ctx context.Context, prID, oldUserID string, rv model.ReviewerAssignment, reason string) error {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+        DELETE FROM pull_request_reviewers
+        WHERE pull_request_id=$1 AND user_id=$2
+    `, prID, oldUserID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO pull_request_reviewers(pull_request_id, user_id, is_fallback, selection_reason, matched_paths, matched_tags)
+        VALUES ($1,$2,$3,$4,$5,$6)
+    `, prID, rv.UserID, rv.Fallback, selectionReason(rv), pq.Array(nonNil(rv.MatchedPaths)), pq.Array(nonNil(rv.MatchedTags))); err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"old_user_id":      oldUserID,
+		"new_user_id":      rv.UserID,
+		"fallback":         rv.Fallback,
+		"reason":           reason,
+		"selection_reason": selectionReason(rv),
+	}
+	if err := appendEvent(ctx, tx, prID, model.EventReassigned, rv.UserID, payload); err != nil {
+		return err
+	}
+	if err := enqueuePR(ctx, tx, model.TopicReviewerReassigned, prID, payload); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveReviewer снимает ревьювера с открытого PR; reason попадает в журнал.
+// Если PR уже не OPEN, возвращается ErrStatusChanged.
+func (r *PRsRepo) RemoveReviewer(ctx context.Context, prID, userID, reason string) error {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
         DELETE FROM pull_request_reviewers
         WHERE pull_request_id=$1 AND user_id=$2
     `, prID, userID)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := appendEvent(ctx, tx, prID, model.EventReviewerRemoved, userID, map[string]any{"reason": reason}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *PRsRepo) AddReviewer(ctx context.Context, prID, userID string) error {
-	_, err := r.db.ExecContext(ctx, `
-        INSERT INTO pull_request_reviewers(pull_request_id, user_id)
-        VALUES ($1,$2)
+// AddReviewer назначает ревьювера на открытый PR; reason попадает в журнал.
+// Если PR уже не OPEN, возвращается ErrStatusChanged.
+func (r *PRsRepo) AddReviewer(ctx context.Context, prID string, rv model.ReviewerAssignment, reason string) error {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenPR(ctx, tx, prID); err != nil {
+		return err
+	}
+	if err := insertReviewer(ctx, tx, prID, rv, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertReviewer назначает ревьювера, если он ещё не назначен, и записывает это в журнал.
+func insertReviewer(ctx context.Context, q DBTX, prID string, rv model.ReviewerAssignment, reason string) error {
+	res, err := q.ExecContext(ctx, `
+        INSERT INTO pull_request_reviewers(pull_request_id, user_id, is_fallback, selection_reason, matched_paths, matched_tags)
+        VALUES ($1,$2,$3,$4,$5,$6)
         ON CONFLICT DO NOTHING
-    `, prID, userID)
+    `, prID, rv.UserID, rv.Fallback, selectionReason(rv), pq.Array(nonNil(rv.MatchedPaths)), pq.Array(nonNil(rv.MatchedTags)))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := appendEvent(ctx, q, prID, model.EventReviewerAssigned, rv.UserID, map[string]any{
+		"fallback":         rv.Fallback,
+		"reason":           reason,
+		"selection_reason": selectionReason(rv),
+		"matched_paths":    nonNil(rv.MatchedPaths),
+		"matched_tags":     nonNil(rv.MatchedTags),
+	}); err != nil {
+		return err
+	}
+	return enqueuePR(ctx, q, model.TopicReviewerAssigned, prID, map[string]any{
+		"user_id":  rv.UserID,
+		"fallback": rv.Fallback,
+		"reason":   reason,
+	})
+}
+
+// selectionReason — причина выбора ревьювера; если сервис её не указал, она выводится из Fallback.
+func selectionReason(rv model.ReviewerAssignment) model.SelectionReason {
+	switch {
+	case rv.Reason != "":
+		return rv.Reason
+	case rv.Fallback:
+		return model.ReasonFallbackTeam
+	}
+	return model.ReasonTeam
+}
+
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+func (r *PRsRepo) SetUnderstaffed(ctx context.Context, prID string, understaffed bool) error {
+	_, err := r.q().ExecContext(ctx, `
+        UPDATE pull_requests SET understaffed=$2 WHERE pull_request_id=$1
+    `, prID, understaffed)
 	return err
 }
 
+// RecentReviewers возвращает, сколько раз каждый пользователь назначался ревьювером на PR автора,
+// созданные начиная с since.
+func (r *PRsRepo) RecentReviewers(ctx context.Context, author string, since time.Time) (map[string]int, error) {
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT r.user_id, COUNT(*)
+        FROM pull_request_reviewers r
+        JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+        WHERE pr.author_id = $1 AND pr.created_at >= $2
+        GROUP BY r.user_id
+    `, author, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[string]int)
+	for rows.Next() {
+		var uid string
+		var n int
+		if err := rows.Scan(&uid, &n); err != nil {
+			return nil, err
+		}
+		res[uid] = n
+	}
+	return res, rows.Err()
+}
+
+// ListReviewerPairs возвращает число назначений по парам автор→ревьювер в PR, созданных начиная с since.
+// Если teams не пуст, учитываются только PR этих команд.
+func (r *PRsRepo) ListReviewerPairs(ctx context.Context, teams []string, since time.Time) ([]model.ReviewerPair, error) {
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT pr.author_id, r.user_id, COUNT(*)
+        FROM pull_request_reviewers r
+        JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+        WHERE pr.created_at >= $2 AND (cardinality($1::text[]) = 0 OR pr.team_name = ANY($1))
+        GROUP BY pr.author_id, r.user_id
+        ORDER BY pr.author_id, r.user_id
+    `, pq.Array(nonNil(teams)), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.ReviewerPair{}
+	for rows.Next() {
+		var p model.ReviewerPair
+		if err := rows.Scan(&p.AuthorID, &p.ReviewerID, &p.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
 func (r *PRsRepo) CountAssignmentsByReviewer(ctx context.Context) ([]model.ReviewerStat, error) {
-	rows, err := r.db.QueryContext(ctx, `
-        SELECT user_id, COUNT(*) AS assigned_count
-        FROM pull_request_reviewers
-        GROUP BY user_id
-        ORDER BY user_id
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT r.user_id,
+               COUNT(*) AS assigned_count,
+               COUNT(*) FILTER (WHERE pr.status = 'OPEN') AS open_count
+        FROM pull_request_reviewers r
+        JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+        GROUP BY r.user_id
+        ORDER BY r.user_id
     `)
 	if err != nil {
 		return nil, err
@@ -160,7 +604,7 @@ func (r *PRsRepo) CountAssignmentsByReviewer(ctx context.Context) ([]model.Revie
 	var stats []model.ReviewerStat
 	for rows.Next() {
 		var s model.ReviewerStat
-		if err := rows.Scan(&s.UserID, &s.AssignedCount); err != nil {
+		if err := rows.Scan(&s.UserID, &s.AssignedCount, &s.OpenCount); err != nil {
 			return nil, err
 		}
 		stats = append(stats, s)
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// ListOverdueReviews возвращает ревью в PENDING по открытым PR, которым к моменту now пора напомнить
+// или которые пора эскалировать по SLA команды PR.
+func (r *PRsRepo) ListOverdueReviews(ctx context.Context, now time.Time) ([]model.OverdueReview, error) {
+	def := model.DefaultTeamSettings("")
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT pull_request_id, user_id, team_name, assigned_at, reminded_at, escalated_at, remind_h, escalate_h
+        FROM (
+            SELECT r.pull_request_id, r.user_id, COALESCE(pr.team_name, '') AS team_name, r.assigned_at, r.reminded_at, r.escalated_at,
+                   COALESCE(ts.reminder_after_hours, $2) AS remind_h,
+                   COALESCE(ts.escalate_after_hours, $3) AS escalate_h
+            FROM pull_request_reviewers r
+            JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+            LEFT JOIN team_settings ts ON ts.team_name = pr.team_name
+            WHERE pr.status = 'OPEN' AND r.state = 'PENDING'
+        ) t
+        WHERE (escalate_h > 0 AND escalated_at IS NULL AND assigned_at <= $1::timestamptz - make_interval(hours => escalate_h))
+           OR (remind_h > 0 AND COALESCE(reminded_at, assigned_at) <= $1::timestamptz - make_interval(hours => remind_h))
+        ORDER BY assigned_at, pull_request_id, user_id
+    `, now, def.ReminderAfterHours, def.EscalateAfterHours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []model.OverdueReview
+	for rows.Next() {
+		var o model.OverdueReview
+		if err := rows.Scan(&o.PullRequestID, &o.UserID, &o.TeamName, &o.AssignedAt, &o.RemindedAt, &o.EscalatedAt,
+			&o.ReminderAfterHours, &o.EscalateAfterHours); err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, rows.Err()
+}
+
+// RemindReviewer отмечает напоминание ревьюверу и ставит в outbox событие review.reminder.
+// Если ревью уже не в PENDING или ревьювер снят, возвращается ErrNotFound.
+func (r *PRsRepo) RemindReviewer(ctx context.Context, prID, userID string, now time.Time) error {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var assignedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+        UPDATE pull_request_reviewers
+        SET reminded_at=$3
+        WHERE pull_request_id=$1 AND user_id=$2 AND state='PENDING'
+        RETURNING assigned_at
+    `, prID, userID, now).Scan(&assignedAt)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := enqueuePR(ctx, tx, model.TopicReviewReminder, prID, map[string]any{
+		"user_id":     userID,
+		"assigned_at": assignedAt,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordEscalation сохраняет эскалацию, пишет её в журнал PR и ставит в outbox событие review.escalated.
+// Если замены не нашлось, ревью помечается эскалированным, чтобы не эскалировать его повторно.
+func (r *PRsRepo) RecordEscalation(ctx context.Context, e model.Escalation) (model.Escalation, error) {
+	tx, err := beginTx(ctx, r.db, r.tx)
+	if err != nil {
+		return model.Escalation{}, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+        INSERT INTO review_escalations (pull_request_id, team_name, from_user_id, to_user_id, assigned_at)
+        VALUES ($1,$2,$3,$4,$5)
+        RETURNING id, created_at
+    `, e.PullRequestID, e.TeamName, e.FromUserID, e.ToUserID, e.AssignedAt).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return model.Escalation{}, err
+	}
+	if e.ToUserID == nil {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE pull_request_reviewers SET escalated_at=$3
+            WHERE pull_request_id=$1 AND user_id=$2
+        `, e.PullRequestID, e.FromUserID, e.CreatedAt); err != nil {
+			return model.Escalation{}, err
+		}
+	}
+	payload := map[string]any{
+		"from_user_id": e.FromUserID,
+		"to_user_id":   e.ToUserID,
+		"team_name":    e.TeamName,
+		"assigned_at":  e.AssignedAt,
+	}
+	if err := appendEvent(ctx, tx, e.PullRequestID, model.EventEscalated, e.FromUserID, payload); err != nil {
+		return model.Escalation{}, err
+	}
+	if err := enqueuePR(ctx, tx, model.TopicReviewEscalated, e.PullRequestID, payload); err != nil {
+		return model.Escalation{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return model.Escalation{}, err
+	}
+	return e, nil
+}
+
+// ListEscalations возвращает до limit эскалаций, новые первыми; пустой teams — по всем командам.
+func (r *PRsRepo) ListEscalations(ctx context.Context, teams []string, limit int) ([]model.Escalation, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := r.q().QueryContext(ctx, `
+        SELECT id, pull_request_id, team_name, from_user_id, to_user_id, assigned_at, created_at
+        FROM review_escalations
+        WHERE cardinality($1::text[]) = 0 OR team_name = ANY($1)
+        ORDER BY id DESC
+        LIMIT $2
+    `, pq.Array(nonNil(teams)), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.Escalation{}
+	for rows.Next() {
+		var e model.Escalation
+		if err := rows.Scan(&e.ID, &e.PullRequestID, &e.TeamName, &e.FromUserID, &e.ToUserID, &e.AssignedAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+
+	"github.com/lib/pq"
 
 	"pr-reviewer-service/internal/model"
 )
 
 var ErrTeamExists = errors.New("team exists")
 var ErrNotFound = errors.New("not found")
+var ErrTeamCycle = errors.New("team hierarchy cycle")
 
 type TeamsRepo struct {
 	db *sql.DB
@@ -17,6 +21,8 @@ type TeamsRepo struct {
 
 func NewTeamsRepo(db *sql.DB) *TeamsRepo { return &TeamsRepo{db: db} }
 
+// CreateTeamWithMembers создаёт команду с участниками (см. insertMembers). Если родительской
+// команды нет, возвращается ErrNotFound.
 func (r *TeamsRepo) CreateTeamWithMembers(ctx context.Context, t model.Team) (model.Team, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -34,48 +40,356 @@ func (r *TeamsRepo) CreateTeamWithMembers(ctx context.Context, t model.Team) (mo
 		return model.Team{}, err
 	}
 
-	if _, err := tx.ExecContext(ctx, `INSERT INTO teams(team_name) VALUES ($1)`, t.TeamName); err != nil {
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO teams(team_name, parent_team) VALUES ($1, NULLIF($2, ''))
+    `, t.TeamName, t.ParentTeam); err != nil {
+		if isForeignKeyViolation(err) {
+			return model.Team{}, ErrNotFound
+		}
+		return model.Team{}, err
+	}
+	if err := insertMembers(ctx, tx, t.TeamName, t.Members); err != nil {
+		return model.Team{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return model.Team{}, err
 	}
+	return t, nil
+}
+
+// MembersExistError — часть участников уже состоит в команде.
+type MembersExistError struct {
+	IDs []string
+}
+
+func (e *MembersExistError) Error() string {
+	return "users already in team: " + strings.Join(e.IDs, ", ")
+}
 
-	for _, m := range t.Members {
-		_, err := tx.ExecContext(ctx, `
-            INSERT INTO users (user_id, username, team_name, is_active)
-            VALUES ($1,$2,$3,$4)
-            ON CONFLICT (user_id) DO UPDATE
-              SET username = EXCLUDED.username,
-                  team_name = EXCLUDED.team_name,
-                  is_active = EXCLUDED.is_active
-        `, m.UserID, m.Username, t.TeamName, m.IsActive)
+// insertMembers добавляет участников в команду team. Новые пользователи заводятся с этой командой
+// в качестве основной; уже заведённые становятся её участниками, сохраняя основную команду и свои
+// данные, а review_weight задаёт их вес в этой команде. Если кто-то уже состоит в команде,
+// ничего не добавляется и возвращается *MembersExistError.
+func insertMembers(ctx context.Context, tx *sql.Tx, team string, members []model.TeamMember) error {
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	rows, err := tx.QueryContext(ctx, `
+        SELECT user_id FROM team_memberships WHERE team_name = $1 AND user_id = ANY($2) ORDER BY user_id
+    `, team, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var existing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		existing = append(existing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return &MembersExistError{IDs: existing}
+	}
+
+	for _, m := range members {
+		weight := m.ReviewWeight
+		if weight <= 0 {
+			weight = 1
+		}
+		res, err := tx.ExecContext(ctx, `
+            INSERT INTO users (user_id, username, team_name, is_active, review_weight, max_open_reviews)
+            VALUES ($1,$2,$3,$4,$5,$6)
+            ON CONFLICT (user_id) DO NOTHING
+        `, m.UserID, m.Username, team, m.IsActive, weight, m.MaxOpenReviews)
 		if err != nil {
-			return model.Team{}, err
+			return err
+		}
+		// Вес в команде задаётся только уже заведённым пользователям: у новых он общий.
+		created, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		var teamWeight *int
+		if created == 0 && m.ReviewWeight > 0 {
+			teamWeight = &m.ReviewWeight
+		}
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO team_memberships (team_name, user_id, review_weight) VALUES ($1,$2,$3)
+        `, team, m.UserID, teamWeight); err != nil {
+			if isUniqueViolation(err) {
+				return &MembersExistError{IDs: []string{m.UserID}}
+			}
+			return err
 		}
 	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
+// AddMembers добавляет участников в существующую команду (см. insertMembers). Для команды, которой нет,
+// возвращается ErrNotFound, для тех, кто уже в ней состоит, — *MembersExistError.
+func (r *TeamsRepo) AddMembers(ctx context.Context, team string, members []model.TeamMember) (model.Team, error) {
+	err := InTx(ctx, r.db, func(tx *sql.Tx) error {
+		var tmp string
+		err := tx.QueryRowContext(ctx, `SELECT team_name FROM teams WHERE team_name=$1 FOR UPDATE`, team).Scan(&tmp)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return insertMembers(ctx, tx, team, members)
+	})
+	if err != nil {
 		return model.Team{}, err
 	}
-	return t, nil
+	return r.GetTeam(ctx, team, nil, false)
 }
 
-func (r *TeamsRepo) GetTeam(ctx context.Context, name string) (model.Team, error) {
+// RenameTeam переименовывает команду. Участники, настройки и запасные команды переходят на новое имя
+// по внешним ключам, правила CODEOWNERS команды и её эскалации — здесь же.
+// Для команды, которой нет, возвращается ErrNotFound, если новое имя занято — ErrTeamExists.
+func (r *TeamsRepo) RenameTeam(ctx context.Context, name, newName string) (model.Team, error) {
+	err := InTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE teams SET team_name=$2 WHERE team_name=$1`, name, newName)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrTeamExists
+			}
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE codeowners_rules SET scope_name=$2 WHERE scope='team' AND scope_name=$1
+        `, name, newName); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE review_escalations SET team_name=$2 WHERE team_name=$1`, name, newName)
+		return err
+	})
+	if err != nil {
+		return model.Team{}, err
+	}
+	return r.GetTeam(ctx, newName, nil, false)
+}
+
+// SetParent задаёт родительскую команду; пустая делает команду командой верхнего уровня.
+// Если одной из команд нет, возвращается ErrNotFound, если родитель — сама команда или её
+// подкоманда, — ErrTeamCycle.
+func (r *TeamsRepo) SetParent(ctx context.Context, team, parent string) (model.Team, error) {
+	err := InTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Блокировка таблицы не даёт двум параллельным изменениям вместе образовать цикл.
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE teams IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		if parent != "" {
+			var cycle bool
+			if err := tx.QueryRowContext(ctx, `
+                WITH RECURSIVE up AS (
+                    SELECT team_name, parent_team FROM teams WHERE team_name = $2
+                    UNION ALL
+                    SELECT t.team_name, t.parent_team FROM teams t JOIN up ON t.team_name = up.parent_team
+                )
+                SELECT EXISTS (SELECT 1 FROM up WHERE team_name = $1)
+            `, team, parent).Scan(&cycle); err != nil {
+				return err
+			}
+			if cycle {
+				return ErrTeamCycle
+			}
+		}
+		res, err := tx.ExecContext(ctx, `
+            UPDATE teams SET parent_team=NULLIF($2, '') WHERE team_name=$1
+        `, team, parent)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Team{}, err
+	}
+	return r.GetTeam(ctx, team, nil, false)
+}
+
+// Subtree возвращает команду и все её подкоманды, от верхних уровней к нижним.
+// Если команды нет, возвращается ErrNotFound.
+func (r *TeamsRepo) Subtree(ctx context.Context, team string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        WITH RECURSIVE sub AS (
+            SELECT team_name, 0 AS depth FROM teams WHERE team_name = $1
+            UNION ALL
+            SELECT t.team_name, sub.depth + 1 FROM teams t JOIN sub ON t.parent_team = sub.team_name
+        )
+        SELECT team_name FROM sub ORDER BY depth, team_name
+    `, team)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res = append(res, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// ListParents возвращает родительскую команду каждой команды; у команд верхнего уровня — пустую строку.
+func (r *TeamsRepo) ListParents(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT team_name, COALESCE(parent_team, '') FROM teams`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[string]string)
+	for rows.Next() {
+		var team, parent string
+		if err := rows.Scan(&team, &parent); err != nil {
+			return nil, err
+		}
+		res[team] = parent
+	}
+	return res, rows.Err()
+}
+
+// SetMemberWeight задаёт вес участника в команде для стратегии weighted; nil — общий вес пользователя.
+// Если пользователь не состоит в команде, возвращается ErrNotFound.
+func (r *TeamsRepo) SetMemberWeight(ctx context.Context, team, userID string, weight *int) error {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE team_memberships SET review_weight=$3 WHERE team_name=$1 AND user_id=$2
+    `, team, userID, weight)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+var ErrTeamNotEmpty = errors.New("team not empty")
+
+// TeamInUseError — команда указана запасной у других команд.
+type TeamInUseError struct {
+	Teams []string
+}
+
+func (e *TeamInUseError) Error() string {
+	return "team is a fallback of: " + strings.Join(e.Teams, ", ")
+}
+
+// DeleteTeam удаляет команду без участников вместе с её настройками и правилами CODEOWNERS.
+// Для команды, которой нет, возвращается ErrNotFound, для команды с участниками или подкомандами — ErrTeamNotEmpty,
+// для запасной команды других команд — *TeamInUseError.
+func (r *TeamsRepo) DeleteTeam(ctx context.Context, name string) error {
+	return InTx(ctx, r.db, func(tx *sql.Tx) error {
+		var tmp string
+		err := tx.QueryRowContext(ctx, `SELECT team_name FROM teams WHERE team_name=$1 FOR UPDATE`, name).Scan(&tmp)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var members int
+		if err := tx.QueryRowContext(ctx, `
+            SELECT (SELECT COUNT(*) FROM team_memberships WHERE team_name=$1)
+                 + (SELECT COUNT(*) FROM teams WHERE parent_team=$1)
+        `, name).Scan(&members); err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrTeamNotEmpty
+		}
+
+		var teams []string
+		if err := tx.QueryRowContext(ctx, `
+            SELECT COALESCE(array_agg(team_name ORDER BY team_name), '{}') FROM team_fallbacks WHERE fallback_team=$1
+        `, name).Scan(pq.Array(&teams)); err != nil {
+			return err
+		}
+		if len(teams) > 0 {
+			return &TeamInUseError{Teams: teams}
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM codeowners_rules WHERE scope='team' AND scope_name=$1`, name); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM teams WHERE team_name=$1`, name)
+		return err
+	})
+}
+
+// GetTeam возвращает команду с участниками; если tags не пуст — только с участниками, у которых есть все эти теги.
+// Если subteams, в участники входят и участники всех подкоманд (каждый один раз, с ближайшей к команде
+// подкомандой в TeamName).
+func (r *TeamsRepo) GetTeam(ctx context.Context, name string, tags []string, subteams bool) (model.Team, error) {
 	var t model.Team
 	t.TeamName = name
 
-	row := r.db.QueryRowContext(ctx, `SELECT team_name FROM teams WHERE team_name=$1`, name)
-	if err := row.Scan(&t.TeamName); err != nil {
+	row := r.db.QueryRowContext(ctx, `
+        SELECT team_name, COALESCE(parent_team, ''),
+               ARRAY(SELECT c.team_name FROM teams c WHERE c.parent_team = teams.team_name ORDER BY c.team_name)
+        FROM teams WHERE team_name=$1
+    `, name)
+	if err := row.Scan(&t.TeamName, &t.ParentTeam, pq.Array(&t.Subteams)); err != nil {
 		if err == sql.ErrNoRows {
 			return model.Team{}, ErrNotFound
 		}
 		return model.Team{}, err
 	}
 
+	teams := []string{name}
+	if subteams {
+		var err error
+		if teams, err = r.Subtree(ctx, name); err != nil {
+			return model.Team{}, err
+		}
+	}
 	rows, err := r.db.QueryContext(ctx, `
-        SELECT user_id, username, is_active
-        FROM users
-        WHERE team_name=$1
-        ORDER BY user_id
-    `, name)
+        SELECT DISTINCT ON (u.user_id)
+               u.user_id, u.username, u.is_active, COALESCE(m.review_weight, u.review_weight), u.max_open_reviews,
+               u.tags, COALESCE(u.level, ''), u.team_name IS NOT DISTINCT FROM m.team_name, m.team_name
+        FROM team_memberships m
+        JOIN users u ON u.user_id = m.user_id
+        WHERE m.team_name = ANY($1) AND u.tags @> $2::text[]
+        ORDER BY u.user_id, array_position($1, m.team_name)
+    `, pq.Array(teams), pq.Array(nonNil(tags)))
 	if err != nil {
 		return model.Team{}, err
 	}
@@ -83,10 +397,135 @@ func (r *TeamsRepo) GetTeam(ctx context.Context, name string) (model.Team, error
 
 	for rows.Next() {
 		var m model.TeamMember
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.ReviewWeight, &m.MaxOpenReviews, pq.Array(&m.Tags), &m.Level, &m.Primary, &m.TeamName); err != nil {
 			return model.Team{}, err
 		}
+		if !subteams {
+			m.TeamName = ""
+		}
 		t.Members = append(t.Members, m)
 	}
 	return t, nil
 }
+
+// GetSettings возвращает настройки команды; если они не задавались, возвращаются значения по умолчанию.
+func (r *TeamsRepo) GetSettings(ctx context.Context, team string) (model.TeamSettings, error) {
+	if err := r.ensureTeam(ctx, team); err != nil {
+		return model.TeamSettings{}, err
+	}
+
+	settings := model.DefaultTeamSettings(team)
+	err := r.db.QueryRowContext(ctx, `
+        SELECT selection_strategy, reviewer_count, min_reviewers, understaffed_policy,
+               required_approvals, block_on_changes_requested, COALESCE(chat_webhook_url, ''),
+               reminder_after_hours, escalate_after_hours, pair_window_days,
+               min_senior_reviewers, forbid_junior_only
+        FROM team_settings
+        WHERE team_name=$1
+    `, team).Scan(&settings.SelectionStrategy, &settings.ReviewerCount, &settings.MinReviewers, &settings.UnderstaffedPolicy,
+		&settings.RequiredApprovals, &settings.BlockOnChangesRequested, &settings.ChatWebhookURL,
+		&settings.ReminderAfterHours, &settings.EscalateAfterHours, &settings.PairWindowDays,
+		&settings.MinSeniorReviewers, &settings.ForbidJuniorOnly)
+	if err != nil && err != sql.ErrNoRows {
+		return model.TeamSettings{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT fallback_team FROM team_fallbacks
+        WHERE team_name=$1
+        ORDER BY position
+    `, team)
+	if err != nil {
+		return model.TeamSettings{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fb string
+		if err := rows.Scan(&fb); err != nil {
+			return model.TeamSettings{}, err
+		}
+		settings.FallbackTeams = append(settings.FallbackTeams, fb)
+	}
+	if err := rows.Err(); err != nil {
+		return model.TeamSettings{}, err
+	}
+	return settings, nil
+}
+
+// UpsertSettings сохраняет настройки команды и заменяет список запасных команд.
+// Если команда или одна из запасных команд не существует, возвращается ErrNotFound.
+func (r *TeamsRepo) UpsertSettings(ctx context.Context, s model.TeamSettings) (model.TeamSettings, error) {
+	if err := r.ensureTeam(ctx, s.TeamName); err != nil {
+		return model.TeamSettings{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.TeamSettings{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO team_settings (team_name, selection_strategy, reviewer_count, min_reviewers, understaffed_policy,
+                                   required_approvals, block_on_changes_requested, chat_webhook_url,
+                                   reminder_after_hours, escalate_after_hours, pair_window_days,
+                                   min_senior_reviewers, forbid_junior_only)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8,''),$9,$10,$11,$12,$13)
+        ON CONFLICT (team_name) DO UPDATE
+          SET selection_strategy = EXCLUDED.selection_strategy,
+              reviewer_count = EXCLUDED.reviewer_count,
+              min_reviewers = EXCLUDED.min_reviewers,
+              understaffed_policy = EXCLUDED.understaffed_policy,
+              required_approvals = EXCLUDED.required_approvals,
+              block_on_changes_requested = EXCLUDED.block_on_changes_requested,
+              chat_webhook_url = EXCLUDED.chat_webhook_url,
+              reminder_after_hours = EXCLUDED.reminder_after_hours,
+              escalate_after_hours = EXCLUDED.escalate_after_hours,
+              pair_window_days = EXCLUDED.pair_window_days,
+              min_senior_reviewers = EXCLUDED.min_senior_reviewers,
+              forbid_junior_only = EXCLUDED.forbid_junior_only
+    `, s.TeamName, s.SelectionStrategy, s.ReviewerCount, s.MinReviewers, s.UnderstaffedPolicy,
+		s.RequiredApprovals, s.BlockOnChangesRequested, s.ChatWebhookURL,
+		s.ReminderAfterHours, s.EscalateAfterHours, s.PairWindowDays,
+		s.MinSeniorReviewers, s.ForbidJuniorOnly)
+	if err != nil {
+		return model.TeamSettings{}, err
+	}
+
+	var known int
+	if err := tx.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM teams WHERE team_name = ANY($1)
+    `, pq.Array(s.FallbackTeams)).Scan(&known); err != nil {
+		return model.TeamSettings{}, err
+	}
+	if known != len(s.FallbackTeams) {
+		return model.TeamSettings{}, ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM team_fallbacks WHERE team_name=$1`, s.TeamName); err != nil {
+		return model.TeamSettings{}, err
+	}
+	for i, fb := range s.FallbackTeams {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO team_fallbacks (team_name, fallback_team, position)
+            VALUES ($1,$2,$3)
+        `, s.TeamName, fb, i); err != nil {
+			return model.TeamSettings{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.TeamSettings{}, err
+	}
+	return s, nil
+}
+
+func (r *TeamsRepo) ensureTeam(ctx context.Context, team string) error {
+	var tmp string
+	err := r.db.QueryRowContext(ctx, `SELECT team_name FROM teams WHERE team_name=$1`, team).Scan(&tmp)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// DBTX — общее подмножество *sql.DB и *sql.Tx.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// InTx выполняет fn в транзакции и коммитит её, если fn не вернула ошибку.
+func InTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scopedTx — транзакция метода репозитория. Если репозиторий привязан к внешней транзакции
+// (WithTx), Commit и Rollback ничего не делают: транзакцией управляет её владелец.
+type scopedTx struct {
+	*sql.Tx
+	owned bool
+}
+
+func beginTx(ctx context.Context, db *sql.DB, outer *sql.Tx) (scopedTx, error) {
+	if outer != nil {
+		return scopedTx{Tx: outer}, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return scopedTx{}, err
+	}
+	return scopedTx{Tx: tx, owned: true}, nil
+}
+
+func (t scopedTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t scopedTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// isUniqueViolation сообщает, что запрос нарушил уникальный индекс (SQLSTATE 23505).
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation сообщает, что запрос сослался на несуществующую строку (SQLSTATE 23503).
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -3,22 +3,55 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
 
 	"pr-reviewer-service/internal/model"
 )
 
 type UsersRepo struct{ db *sql.DB }
 
+const userColumns = `user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews, chat_handle, email, work_schedule, tags, COALESCE(level, ''),
+    ARRAY(SELECT m.team_name FROM team_memberships m WHERE m.user_id = users.user_id ORDER BY m.team_name)`
+
+func scanUser(row interface{ Scan(...any) error }) (model.User, error) {
+	var u model.User
+	var schedule []byte
+	if err := row.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews, &u.ChatHandle, &u.Email, &schedule, pq.Array(&u.Tags), &u.Level, pq.Array(&u.Teams)); err != nil {
+		return model.User{}, err
+	}
+	if schedule != nil {
+		u.WorkSchedule = &model.WorkSchedule{}
+		if err := json.Unmarshal(schedule, u.WorkSchedule); err != nil {
+			return model.User{}, err
+		}
+	}
+	return u, nil
+}
+
 func NewUsersRepo(db *sql.DB) *UsersRepo { return &UsersRepo{db: db} }
 
 func (r *UsersRepo) SetIsActive(ctx context.Context, id string, active bool) (model.User, error) {
-	var u model.User
-	err := r.db.QueryRowContext(ctx, `
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
         UPDATE users
         SET is_active=$2
         WHERE user_id=$1
-        RETURNING user_id, username, team_name, is_active
-    `, id, active).Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
+        RETURNING `+userColumns, id, active))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// SetMaxOpenReviews задаёт лимит одновременных открытых ревью; nil снимает ограничение.
+func (r *UsersRepo) SetMaxOpenReviews(ctx context.Context, id string, limit *int) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET max_open_reviews=$2
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, limit))
 	if err == sql.ErrNoRows {
 		return model.User{}, ErrNotFound
 	}
@@ -26,20 +59,166 @@ func (r *UsersRepo) SetIsActive(ctx context.Context, id string, active bool) (mo
 }
 
 func (r *UsersRepo) GetUser(ctx context.Context, id string) (model.User, error) {
-	var u model.User
-	err := r.db.QueryRowContext(ctx, `
-        SELECT user_id, username, team_name, is_active
-        FROM users WHERE user_id=$1`, id).
-		Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        SELECT `+userColumns+` FROM users WHERE user_id=$1`, id))
 	if err == sql.ErrNoRows {
 		return model.User{}, ErrNotFound
 	}
 	return u, err
 }
 
+// List возвращает пользователей по фильтру: участники команды (если задана) и все теги из tags.
+func (r *UsersRepo) List(ctx context.Context, team string, tags []string) ([]model.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+userColumns+`
+        FROM users
+        WHERE ($1 = '' OR EXISTS (SELECT 1 FROM team_memberships m WHERE m.user_id = users.user_id AND m.team_name = $1))
+          AND tags @> $2::text[]
+        ORDER BY user_id
+    `, team, pq.Array(nonNil(tags)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+// AddTags добавляет пользователю теги; уже имеющиеся не дублируются.
+func (r *UsersRepo) AddTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	return r.updateTags(ctx, id, `ARRAY(SELECT DISTINCT t FROM unnest(tags || $2::text[]) t ORDER BY t)`, tags)
+}
+
+// RemoveTags снимает с пользователя указанные теги.
+func (r *UsersRepo) RemoveTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	return r.updateTags(ctx, id, `ARRAY(SELECT t FROM unnest(tags) t WHERE t <> ALL($2::text[]) ORDER BY t)`, tags)
+}
+
+// SetTags заменяет теги пользователя.
+func (r *UsersRepo) SetTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	return r.updateTags(ctx, id, `ARRAY(SELECT DISTINCT t FROM unnest($2::text[]) t ORDER BY t)`, tags)
+}
+
+func (r *UsersRepo) updateTags(ctx context.Context, id, expr string, tags []string) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET tags=`+expr+`
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, pq.Array(nonNil(tags))))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// ListByID возвращает указанных пользователей; несуществующие id пропускаются.
+func (r *UsersRepo) ListByID(ctx context.Context, ids []string) ([]model.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+userColumns+` FROM users WHERE user_id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []model.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+// SetLevel задаёт уровень пользователя; пустой убирает его.
+func (r *UsersRepo) SetLevel(ctx context.Context, id string, level model.Level) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET level=NULLIF($2, '')
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, level))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// SetTeam делает team основной командой пользователя вместо прежней: участие в прежней основной
+// команде заканчивается, в остальных сохраняется. Если пользователя или команды нет, возвращается ErrNotFound.
+func (r *UsersRepo) SetTeam(ctx context.Context, id, team string) (model.User, error) {
+	err := InTx(ctx, r.db, func(tx *sql.Tx) error {
+		var old sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT team_name FROM users WHERE user_id=$1 FOR UPDATE`, id).Scan(&old)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+            DELETE FROM team_memberships WHERE user_id=$1 AND team_name=$2
+        `, id, old.String); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO team_memberships (team_name, user_id) VALUES ($2, $1)
+            ON CONFLICT (team_name, user_id) DO NOTHING
+        `, id, team); err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE users SET team_name=$2 WHERE user_id=$1`, id, team)
+		return err
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return r.GetUser(ctx, id)
+}
+
+// RemoveFromTeam заканчивает участие пользователя в команде team. Если она была основной,
+// основной становится первая по имени из оставшихся (или никакая). Если пользователь не состоит
+// в команде, возвращается ErrNotFound.
+func (r *UsersRepo) RemoveFromTeam(ctx context.Context, id, team string) (model.User, error) {
+	err := InTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM team_memberships WHERE user_id=$1 AND team_name=$2`, id, team)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		_, err = tx.ExecContext(ctx, `
+            UPDATE users
+            SET team_name = (SELECT MIN(team_name) FROM team_memberships WHERE user_id=$1)
+            WHERE user_id=$1 AND team_name=$2
+        `, id, team)
+		return err
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return r.GetUser(ctx, id)
+}
+
+// ListByTeam возвращает пользователей, для которых команда основная.
 func (r *UsersRepo) ListByTeam(ctx context.Context, team string) ([]model.User, error) {
 	rows, err := r.db.QueryContext(ctx, `
-        SELECT user_id, username, team_name, is_active
+        SELECT `+userColumns+`
         FROM users
         WHERE team_name=$1
     `, team)
@@ -50,11 +229,211 @@ func (r *UsersRepo) ListByTeam(ctx context.Context, team string) ([]model.User,
 
 	var res []model.User
 	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
+		u, err := scanUser(rows)
+		if err != nil {
 			return nil, err
 		}
 		res = append(res, u)
 	}
 	return res, rows.Err()
 }
+
+// ListReviewCandidates возвращает активных участников команды (team_memberships) вместе с числом их
+// открытых ревью, весом в этой команде и рабочими часами. Пользователи, достигшие своего лимита
+// max_open_reviews или недоступные в момент at (user_availability), в выборку не попадают.
+func (r *UsersRepo) ListReviewCandidates(ctx context.Context, team string, at time.Time) ([]model.ReviewCandidate, error) {
+	return r.listReviewCandidates(ctx, team, `m.team_name IS NOT NULL`, at)
+}
+
+// ListReviewCandidatesOutside — то же, что ListReviewCandidates, но среди участников других команд,
+// не состоящих в указанной.
+func (r *UsersRepo) ListReviewCandidatesOutside(ctx context.Context, team string, at time.Time) ([]model.ReviewCandidate, error) {
+	return r.listReviewCandidates(ctx, team, `m.team_name IS NULL`, at)
+}
+
+// ListReviewCandidatesByID — то же, что ListReviewCandidates, но среди указанных пользователей из любых команд.
+func (r *UsersRepo) ListReviewCandidatesByID(ctx context.Context, ids []string, at time.Time) ([]model.ReviewCandidate, error) {
+	return r.listReviewCandidates(ctx, "", `u.user_id = ANY($3)`, at, pq.Array(ids))
+}
+
+// listReviewCandidates выбирает кандидатов по условию cond; вес берётся из участия в команде team,
+// а без него — общий вес пользователя. Пользователи вне команд кандидатами не бывают.
+func (r *UsersRepo) listReviewCandidates(ctx context.Context, team, cond string, at time.Time, args ...any) ([]model.ReviewCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT u.user_id, COALESCE(m.review_weight, u.review_weight), COUNT(pr.pull_request_id) AS open_count,
+               u.work_schedule, u.tags, COALESCE(u.level, '')
+        FROM users u
+        LEFT JOIN team_memberships m ON m.user_id = u.user_id AND m.team_name = $1
+        LEFT JOIN pull_request_reviewers r ON r.user_id = u.user_id
+        LEFT JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id AND pr.status = 'OPEN'
+        WHERE `+cond+` AND u.is_active=TRUE
+          AND EXISTS (SELECT 1 FROM team_memberships o WHERE o.user_id = u.user_id)
+          AND NOT EXISTS (
+              SELECT 1 FROM user_availability a
+              WHERE a.user_id = u.user_id AND a.starts_at <= $2 AND $2 < a.ends_at)
+        GROUP BY u.user_id, m.review_weight, u.review_weight, u.max_open_reviews, u.work_schedule, u.tags, u.level
+        HAVING u.max_open_reviews IS NULL OR COUNT(pr.pull_request_id) < u.max_open_reviews
+        ORDER BY u.user_id
+    `, append([]any{team, at}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []model.ReviewCandidate
+	for rows.Next() {
+		var c model.ReviewCandidate
+		var schedule []byte
+		if err := rows.Scan(&c.UserID, &c.Weight, &c.Load, &schedule, pq.Array(&c.Tags), &c.Level); err != nil {
+			return nil, err
+		}
+		if schedule != nil {
+			c.Schedule = &model.WorkSchedule{}
+			if err := json.Unmarshal(schedule, c.Schedule); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// SetWorkSchedule задаёт рабочие часы пользователя; nil убирает их.
+func (r *UsersRepo) SetWorkSchedule(ctx context.Context, id string, schedule *model.WorkSchedule) (model.User, error) {
+	var raw any
+	if schedule != nil {
+		b, err := json.Marshal(schedule)
+		if err != nil {
+			return model.User{}, err
+		}
+		raw = string(b)
+	}
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET work_schedule=$2
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, raw))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// SetChatHandle задаёт идентификатор пользователя в чате; nil убирает его.
+func (r *UsersRepo) SetChatHandle(ctx context.Context, id string, handle *string) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET chat_handle=$2
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, handle))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// SetEmail задаёт адрес для email-уведомлений; nil убирает его.
+func (r *UsersRepo) SetEmail(ctx context.Context, id string, email *string) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET email=$2
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, email))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// SetUsername меняет имя пользователя.
+func (r *UsersRepo) SetUsername(ctx context.Context, id, username string) (model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, `
+        UPDATE users
+        SET username=$2
+        WHERE user_id=$1
+        RETURNING `+userColumns, id, username))
+	if err == sql.ErrNoRows {
+		return model.User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// GetNotificationPreferences возвращает настройки уведомлений пользователя (по умолчанию — всё включено).
+func (r *UsersRepo) GetNotificationPreferences(ctx context.Context, id string) (model.NotificationPreferences, error) {
+	p := model.DefaultNotificationPreferences(id)
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COALESCE(np.email_assigned, TRUE), COALESCE(np.email_replaced, TRUE), COALESCE(np.email_reminder, TRUE)
+        FROM users u
+        LEFT JOIN notification_preferences np ON np.user_id = u.user_id
+        WHERE u.user_id=$1
+    `, id).Scan(&p.EmailAssigned, &p.EmailReplaced, &p.EmailReminder)
+	if err == sql.ErrNoRows {
+		return model.NotificationPreferences{}, ErrNotFound
+	}
+	return p, err
+}
+
+func (r *UsersRepo) UpsertNotificationPreferences(ctx context.Context, p model.NotificationPreferences) (model.NotificationPreferences, error) {
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO notification_preferences (user_id, email_assigned, email_replaced, email_reminder)
+        SELECT user_id, $2, $3, $4 FROM users WHERE user_id=$1
+        ON CONFLICT (user_id) DO UPDATE
+          SET email_assigned = EXCLUDED.email_assigned,
+              email_replaced = EXCLUDED.email_replaced,
+              email_reminder = EXCLUDED.email_reminder
+        RETURNING user_id
+    `, p.UserID, p.EmailAssigned, p.EmailReplaced, p.EmailReminder).Scan(&p.UserID)
+	if err == sql.ErrNoRows {
+		return model.NotificationPreferences{}, ErrNotFound
+	}
+	return p, err
+}
+
+// ChatHandles возвращает идентификаторы в чате для тех из ids, у кого они заданы.
+func (r *UsersRepo) ChatHandles(ctx context.Context, ids []string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT user_id, chat_handle FROM users
+        WHERE user_id = ANY($1) AND chat_handle IS NOT NULL
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[string]string)
+	for rows.Next() {
+		var id, handle string
+		if err := rows.Scan(&id, &handle); err != nil {
+			return nil, err
+		}
+		res[id] = handle
+	}
+	return res, rows.Err()
+}
+
+// LinkAccount связывает логин во внешней системе с пользователем. Повторная привязка логина
+// переносит его на нового пользователя.
+func (r *UsersRepo) LinkAccount(ctx context.Context, acc model.ExternalAccount) (model.ExternalAccount, error) {
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO external_accounts (provider, login, user_id)
+        SELECT $1, lower($2), user_id FROM users WHERE user_id=$3
+        ON CONFLICT (provider, login) DO UPDATE SET user_id = EXCLUDED.user_id
+        RETURNING provider, login, user_id
+    `, acc.Provider, acc.Login, acc.UserID).Scan(&acc.Provider, &acc.Login, &acc.UserID)
+	if err == sql.ErrNoRows {
+		return model.ExternalAccount{}, ErrNotFound
+	}
+	return acc, err
+}
+
+// UserIDByLogin возвращает пользователя, привязанного к логину во внешней системе.
+func (r *UsersRepo) UserIDByLogin(ctx context.Context, provider, login string) (string, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx, `
+        SELECT user_id FROM external_accounts WHERE provider=$1 AND login=lower($2)
+    `, provider, login).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return id, err
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// AvailabilityScheduler с началом периода недоступности с auto_reassign передаёт другим
+// PENDING-ревью пользователя. Работает со своим интервалом, независимо от планировщика SLA.
+type AvailabilityScheduler struct {
+	prs      *PRService
+	interval time.Duration
+}
+
+func NewAvailabilityScheduler(prs *PRService, interval time.Duration) *AvailabilityScheduler {
+	return &AvailabilityScheduler{prs: prs, interval: interval}
+}
+
+// Run работает до отмены ctx.
+func (s *AvailabilityScheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		reassigned, left, err := s.Tick(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("availability: %v", err)
+		}
+		if reassigned+left > 0 {
+			log.Printf("availability: away reviewers: reassigned %d, no candidate %d", reassigned, left)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick выполняет один проход: возвращает число переназначенных ревью и оставшихся без замены.
+func (s *AvailabilityScheduler) Tick(ctx context.Context) (int, int, error) {
+	return s.prs.reassignUnavailable(ctx, s.prs.now())
+}
+
+// reassignUnavailable передаёт другим PENDING-ревью в OPEN PR пользователей, у которых к моменту now
+// начался период недоступности с auto_reassign. Замена идёт тем же путём, что и Reassign.
+// Возвращает число переназначенных ревью и тех, для которых замены не нашлось (они остаются за пользователем).
+func (s *PRService) reassignUnavailable(ctx context.Context, now time.Time) (int, int, error) {
+	windows, err := s.users.ListStartedAutoReassign(ctx, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	reassigned, left := 0, 0
+	var errs []error
+	for _, w := range windows {
+		n, l, err := s.reassignPending(ctx, w.UserID, "", "unavailable")
+		reassigned += n
+		left += l
+		// При ошибке период остаётся необработанным и будет повторён на следующем проходе.
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.users.MarkReassigned(ctx, w.ID, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return reassigned, left, errors.Join(errs...)
+}
+
+// reassignPending передаёт другим PENDING-ревью пользователя userID в OPEN PR команды team (пустая —
+// любой команды) тем же путём, что и Reassign; reason попадает в журнал. Возвращает число переназначенных
+// ревью и тех, для которых замены не нашлось (они остаются за пользователем).
+func (s *PRService) reassignPending(ctx context.Context, userID, team, reason string) (int, int, error) {
+	reviews, err := s.prs.ListForReviewer(ctx, userID, []model.ReviewState{model.ReviewPending})
+	if err != nil {
+		return 0, 0, err
+	}
+	reassigned, left := 0, 0
+	var errs []error
+	for _, rv := range reviews {
+		if rv.PullRequest.Status != model.PRStatusOpen || (team != "" && rv.PullRequest.TeamName != team) {
+			continue
+		}
+		_, _, err := s.reassign(ctx, rv.PullRequest.ID, userID, reason)
+		switch err {
+		case nil:
+			reassigned++
+		case ErrNoCandidate:
+			left++
+		case ErrNotAssigned, ErrPRNotOpen, ErrPRMerged:
+			// PR изменился после выборки
+		default:
+			errs = append(errs, err)
+		}
+	}
+	return reassigned, left, errors.Join(errs...)
+}
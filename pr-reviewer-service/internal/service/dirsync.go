@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+// ErrEmptyDirectory — все группы каталога пусты; синхронизация не выполняется, чтобы ошибка
+// на стороне каталога не деактивировала всех пользователей.
+var ErrEmptyDirectory = errors.New("directory groups are empty")
+
+// Directory — внешний каталог пользователей (LDAP): группы, каждая из которых задаёт состав команды.
+type Directory interface {
+	Groups(ctx context.Context) ([]model.DirectoryGroup, error)
+}
+
+// DirectorySync приводит пользователей и команды к составу групп каталога. Управляемые команды —
+// те, для которых есть группа: их участники заводятся, переводятся и деактивируются по каталогу,
+// остальные команды и их участники не трогаются.
+type DirectorySync struct {
+	prs      *PRService
+	dir      Directory
+	interval time.Duration
+}
+
+func NewDirectorySync(prs *PRService, dir Directory, interval time.Duration) *DirectorySync {
+	return &DirectorySync{prs: prs, dir: dir, interval: interval}
+}
+
+// Run синхронизирует каталог раз в interval до отмены ctx.
+func (s *DirectorySync) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		res, err := s.Sync(ctx, false)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("directory sync: %v", err)
+		}
+		if n := len(res.Created) + len(res.Renamed) + len(res.Reactivated) + len(res.Joined) + len(res.Moved) + len(res.Left) + len(res.Deactivated); n > 0 {
+			log.Printf("directory sync: created %d, renamed %d, reactivated %d, joined %d, moved %d, left %d, deactivated %d, reassigned %d, no candidate %d",
+				len(res.Created), len(res.Renamed), len(res.Reactivated), len(res.Joined), len(res.Moved), len(res.Left), len(res.Deactivated), res.Reassigned, res.UnassignedLeft)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Sync читает группы каталога и применяет к базе разницу с ними: заводит недостающие команды
+// и пользователей, обновляет имена, возвращает активность, добавляет и снимает участия, переводит
+// основную команду и деактивирует пропавших из каталога. Ревью тех, кто покинул команду или
+// деактивирован, передаются другим ревьюверам. При dryRun возвращается только план.
+// При ошибке синхронизация прерывается; следующий проход доделает оставшееся.
+func (s *DirectorySync) Sync(ctx context.Context, dryRun bool) (model.SyncReport, error) {
+	groups, err := s.dir.Groups(ctx)
+	if err != nil {
+		return model.SyncReport{DryRun: dryRun}, err
+	}
+	users, err := s.prs.users.List(ctx, "", nil)
+	if err != nil {
+		return model.SyncReport{DryRun: dryRun}, err
+	}
+	teams, err := s.prs.teams.ListParents(ctx)
+	if err != nil {
+		return model.SyncReport{DryRun: dryRun}, err
+	}
+	plan, err := planSync(groups, users, teams)
+	if err != nil {
+		return model.SyncReport{DryRun: dryRun}, err
+	}
+	plan.report.DryRun = dryRun
+	if dryRun {
+		return plan.report, nil
+	}
+	return plan.report, s.apply(ctx, &plan)
+}
+
+func (s *DirectorySync) apply(ctx context.Context, plan *syncPlan) error {
+	rep := &plan.report
+	for _, t := range rep.CreatedTeams {
+		if _, err := s.prs.teams.CreateTeamWithMembers(ctx, model.Team{TeamName: t}); err != nil && err != repository.ErrTeamExists {
+			return err
+		}
+	}
+	// Новые пользователи получают основной команду, в которую добавлены первой.
+	for _, m := range rep.Created {
+		member := model.TeamMember{UserID: m.UserID, Username: plan.usernames[m.UserID], IsActive: true}
+		if _, err := s.prs.teams.AddMembers(ctx, m.TeamName, []model.TeamMember{member}); err != nil {
+			return err
+		}
+	}
+	for _, id := range rep.Renamed {
+		if _, err := s.prs.users.SetUsername(ctx, id, plan.usernames[id]); err != nil {
+			return err
+		}
+	}
+	for _, id := range rep.Reactivated {
+		if _, err := s.prs.users.SetIsActive(ctx, id, true); err != nil {
+			return err
+		}
+	}
+	for _, m := range rep.Joined {
+		member := model.TeamMember{UserID: m.UserID, Username: plan.usernames[m.UserID], IsActive: true}
+		_, err := s.prs.teams.AddMembers(ctx, m.TeamName, []model.TeamMember{member})
+		var exists *repository.MembersExistError
+		if err != nil && !errors.As(err, &exists) {
+			return err
+		}
+	}
+	for _, m := range rep.Moved {
+		res, err := s.prs.MoveMember(ctx, m.UserID, m.ToTeam, true)
+		if err != nil && err != ErrAlreadyMember {
+			return err
+		}
+		rep.Reassigned += res.Reassigned
+		rep.UnassignedLeft += res.UnassignedLeft
+	}
+	for _, m := range rep.Left {
+		res, err := s.prs.RemoveMember(ctx, m.TeamName, m.UserID, true)
+		if err != nil && err != ErrNotMember {
+			return err
+		}
+		rep.Reassigned += res.Reassigned
+		rep.UnassignedLeft += res.UnassignedLeft
+	}
+	if len(plan.deactivate) > 0 {
+		res, err := s.prs.deactivateUsers(ctx, plan.deactivate, deactivation{
+			reason:  "directory_sync",
+			event:   model.EventUsersDeactivated,
+			topic:   model.TopicUsersDeactivated,
+			payload: map[string]any{"source": "directory"},
+		})
+		if err != nil {
+			return err
+		}
+		rep.Reassigned += res.Reassigned
+		rep.UnassignedLeft += res.UnassignedLeft
+	}
+	return nil
+}
+
+// syncPlan — изменения, которые нужно применить; report описывает их, usernames — имена из каталога,
+// deactivate — пользователи, пропавшие из каталога.
+type syncPlan struct {
+	report     model.SyncReport
+	usernames  map[string]string
+	deactivate []model.User
+}
+
+// planSync сравнивает группы каталога с пользователями users и командами teams (команда → родитель).
+// Команды пользователя берутся в порядке групп; новый пользователь и тот, чья основная команда
+// управляемая, но в каталоге его в ней нет, получают основной первую из своих команд, где уже состоят
+// (иначе первую). Пользователь, которого нет ни в одной группе, деактивируется, если его основная
+// команда управляемая, иначе только снимается с управляемых команд.
+func planSync(groups []model.DirectoryGroup, users []model.User, teams map[string]string) (syncPlan, error) {
+	plan := syncPlan{usernames: make(map[string]string)}
+	rep := &plan.report
+
+	managed := make(map[string]bool, len(groups))
+	dirTeams := make(map[string][]string)
+	var ids []string
+	empty := true
+	for _, g := range groups {
+		if managed[g.TeamName] {
+			continue
+		}
+		managed[g.TeamName] = true
+		if _, ok := teams[g.TeamName]; !ok {
+			rep.CreatedTeams = append(rep.CreatedTeams, g.TeamName)
+		}
+		seen := make(map[string]bool, len(g.Members))
+		for _, m := range g.Members {
+			if seen[m.UserID] {
+				continue
+			}
+			seen[m.UserID] = true
+			empty = false
+			if _, ok := dirTeams[m.UserID]; !ok {
+				ids = append(ids, m.UserID)
+				plan.usernames[m.UserID] = m.Username
+			}
+			dirTeams[m.UserID] = append(dirTeams[m.UserID], g.TeamName)
+		}
+	}
+	if len(groups) > 0 && empty {
+		return syncPlan{}, ErrEmptyDirectory
+	}
+	sort.Strings(ids)
+
+	byID := make(map[string]model.User, len(users))
+	for _, u := range users {
+		byID[u.UserID] = u
+	}
+
+	for _, id := range ids {
+		want := dirTeams[id]
+		u, ok := byID[id]
+		if !ok {
+			rep.Created = append(rep.Created, model.SyncMembership{UserID: id, TeamName: want[0]})
+			for _, t := range want[1:] {
+				rep.Joined = append(rep.Joined, model.SyncMembership{UserID: id, TeamName: t})
+			}
+			continue
+		}
+		if u.Username != plan.usernames[id] {
+			rep.Renamed = append(rep.Renamed, id)
+		}
+		if !u.IsActive {
+			rep.Reactivated = append(rep.Reactivated, id)
+		}
+		home := u.TeamName
+		if home == "" || (managed[home] && !contains(want, home)) {
+			to := want[0]
+			for _, t := range want {
+				if inTeam(u, t) {
+					to = t
+					break
+				}
+			}
+			rep.Moved = append(rep.Moved, model.SyncMove{UserID: id, FromTeam: home, ToTeam: to})
+			home = to
+		}
+		for _, t := range want {
+			if t != home && !inTeam(u, t) {
+				rep.Joined = append(rep.Joined, model.SyncMembership{UserID: id, TeamName: t})
+			}
+		}
+		// Прежняя основная команда при переводе освобождается сама.
+		for _, t := range u.Teams {
+			if managed[t] && !contains(want, t) && t != u.TeamName {
+				rep.Left = append(rep.Left, model.SyncMembership{UserID: id, TeamName: t})
+			}
+		}
+	}
+
+	// Пропавшие из каталога: users упорядочены по user_id.
+	for _, u := range users {
+		if _, ok := dirTeams[u.UserID]; ok {
+			continue
+		}
+		if managed[u.TeamName] {
+			if u.IsActive {
+				rep.Deactivated = append(rep.Deactivated, u.UserID)
+				plan.deactivate = append(plan.deactivate, u)
+			}
+			continue
+		}
+		for _, t := range u.Teams {
+			if managed[t] {
+				rep.Left = append(rep.Left, model.SyncMembership{UserID: u.UserID, TeamName: t})
+			}
+		}
+	}
+	return plan, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
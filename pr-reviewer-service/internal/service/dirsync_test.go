@@ -0,0 +1,75 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestPlanSync(t *testing.T) {
+	groups := []model.DirectoryGroup{
+		{TeamName: "backend", Members: []model.DirectoryUser{{UserID: "u1", Username: "Alice"}, {UserID: "u2", Username: "Bobby"}, {UserID: "u5", Username: "Eve"}}},
+		{TeamName: "frontend", Members: []model.DirectoryUser{{UserID: "u3", Username: "Carol"}, {UserID: "u5", Username: "Eve"}}},
+		{TeamName: "mobile", Members: []model.DirectoryUser{{UserID: "u1", Username: "Alice"}}},
+	}
+	users := []model.User{
+		{UserID: "u1", Username: "Alice", TeamName: "backend", Teams: []string{"backend", "frontend"}, IsActive: true},
+		{UserID: "u2", Username: "Bob", TeamName: "backend", Teams: []string{"backend"}, IsActive: false},
+		{UserID: "u3", Username: "Carol", TeamName: "backend", Teams: []string{"backend", "frontend"}, IsActive: true},
+		{UserID: "u4", Username: "Dan", TeamName: "frontend", Teams: []string{"frontend"}, IsActive: true},
+		{UserID: "u6", Username: "Frank", TeamName: "sales", Teams: []string{"sales", "backend"}, IsActive: true},
+		{UserID: "u7", Username: "Grace", TeamName: "sales", Teams: []string{"sales"}, IsActive: true},
+	}
+	teams := map[string]string{"backend": "", "frontend": "", "sales": ""}
+
+	plan, err := planSync(groups, users, teams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.SyncReport{
+		CreatedTeams: []string{"mobile"},
+		Created:      []model.SyncMembership{{UserID: "u5", TeamName: "backend"}},
+		Renamed:      []string{"u2"},
+		Reactivated:  []string{"u2"},
+		Joined: []model.SyncMembership{
+			{UserID: "u1", TeamName: "mobile"},
+			{UserID: "u5", TeamName: "frontend"},
+		},
+		Moved: []model.SyncMove{{UserID: "u3", FromTeam: "backend", ToTeam: "frontend"}},
+		Left: []model.SyncMembership{
+			{UserID: "u1", TeamName: "frontend"},
+			{UserID: "u6", TeamName: "backend"},
+		},
+		Deactivated: []string{"u4"},
+	}
+	if !reflect.DeepEqual(plan.report, want) {
+		t.Fatalf("got %+v\nwant %+v", plan.report, want)
+	}
+	if len(plan.deactivate) != 1 || plan.deactivate[0].TeamName != "frontend" {
+		t.Fatalf("deactivate: %+v", plan.deactivate)
+	}
+	if plan.usernames["u2"] != "Bobby" {
+		t.Fatalf("username must come from the directory, got %q", plan.usernames["u2"])
+	}
+}
+
+func TestPlanSync_InSync(t *testing.T) {
+	groups := []model.DirectoryGroup{{TeamName: "backend", Members: []model.DirectoryUser{{UserID: "u1", Username: "Alice"}}}}
+	users := []model.User{{UserID: "u1", Username: "Alice", TeamName: "backend", Teams: []string{"backend"}, IsActive: true}}
+	plan, err := planSync(groups, users, map[string]string{"backend": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plan.report, model.SyncReport{}) {
+		t.Fatalf("nothing to change, got %+v", plan.report)
+	}
+}
+
+func TestPlanSync_EmptyDirectory(t *testing.T) {
+	groups := []model.DirectoryGroup{{TeamName: "backend"}, {TeamName: "frontend"}}
+	users := []model.User{{UserID: "u1", Username: "Alice", TeamName: "backend", Teams: []string{"backend"}, IsActive: true}}
+	if _, err := planSync(groups, users, nil); err != ErrEmptyDirectory {
+		t.Fatalf("got %v, want ErrEmptyDirectory", err)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+// ErrUnknownAccount — логин из внешней системы не привязан ни к одному пользователю.
+var ErrUnknownAccount = errors.New("unknown external account")
+
+// ForgeService применяет события PR из внешних систем (GitHub, GitLab) через PRService.
+type ForgeService struct {
+	prs   *PRService
+	users *repository.UsersRepo
+}
+
+func NewForgeService(prs *PRService, users *repository.UsersRepo) *ForgeService {
+	return &ForgeService{prs: prs, users: users}
+}
+
+// ForgeOutcome — что сделал сервис в ответ на событие.
+type ForgeOutcome string
+
+const (
+	OutcomeCreated   ForgeOutcome = "created"
+	OutcomeReady     ForgeOutcome = "ready"
+	OutcomeDraft     ForgeOutcome = "draft"
+	OutcomeClosed    ForgeOutcome = "closed"
+	OutcomeMerged    ForgeOutcome = "merged"
+	OutcomeReopened  ForgeOutcome = "reopened"
+	OutcomeUnchanged ForgeOutcome = "unchanged"
+)
+
+// Apply применяет событие к PR. Повторная доставка того же события ничего не меняет
+// (OutcomeUnchanged). Если PR ещё не известен сервису, возвращается repository.ErrNotFound.
+func (s *ForgeService) Apply(ctx context.Context, ev model.ForgeEvent) (ForgeOutcome, model.PullRequest, error) {
+	id := ev.PullRequestID()
+	if ev.Action == model.ForgeOpened {
+		return s.open(ctx, ev)
+	}
+
+	pr, err := s.prs.Get(ctx, id)
+	if err != nil {
+		return "", model.PullRequest{}, err
+	}
+	switch ev.Action {
+	case model.ForgeReady:
+		if pr.Status != model.PRStatusDraft {
+			return OutcomeUnchanged, pr, nil
+		}
+		pr, err = s.prs.Ready(ctx, id)
+		return OutcomeReady, pr, err
+	case model.ForgeConvertedToDraft:
+		if pr.Status != model.PRStatusOpen {
+			return OutcomeUnchanged, pr, nil
+		}
+		pr, err = s.prs.ConvertToDraft(ctx, id)
+		return OutcomeDraft, pr, err
+	case model.ForgeClosed:
+		if pr.Status == model.PRStatusClosed {
+			return OutcomeUnchanged, pr, nil
+		}
+		pr, err = s.prs.Close(ctx, id)
+		return OutcomeClosed, pr, err
+	case model.ForgeReopened:
+		if pr.Status != model.PRStatusClosed {
+			return OutcomeUnchanged, pr, nil
+		}
+		pr, err = s.prs.Reopen(ctx, id)
+		return OutcomeReopened, pr, err
+	case model.ForgeMerged:
+		if pr.Status == model.PRStatusMerged {
+			return OutcomeUnchanged, pr, nil
+		}
+		// PR уже смержен во внешней системе: фиксируем факт, даже если правила команды
+		// не выполнены; обход попадёт в merge_overrides.
+		pr, err = s.prs.Merge(ctx, id, MergeOptions{
+			Force:  true,
+			Actor:  ev.Provider + ":" + ev.ActorLogin,
+			Reason: "merged in " + ev.Provider,
+		})
+		return OutcomeMerged, pr, err
+	}
+	return OutcomeUnchanged, pr, nil
+}
+
+func (s *ForgeService) open(ctx context.Context, ev model.ForgeEvent) (ForgeOutcome, model.PullRequest, error) {
+	author, err := s.users.UserIDByLogin(ctx, ev.Provider, ev.AuthorLogin)
+	if err == repository.ErrNotFound {
+		return "", model.PullRequest{}, ErrUnknownAccount
+	}
+	if err != nil {
+		return "", model.PullRequest{}, err
+	}
+	pr, err := s.prs.Create(ctx, CreateInput{
+		ID:              ev.PullRequestID(),
+		Name:            ev.Title,
+		AuthorID:        author,
+		Draft:           ev.Draft,
+		Source:          ev.Provider,
+		ExternalProject: ev.Repo,
+		ExternalIID:     ev.Number,
+	})
+	if err == repository.ErrPRExists {
+		pr, err = s.prs.Get(ctx, ev.PullRequestID())
+		return OutcomeUnchanged, pr, err
+	}
+	return OutcomeCreated, pr, err
+}
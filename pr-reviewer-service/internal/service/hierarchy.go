@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// hierarchyFallbacks возвращает команды, к которым поднимается подбор ревьюверов по дереву команд
+// parents (команда → родитель) от team: соседние команды по имени, затем родитель, затем соседи
+// родителя и его родитель и так до команды верхнего уровня. Команды верхнего уровня соседями
+// друг другу не считаются.
+func hierarchyFallbacks(parents map[string]string, team string) []string {
+	children := make(map[string][]string)
+	for t, p := range parents {
+		if p != "" {
+			children[p] = append(children[p], t)
+		}
+	}
+	for _, c := range children {
+		sort.Strings(c)
+	}
+
+	var res []string
+	seen := map[string]bool{team: true}
+	for cur := team; parents[cur] != ""; cur = parents[cur] {
+		parent := parents[cur]
+		if seen[parent] {
+			break
+		}
+		for _, sib := range children[parent] {
+			if !seen[sib] {
+				seen[sib] = true
+				res = append(res, sib)
+			}
+		}
+		seen[parent] = true
+		res = append(res, parent)
+	}
+	return res
+}
+
+// pools возвращает пулы подбора ревьюверов для команды settings по порядку: сама команда,
+// её запасные команды, затем команды выше по дереву (hierarchyFallbacks).
+func (s *PRService) pools(ctx context.Context, settings model.TeamSettings) ([]string, error) {
+	res := append([]string{settings.TeamName}, settings.FallbackTeams...)
+	if settings.TeamName == "" {
+		return res, nil
+	}
+	parents, err := s.teams.ListParents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(res))
+	for _, t := range res {
+		seen[t] = true
+	}
+	for _, t := range hierarchyFallbacks(parents, settings.TeamName) {
+		if !seen[t] {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// teamScope возвращает команды, по которым считается статистика: team, а если subteams — и все
+// её подкоманды. Пустой team — все команды (nil).
+func (s *PRService) teamScope(ctx context.Context, team string, subteams bool) ([]string, error) {
+	if team == "" {
+		return nil, nil
+	}
+	if subteams {
+		return s.teams.Subtree(ctx, team)
+	}
+	return []string{team}, nil
+}
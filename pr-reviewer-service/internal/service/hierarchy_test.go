@@ -0,0 +1,41 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHierarchyFallbacks(t *testing.T) {
+	// platform → {backend → {api, db}, infra}, sales — отдельный отдел.
+	parents := map[string]string{
+		"platform": "",
+		"backend":  "platform",
+		"infra":    "platform",
+		"db":       "backend",
+		"api":      "backend",
+		"sales":    "",
+	}
+
+	cases := []struct {
+		team string
+		want []string
+	}{
+		{"api", []string{"db", "backend", "infra", "platform"}},
+		{"backend", []string{"infra", "platform"}},
+		{"platform", nil},
+		{"sales", nil},
+		{"unknown", nil},
+	}
+	for _, c := range cases {
+		if got := hierarchyFallbacks(parents, c.team); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.team, got, c.want)
+		}
+	}
+}
+
+func TestHierarchyFallbacks_StopsOnCycle(t *testing.T) {
+	parents := map[string]string{"a": "b", "b": "a"}
+	if got := hierarchyFallbacks(parents, "a"); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("got %v", got)
+	}
+}
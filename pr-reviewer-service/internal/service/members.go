@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+var (
+	ErrAlreadyMember = errors.New("user already in team")
+	ErrNotMember     = errors.New("user not in team")
+)
+
+// MemberChange — результат перевода пользователя в другую команду или удаления из команды.
+type MemberChange struct {
+	User           model.User `json:"user"`
+	Reassigned     int        `json:"reassigned"`
+	UnassignedLeft int        `json:"unassigned_left"`
+}
+
+// MoveMember делает team основной командой пользователя вместо прежней; участие в остальных командах
+// сохраняется. Если reassign, его PENDING-ревью в OPEN PR прежней основной команды до перевода
+// передаются другим ревьюверам тем же путём, что и Reassign; ревью без замены остаются за пользователем.
+func (s *PRService) MoveMember(ctx context.Context, userID, team string, reassign bool) (MemberChange, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return MemberChange{}, err
+	}
+	if user.TeamName == team {
+		return MemberChange{}, ErrAlreadyMember
+	}
+	// Команду проверяем до переназначений, чтобы не менять PR впустую.
+	if _, err := s.teams.GetSettings(ctx, team); err != nil {
+		return MemberChange{}, err
+	}
+
+	var res MemberChange
+	if reassign && user.TeamName != "" {
+		if res.Reassigned, res.UnassignedLeft, err = s.reassignPending(ctx, userID, user.TeamName, "member_moved"); err != nil {
+			return MemberChange{}, err
+		}
+	}
+	if res.User, err = s.users.SetTeam(ctx, userID, team); err != nil {
+		return MemberChange{}, err
+	}
+	return res, nil
+}
+
+// RemoveMember заканчивает участие пользователя в команде team. Он остаётся в сервисе; если других
+// команд у него нет, он не выбирается ревьювером. Если reassign, его PENDING-ревью в OPEN PR этой
+// команды передаются другим ревьюверам, как в MoveMember.
+func (s *PRService) RemoveMember(ctx context.Context, team, userID string, reassign bool) (MemberChange, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return MemberChange{}, err
+	}
+	if !inTeam(user, team) {
+		return MemberChange{}, ErrNotMember
+	}
+
+	var res MemberChange
+	if reassign {
+		if res.Reassigned, res.UnassignedLeft, err = s.reassignPending(ctx, userID, team, "member_removed"); err != nil {
+			return MemberChange{}, err
+		}
+	}
+	if res.User, err = s.users.RemoveFromTeam(ctx, userID, team); err != nil {
+		if err == repository.ErrNotFound {
+			return MemberChange{}, ErrNotMember
+		}
+		return MemberChange{}, err
+	}
+	return res, nil
+}
+
+// inTeam сообщает, что пользователь состоит в команде team.
+func inTeam(u model.User, team string) bool {
+	for _, t := range u.Teams {
+		if t == team {
+			return true
+		}
+	}
+	return false
+}
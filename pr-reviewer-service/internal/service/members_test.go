@@ -0,0 +1,19 @@
+package service
+
+import (
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestInTeam(t *testing.T) {
+	u := model.User{UserID: "u1", TeamName: "backend", Teams: []string{"backend", "frontend"}}
+	for team, want := range map[string]bool{"backend": true, "frontend": true, "ops": false, "": false} {
+		if got := inTeam(u, team); got != want {
+			t.Fatalf("%q: got %v, want %v", team, got, want)
+		}
+	}
+	if inTeam(model.User{UserID: "u2"}, "") {
+		t.Fatalf("user without teams must not be a member of the empty team")
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/orgfile"
+)
+
+// ImportError — импорт отклонён из-за ошибок в строках файла; ничего не изменено.
+type ImportError struct {
+	Rows []model.ImportRowError
+}
+
+func (e *ImportError) Error() string {
+	return "invalid import: " + strings.Join(e.Details(), "; ")
+}
+
+// Details возвращает ошибки строк в виде "line N: ...".
+func (e *ImportError) Details() []string {
+	res := make([]string, 0, len(e.Rows))
+	for _, r := range e.Rows {
+		res = append(res, r.String())
+	}
+	return res
+}
+
+// Import загружает оргструктуру из файла формата f (см. TeamsRepo.ImportOrg): сначала проверяются
+// все строки, и при ошибках ничего не меняется и возвращается *ImportError со всеми ошибками.
+// При dryRun изменения только подсчитываются.
+func (s *TeamsService) Import(ctx context.Context, r io.Reader, f orgfile.Format, dryRun bool) (model.ImportResult, error) {
+	rows, rowErrs, err := orgfile.Read(r, f)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	if len(rowErrs) == 0 && len(rows) == 0 {
+		rowErrs = append(rowErrs, model.ImportRowError{Message: "file has no rows"})
+	}
+	if len(rowErrs) > 0 {
+		return model.ImportResult{}, &ImportError{Rows: rowErrs}
+	}
+	parents, err := s.teams.ListParents(ctx)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	if rowErrs := validateOrg(rows, parents); len(rowErrs) > 0 {
+		return model.ImportResult{}, &ImportError{Rows: rowErrs}
+	}
+	return s.teams.ImportOrg(ctx, rows, dryRun)
+}
+
+// Export записывает всю оргструктуру в w в формате f.
+func (s *TeamsService) Export(ctx context.Context, w io.Writer, f orgfile.Format) error {
+	rows, err := s.teams.ExportOrg(ctx)
+	if err != nil {
+		return err
+	}
+	return orgfile.Write(w, f, rows)
+}
+
+// validateOrg проверяет строки импорта вместе с текущим деревом команд parents (команда → родитель):
+// обязательные поля, согласованность повторяющихся данных команды и пользователя, повторы участий,
+// существование родителей и отсутствие циклов.
+func validateOrg(rows []model.OrgRow, parents map[string]string) []model.ImportRowError {
+	var errs []model.ImportRowError
+	fail := func(line int, format string, args ...any) {
+		errs = append(errs, model.ImportRowError{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	inFile := make(map[string]bool)
+	for _, r := range rows {
+		inFile[r.TeamName] = true
+	}
+
+	parentLine := make(map[string]model.OrgRow)
+	userLine := make(map[string]model.OrgRow)
+	primaryLine := make(map[string]int)
+	memberLine := make(map[[2]string]int)
+	for _, r := range rows {
+		if r.TeamName == "" {
+			fail(r.Line, "team_name is required")
+			continue
+		}
+		// Родитель проверяется по первой строке команды, остальные должны с ней совпадать.
+		if r.ParentTeam != "" {
+			_, exists := parents[r.ParentTeam]
+			prev, ok := parentLine[r.TeamName]
+			switch {
+			case ok:
+				if prev.ParentTeam != r.ParentTeam {
+					fail(r.Line, "parent_team %q conflicts with %q on line %d", r.ParentTeam, prev.ParentTeam, prev.Line)
+				}
+			case r.ParentTeam == r.TeamName:
+				fail(r.Line, "team cannot be its own parent")
+			case !inFile[r.ParentTeam] && !exists:
+				fail(r.Line, "parent_team %q not found", r.ParentTeam)
+			}
+			if !ok {
+				parentLine[r.TeamName] = r
+			}
+		}
+
+		if r.UserID == "" {
+			if r.Username != "" || r.Primary || r.ReviewWeight != 0 || r.Level != "" {
+				fail(r.Line, "user_id is required")
+			}
+			continue
+		}
+		if r.Username == "" {
+			fail(r.Line, "username is required")
+		}
+		if r.ReviewWeight < 0 {
+			fail(r.Line, "review_weight must be positive")
+		}
+		if r.Level != "" && !r.Level.Valid() {
+			fail(r.Line, "unknown level %q", r.Level)
+		}
+		key := [2]string{r.TeamName, r.UserID}
+		if line, dup := memberLine[key]; dup {
+			fail(r.Line, "user %s is already listed in team %s on line %d", r.UserID, r.TeamName, line)
+		} else {
+			memberLine[key] = r.Line
+		}
+		if r.Primary {
+			if line, dup := primaryLine[r.UserID]; dup {
+				fail(r.Line, "user %s already has a primary team on line %d", r.UserID, line)
+			} else {
+				primaryLine[r.UserID] = r.Line
+			}
+		}
+		if prev, ok := userLine[r.UserID]; ok {
+			switch {
+			case r.Username != "" && prev.Username != "" && r.Username != prev.Username:
+				fail(r.Line, "username conflicts with line %d", prev.Line)
+			case r.IsActive != prev.IsActive:
+				fail(r.Line, "is_active conflicts with line %d", prev.Line)
+			case r.Level != "" && prev.Level != "" && r.Level != prev.Level:
+				fail(r.Line, "level conflicts with line %d", prev.Line)
+			}
+		} else {
+			userLine[r.UserID] = r
+		}
+	}
+
+	// Циклы проверяются по дереву после импорта: родители из файла поверх текущих.
+	merged := make(map[string]string, len(parents)+len(parentLine))
+	for t, p := range parents {
+		merged[t] = p
+	}
+	for t, r := range parentLine {
+		if r.ParentTeam != t {
+			merged[t] = r.ParentTeam
+		}
+	}
+	for _, r := range rows {
+		if p, ok := parentLine[r.TeamName]; !ok || p.Line != r.Line || r.ParentTeam == r.TeamName {
+			continue
+		}
+		seen := map[string]bool{r.TeamName: true}
+		for cur := merged[r.TeamName]; cur != ""; cur = merged[cur] {
+			if cur == r.TeamName {
+				fail(r.Line, "parent_team %q would create a cycle", r.ParentTeam)
+				break
+			}
+			if seen[cur] {
+				break
+			}
+			seen[cur] = true
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	return errs
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestValidateOrg_Valid(t *testing.T) {
+	parents := map[string]string{"platform": ""}
+	rows := []model.OrgRow{
+		{TeamName: "backend", ParentTeam: "platform", UserID: "u1", Username: "Alice", IsActive: true, Primary: true, Line: 2},
+		{TeamName: "backend", ParentTeam: "platform", UserID: "u2", Username: "Bob", IsActive: true, Level: model.LevelJunior, Line: 3},
+		{TeamName: "api", ParentTeam: "backend", UserID: "u1", Username: "Alice", IsActive: true, ReviewWeight: 3, Line: 4},
+		{TeamName: "sales", IsActive: true, Line: 5},
+	}
+	if errs := validateOrg(rows, parents); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+}
+
+func TestValidateOrg_RowErrors(t *testing.T) {
+	parents := map[string]string{"platform": "", "backend": "platform"}
+	rows := []model.OrgRow{
+		{UserID: "u0", Username: "Nobody", IsActive: true, Line: 2},
+		{TeamName: "backend", UserID: "u1", IsActive: true, Line: 3},
+		{TeamName: "backend", UserID: "u2", Username: "Bob", IsActive: true, Primary: true, ReviewWeight: -1, Level: "guru", Line: 4},
+		{TeamName: "frontend", UserID: "u2", Username: "Robert", IsActive: true, Primary: true, Line: 5},
+		{TeamName: "backend", UserID: "u2", Username: "Bob", IsActive: true, Line: 6},
+		{TeamName: "mobile", ParentTeam: "design", IsActive: true, Line: 7},
+		{TeamName: "web", ParentTeam: "web", IsActive: true, Line: 8},
+		{TeamName: "frontend", ParentTeam: "platform", UserID: "u3", Username: "Carol", IsActive: true, Line: 9},
+		{TeamName: "frontend", ParentTeam: "backend", UserID: "u4", Username: "Dan", IsActive: true, Line: 10},
+		{TeamName: "ghost", Username: "Eve", IsActive: true, Line: 11},
+	}
+	var lines []int
+	for _, e := range validateOrg(rows, parents) {
+		lines = append(lines, e.Line)
+	}
+	// 4: вес, уровень; 5: второй primary, другое имя; 6: повтор участия.
+	want := []int{2, 3, 4, 4, 5, 5, 6, 7, 8, 10, 11}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("error lines: got %v, want %v", lines, want)
+	}
+}
+
+func TestValidateOrg_Cycle(t *testing.T) {
+	// В базе backend под platform; файл переносит platform под backend.
+	parents := map[string]string{"platform": "", "backend": "platform"}
+	rows := []model.OrgRow{{TeamName: "platform", ParentTeam: "backend", IsActive: true, Line: 2}}
+	errs := validateOrg(rows, parents)
+	if len(errs) != 1 || errs[0].Line != 2 {
+		t.Fatalf("cycle must be reported, got %+v", errs)
+	}
+
+	rows = []model.OrgRow{
+		{TeamName: "a", ParentTeam: "b", IsActive: true, Line: 2},
+		{TeamName: "b", ParentTeam: "a", IsActive: true, Line: 3},
+	}
+	if errs := validateOrg(rows, nil); len(errs) != 2 {
+		t.Fatalf("both teams of the cycle must be reported, got %+v", errs)
+	}
+}
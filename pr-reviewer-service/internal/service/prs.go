@@ -4,80 +4,455 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
+	"pr-reviewer-service/internal/codeowners"
 	"pr-reviewer-service/internal/model"
 	"pr-reviewer-service/internal/repository"
 )
 
 type PRService struct {
-	prs   *repository.PRsRepo
-	users *repository.UsersRepo
-	db    *sql.DB
+	prs       *repository.PRsRepo
+	users     *repository.UsersRepo
+	teams     *repository.TeamsRepo
+	db        *sql.DB
+	selectors map[model.SelectionStrategy]ReviewerSelector
+	// now — часы сервиса: по ним проверяются отпуска и рабочие часы кандидатов.
+	now func() time.Time
 }
 
-func NewPRService(prs *repository.PRsRepo, users *repository.UsersRepo, db *sql.DB) *PRService {
+func NewPRService(prs *repository.PRsRepo, users *repository.UsersRepo, teams *repository.TeamsRepo, db *sql.DB) *PRService {
 	rand.Seed(time.Now().UnixNano())
-	return &PRService{prs: prs, users: users, db: db}
+	return &PRService{prs: prs, users: users, teams: teams, db: db, selectors: NewSelectors(), now: time.Now}
+}
+
+// WithClock подменяет часы сервиса (для тестов и воспроизведения).
+func (s *PRService) WithClock(now func() time.Time) *PRService {
+	s.now = now
+	return s
+}
+
+// CreateInput — параметры создания PR.
+type CreateInput struct {
+	ID       string
+	Name     string
+	AuthorID string
+	// Draft создаёт PR в статусе DRAFT без ревьюверов; они назначаются при переходе в OPEN.
+	Draft bool
+	// Source, ExternalProject и ExternalIID задаются для PR из внешних систем.
+	Source          string
+	ExternalProject string
+	ExternalIID     int
+	// Repository и ChangedFiles нужны для выбора владельцев кода по CODEOWNERS.
+	// Если Repository не задан, берётся ExternalProject.
+	Repository   string
+	ChangedFiles []string
+	// RequiredTags — навыки, которые должны быть хотя бы у одного из ревьюверов.
+	RequiredTags []string
+	// TeamName — команда автора, от имени которой создаётся PR; пустая — основная команда автора.
+	TeamName string
 }
 
-// Create создает PR и назначает до двух активных ревьюверов из команды автора (без автора).
-func (s *PRService) Create(ctx context.Context, id, name, authorID string) (model.PullRequest, error) {
-	author, err := s.users.GetUser(ctx, authorID)
+// Create создает PR и назначает активных ревьюверов из команды PR (без автора).
+// Если переданы изменённые файлы, сначала выбираются их владельцы по правилам CODEOWNERS.
+// Обязательные теги PR по возможности покрываются ревьюверами, непокрытые попадают в UncoveredTags.
+// Если своей команды не хватает, ревьюверы добираются из её запасных команд.
+// Сколько ревьюверов нужно и что делать при нехватке, определяют настройки команды.
+func (s *PRService) Create(ctx context.Context, in CreateInput) (model.PullRequest, error) {
+	author, err := s.users.GetUser(ctx, in.AuthorID)
 	if err != nil {
 		return model.PullRequest{}, err
 	}
 
-	rows, err := s.db.QueryContext(ctx, `
-        SELECT user_id
-        FROM users
-        WHERE team_name=$1
-          AND is_active=TRUE
-          AND user_id <> $2
-    `, author.TeamName, authorID)
+	tags, err := normalizeTags(in.RequiredTags)
 	if err != nil {
 		return model.PullRequest{}, err
 	}
-	defer rows.Close()
-
-	var candidates []string
-	for rows.Next() {
-		var uid string
-		if err := rows.Scan(&uid); err != nil {
-			return model.PullRequest{}, err
+	team := author.TeamName
+	if in.TeamName != "" {
+		if !inTeam(author, in.TeamName) {
+			return model.PullRequest{}, ErrNotMember
 		}
-		candidates = append(candidates, uid)
+		team = in.TeamName
 	}
-	if err := rows.Err(); err != nil {
+	pr := model.PullRequest{
+		ID:              in.ID,
+		Name:            in.Name,
+		AuthorID:        in.AuthorID,
+		TeamName:        team,
+		Status:          model.PRStatusOpen,
+		Source:          in.Source,
+		ExternalProject: in.ExternalProject,
+		ExternalIID:     in.ExternalIID,
+		Repository:      in.Repository,
+		ChangedFiles:    cleanPaths(in.ChangedFiles),
+		RequiredTags:    tags,
+	}
+	if pr.Repository == "" {
+		pr.Repository = in.ExternalProject
+	}
+	if in.Draft {
+		pr.Status = model.PRStatusDraft
+		return s.prs.CreateWithReviewers(ctx, pr)
+	}
+
+	st, err := s.staff(ctx, author, pr)
+	if err != nil {
 		return model.PullRequest{}, err
 	}
+	pr.Reviewers, pr.Understaffed, pr.UncoveredTags = st.reviewers, st.understaffed, st.uncovered
+	return s.prs.CreateWithReviewers(ctx, pr)
+}
+
+// staffing — результат подбора ревьюверов для PR.
+type staffing struct {
+	// reviewers — новые ревьюверы (уже назначенные сюда не входят).
+	reviewers []model.ReviewerAssignment
+	// understaffed — min_reviewers команды не набран.
+	understaffed bool
+	// uncovered — обязательные теги PR, которых нет ни у одного ревьювера.
+	uncovered []string
+}
 
-	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
-	if len(candidates) > 2 {
-		candidates = candidates[:2]
+// staff подбирает ревьюверов для PR автора по настройкам команды PR, не трогая уже назначенных.
+// Сначала выбираются владельцы изменённых файлов, затем кандидаты с ещё не покрытыми обязательными
+// тегами PR, остальные места заполняются из пула команды. В конце состав доводится до правил уровня
+// команды (min_senior_reviewers, forbid_junior_only).
+func (s *PRService) staff(ctx context.Context, author model.User, pr model.PullRequest) (staffing, error) {
+	settings, err := s.teamSettings(ctx, pr.TeamName)
+	if err != nil {
+		return staffing{}, err
 	}
 
-	pr := model.PullRequest{
-		ID:                id,
-		Name:              name,
-		AuthorID:          authorID,
-		AssignedReviewers: candidates,
+	assigned := pr.AssignedReviewers
+	p, err := s.newPick(ctx, settings, author.UserID, assigned...)
+	if err != nil {
+		return staffing{}, err
 	}
-	return s.prs.CreateWithReviewers(ctx, pr)
+	want := settings.ReviewerCount - len(assigned)
+	reviewers, err := s.pickOwners(ctx, settings, pr, p, want)
+	if err != nil {
+		return staffing{}, err
+	}
+	missing, err := s.uncoveredTags(ctx, pr.RequiredTags, reviewerIDs(assigned, reviewers))
+	if err != nil {
+		return staffing{}, err
+	}
+	skilled, err := s.pickForTags(ctx, settings, missing, p, want-len(reviewers))
+	if err != nil {
+		return staffing{}, err
+	}
+	reviewers = append(reviewers, skilled...)
+	fromTeam, err := s.pickWithFallbacks(ctx, settings, p, want-len(reviewers))
+	if err != nil {
+		return staffing{}, err
+	}
+	reviewers = append(reviewers, fromTeam...)
+
+	if len(assigned)+len(reviewers) < settings.MinReviewers {
+		switch settings.UnderstaffedPolicy {
+		case model.PolicyReject:
+			return staffing{}, ErrNotEnoughReviewers
+		case model.PolicyFallback:
+			extra, err := s.pickOutside(ctx, settings, p, want-len(reviewers))
+			if err != nil {
+				return staffing{}, err
+			}
+			reviewers = append(reviewers, extra...)
+		}
+	}
+	reviewers, err = s.enforceSeniority(ctx, settings, p, assigned, reviewers)
+	if err != nil {
+		return staffing{}, err
+	}
+	uncovered, err := s.uncoveredTags(ctx, pr.RequiredTags, reviewerIDs(assigned, reviewers))
+	if err != nil {
+		return staffing{}, err
+	}
+	return staffing{
+		reviewers:    reviewers,
+		understaffed: len(assigned)+len(reviewers) < settings.MinReviewers,
+		uncovered:    uncovered,
+	}, nil
+}
+
+// reviewerIDs объединяет уже назначенных ревьюверов с новыми.
+func reviewerIDs(assigned []string, reviewers []model.ReviewerAssignment) []string {
+	res := append([]string(nil), assigned...)
+	for _, rv := range reviewers {
+		res = append(res, rv.UserID)
+	}
+	return res
+}
+
+// pickWithFallbacks выбирает до n ревьюверов сначала из команды settings, затем по порядку
+// из её запасных команд и команд выше по дереву. Выбранные добавляются в p.exclude.
+func (s *PRService) pickWithFallbacks(ctx context.Context, settings model.TeamSettings, p *pick, n int) ([]model.ReviewerAssignment, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	pools, err := s.pools(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []model.ReviewerAssignment
+	for i, team := range pools {
+		if len(res) >= n {
+			break
+		}
+		all, err := s.users.ListReviewCandidates(ctx, team, s.now())
+		if err != nil {
+			return nil, err
+		}
+		reason := model.ReasonTeam
+		if i > 0 {
+			reason = model.ReasonFallbackTeam
+		}
+		for _, uid := range s.selectFrom(settings.SelectionStrategy, team, all, p, n-len(res)) {
+			p.exclude[uid] = true
+			res = append(res, model.ReviewerAssignment{UserID: uid, Fallback: i > 0, Reason: reason})
+		}
+	}
+	return res, nil
+}
+
+// pickOutside выбирает до n активных пользователей из всех команд, кроме команды settings.
+// Выбранные добавляются в p.exclude и помечаются как fallback.
+func (s *PRService) pickOutside(ctx context.Context, settings model.TeamSettings, p *pick, n int) ([]model.ReviewerAssignment, error) {
+	all, err := s.users.ListReviewCandidatesOutside(ctx, settings.TeamName, s.now())
+	if err != nil {
+		return nil, err
+	}
+	var res []model.ReviewerAssignment
+	for _, uid := range s.selectFrom(settings.SelectionStrategy, "!"+settings.TeamName, all, p, n) {
+		p.exclude[uid] = true
+		res = append(res, model.ReviewerAssignment{UserID: uid, Fallback: true, Reason: model.ReasonOutsideTeam})
+	}
+	return res, nil
+}
+
+// pickOwners выбирает до n владельцев изменённых файлов PR по правилам CODEOWNERS команды settings
+// и репозитория PR. Владельцы могут быть из любых команд, но проходят те же проверки, что и остальные
+// кандидаты (активность, лимит, отпуск). Выбранные добавляются в p.exclude.
+func (s *PRService) pickOwners(ctx context.Context, settings model.TeamSettings, pr model.PullRequest, p *pick, n int) ([]model.ReviewerAssignment, error) {
+	if len(pr.ChangedFiles) == 0 || n <= 0 {
+		return nil, nil
+	}
+	rules, err := s.teams.CodeownersFor(ctx, settings.TeamName, pr.Repository)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	owned := codeowners.Owners(rules, pr.ChangedFiles)
+	if len(owned) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(owned))
+	for uid := range owned {
+		ids = append(ids, uid)
+	}
+	all, err := s.users.ListReviewCandidatesByID(ctx, ids, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	var res []model.ReviewerAssignment
+	for _, uid := range s.selectFrom(settings.SelectionStrategy, "codeowners:"+settings.TeamName, all, p, n) {
+		p.exclude[uid] = true
+		res = append(res, model.ReviewerAssignment{UserID: uid, Reason: model.ReasonCodeowner, MatchedPaths: owned[uid]})
+	}
+	return res, nil
+}
+
+// cleanPaths приводит пути изменённых файлов к виду от корня репозитория без повторов.
+func cleanPaths(paths []string) []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, p := range paths {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "/")
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		res = append(res, p)
+	}
+	return res
+}
+
+// pick — состояние подбора ревьюверов для одного PR.
+type pick struct {
+	// exclude — кого уже нельзя выбрать: автор, назначенные и выбранные ранее.
+	exclude map[string]bool
+	// pairs — сколько раз каждый пользователь ревьюил автора PR за окно pair_window_days.
+	pairs map[string]int
+	// only, если задан, оставляет для выбора только подходящих кандидатов.
+	only func(model.ReviewCandidate) bool
+}
+
+// newPick готовит подбор ревьюверов для PR автора author, исключая его самого и taken.
+// Недавние пары берутся за окно pair_window_days из settings.
+func (s *PRService) newPick(ctx context.Context, settings model.TeamSettings, author string, taken ...string) (*pick, error) {
+	p := &pick{exclude: map[string]bool{author: true}}
+	for _, uid := range taken {
+		p.exclude[uid] = true
+	}
+	if settings.PairWindowDays > 0 {
+		since := s.now().AddDate(0, 0, -settings.PairWindowDays)
+		pairs, err := s.prs.RecentReviewers(ctx, author, since)
+		if err != nil {
+			return nil, err
+		}
+		p.pairs = pairs
+	}
+	return p, nil
+}
+
+// selectFrom выбирает до n кандидатов, не входящих в p.exclude, стратегией strategy.
+// Сначала выбираются те, у кого сейчас рабочее время, остальные добирают недостающих.
+// Недавние пары с автором передаются кандидатам в Pairs, их учитывает каждая стратегия.
+func (s *PRService) selectFrom(strategy model.SelectionStrategy, pool string, all []model.ReviewCandidate, p *pick, n int) []string {
+	selector, ok := s.selectors[strategy]
+	if !ok {
+		selector = s.selectors[model.StrategyRandom]
+	}
+	now := s.now()
+	var inHours, offHours []model.ReviewCandidate
+	for _, c := range all {
+		if p.exclude[c.UserID] || (p.only != nil && !p.only(c)) {
+			continue
+		}
+		c.Pairs = p.pairs[c.UserID]
+		if c.Schedule != nil && !c.Schedule.InHours(now) {
+			offHours = append(offHours, c)
+		} else {
+			inHours = append(inHours, c)
+		}
+	}
+
+	var res []string
+	for _, tier := range [][]model.ReviewCandidate{inHours, offHours} {
+		if len(res) >= n {
+			break
+		}
+		for _, c := range selector.Select(pool, tier, n-len(res)) {
+			res = append(res, c.UserID)
+		}
+	}
+	return res
 }
 
 var (
-	ErrPRMerged    = errors.New("pr merged")
-	ErrNotAssigned = errors.New("reviewer not assigned")
-	ErrNoCandidate = errors.New("no candidate")
+	ErrPRMerged           = errors.New("pr merged")
+	ErrPRNotOpen          = errors.New("pr not open")
+	ErrNotAssigned        = errors.New("reviewer not assigned")
+	ErrNoCandidate        = errors.New("no candidate")
+	ErrNotEnoughReviewers = errors.New("not enough reviewers")
+	ErrInvalidReviewState = errors.New("invalid review state")
 )
 
-func (s *PRService) Merge(ctx context.Context, prID string) (model.PullRequest, error) {
-	return s.prs.Merge(ctx, prID)
+// statusChanged перечитывает PR, который под блокировкой оказался не OPEN, и возвращает
+// ErrPRMerged для смерженного и ErrPRNotOpen для остальных.
+func statusChanged(ctx context.Context, prs *repository.PRsRepo, prID string) error {
+	pr, err := prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return err
+	}
+	if pr.Status == model.PRStatusMerged {
+		return ErrPRMerged
+	}
+	return ErrPRNotOpen
+}
+
+// MergeBlockedError — мерж запрещён правилами команды; Unmet перечисляет невыполненные условия.
+type MergeBlockedError struct {
+	Unmet []string
+}
+
+func (e *MergeBlockedError) Error() string {
+	return "merge blocked: " + strings.Join(e.Unmet, "; ")
+}
+
+// MergeOptions задаёт принудительный мерж. Право на Force проверяет вызывающая сторона.
+type MergeOptions struct {
+	Force  bool
+	Actor  string
+	Reason string
+}
+
+// Merge мержит OPEN PR, если выполнены правила команды PR (число APPROVED и отсутствие
+// CHANGES_REQUESTED). С opts.Force правила обходятся, а обход записывается в merge_overrides.
+// Повторный мерж уже смерженного PR возвращает его без изменений.
+func (s *PRService) Merge(ctx context.Context, prID string, opts MergeOptions) (model.PullRequest, error) {
+	var merged model.PullRequest
+	// Условия проверяются под блокировкой строки PR в той же транзакции, что и мерж:
+	// вердикт или смена ревьювера не проскочат между проверкой и сменой статуса.
+	err := repository.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		prs := s.prs.WithTx(tx)
+		pr, err := prs.GetForUpdate(ctx, prID)
+		if err != nil {
+			return err
+		}
+		if pr.Status == model.PRStatusMerged {
+			merged = pr
+			return nil
+		}
+		if err := checkTransition(pr.Status, model.PRStatusMerged); err != nil {
+			return err
+		}
+		settings, err := s.teamSettings(ctx, pr.TeamName)
+		if err != nil {
+			return err
+		}
+		var override *model.MergeOverride
+		if unmet := unmetMergeConditions(pr, settings); len(unmet) > 0 {
+			if !opts.Force {
+				return &MergeBlockedError{Unmet: unmet}
+			}
+			override = &model.MergeOverride{PullRequestID: prID, Actor: opts.Actor, Reason: opts.Reason, Unmet: unmet}
+		}
+		merged, err = prs.Merge(ctx, prID, override)
+		return err
+	})
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	return merged, nil
+}
+
+// unmetMergeConditions возвращает невыполненные правила мержа команды PR; пустой список — мержить можно.
+func unmetMergeConditions(pr model.PullRequest, settings model.TeamSettings) []string {
+	approvals := 0
+	var changesRequested []string
+	for _, rv := range pr.Reviewers {
+		switch rv.State {
+		case model.ReviewApproved:
+			approvals++
+		case model.ReviewChangesRequested:
+			changesRequested = append(changesRequested, rv.UserID)
+		}
+	}
+
+	var unmet []string
+	if approvals < settings.RequiredApprovals {
+		unmet = append(unmet, fmt.Sprintf("approvals: %d of %d required", approvals, settings.RequiredApprovals))
+	}
+	if settings.BlockOnChangesRequested && len(changesRequested) > 0 {
+		unmet = append(unmet, "changes requested by: "+strings.Join(changesRequested, ", "))
+	}
+	return unmet
 }
 
 func (s *PRService) Reassign(ctx context.Context, prID, oldUserID string) (model.PullRequest, string, error) {
+	return s.reassign(ctx, prID, oldUserID, "manual")
+}
+
+// reassign заменяет ревьювера oldUserID открытого PR; reason попадает в журнал.
+func (s *PRService) reassign(ctx context.Context, prID, oldUserID, reason string) (model.PullRequest, string, error) {
 	pr, err := s.prs.GetWithReviewers(ctx, prID)
 	if err != nil {
 		return model.PullRequest{}, "", err
@@ -85,6 +460,9 @@ func (s *PRService) Reassign(ctx context.Context, prID, oldUserID string) (model
 	if pr.Status == model.PRStatusMerged {
 		return model.PullRequest{}, "", ErrPRMerged
 	}
+	if pr.Status != model.PRStatusOpen {
+		return model.PullRequest{}, "", ErrPRNotOpen
+	}
 
 	assigned := false
 	for _, r := range pr.AssignedReviewers {
@@ -97,111 +475,234 @@ func (s *PRService) Reassign(ctx context.Context, prID, oldUserID string) (model
 		return model.PullRequest{}, "", ErrNotAssigned
 	}
 
-	oldUser, err := s.users.GetUser(ctx, oldUserID)
+	replacement, err := s.replaceReviewer(ctx, s.prs, pr, oldUserID, reason)
 	if err != nil {
 		return model.PullRequest{}, "", err
 	}
 
-	// Ищем активного кандидата из команды старого ревьювера, исключая автора и уже назначенных.
-	rows, err := s.db.QueryContext(ctx, `
-        SELECT user_id
-        FROM users
-        WHERE team_name=$1
-          AND is_active=TRUE
-          AND user_id <> $2
-          AND user_id <> $3
-          AND user_id NOT IN (
-              SELECT user_id FROM pull_request_reviewers WHERE pull_request_id=$4
-          )
-    `, oldUser.TeamName, oldUserID, pr.AuthorID, prID)
+	pr, err = s.prs.GetWithReviewers(ctx, prID)
+	return pr, replacement.UserID, err
+}
+
+// replaceReviewer подбирает замену ревьюверу oldUserID открытого PR и назначает её через prs.
+func (s *PRService) replaceReviewer(ctx context.Context, prs *repository.PRsRepo, pr model.PullRequest, oldUserID, reason string) (model.ReviewerAssignment, error) {
+	oldUser, err := s.users.GetUser(ctx, oldUserID)
 	if err != nil {
-		return model.PullRequest{}, "", err
+		return model.ReviewerAssignment{}, err
 	}
-	defer rows.Close()
 
-	var candidates []string
-	for rows.Next() {
-		var uid string
-		if err := rows.Scan(&uid); err != nil {
-			return model.PullRequest{}, "", err
-		}
-		candidates = append(candidates, uid)
+	// Ищем активного кандидата из команды PR, если старый ревьювер в ней состоит или уже вне команд,
+	// иначе — из его основной команды (или запасных команд выбранной), исключая автора и уже назначенных.
+	team := oldUser.TeamName
+	if team == "" || inTeam(oldUser, pr.TeamName) {
+		team = pr.TeamName
 	}
-	if err := rows.Err(); err != nil {
-		return model.PullRequest{}, "", err
+	settings, err := s.teamSettings(ctx, team)
+	if err != nil {
+		return model.ReviewerAssignment{}, err
+	}
+	p, err := s.newPick(ctx, settings, pr.AuthorID, append([]string{oldUserID}, pr.AssignedReviewers...)...)
+	if err != nil {
+		return model.ReviewerAssignment{}, err
+	}
+	// Правила уровня задаёт команда PR: замена не должна их нарушить.
+	if p.only, err = s.replacementFilter(ctx, pr, oldUserID); err != nil {
+		return model.ReviewerAssignment{}, err
+	}
+	candidates, err := s.pickWithFallbacks(ctx, settings, p, 1)
+	if err != nil {
+		return model.ReviewerAssignment{}, err
 	}
 	if len(candidates) == 0 {
-		return model.PullRequest{}, "", ErrNoCandidate
+		return model.ReviewerAssignment{}, ErrNoCandidate
 	}
-	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
 	replacement := candidates[0]
 
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return model.PullRequest{}, "", err
+	if err := prs.ReplaceReviewer(ctx, pr.ID, oldUserID, replacement, reason); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return model.ReviewerAssignment{}, ErrNotAssigned
+		case repository.ErrStatusChanged:
+			return model.ReviewerAssignment{}, statusChanged(ctx, prs, pr.ID)
+		}
+		return model.ReviewerAssignment{}, err
 	}
-	defer tx.Rollback()
+	return replacement, nil
+}
 
-	if _, err := tx.ExecContext(ctx, `
-        DELETE FROM pull_request_reviewers
-        WHERE pull_request_id=$1 AND user_id=$2
-    `, prID, oldUserID); err != nil {
-		return model.PullRequest{}, "", err
+func (s *PRService) ListByReviewer(ctx context.Context, userID string, states []model.ReviewState) ([]model.AssignedReview, error) {
+	for _, st := range states {
+		if !st.Valid() {
+			return nil, ErrInvalidReviewState
+		}
 	}
+	return s.prs.ListForReviewer(ctx, userID, states)
+}
 
-	if _, err := tx.ExecContext(ctx, `
-        INSERT INTO pull_request_reviewers(pull_request_id, user_id)
-        VALUES ($1,$2)
-    `, prID, replacement); err != nil {
-		return model.PullRequest{}, "", err
+// SubmitReview фиксирует вердикт ревьювера (APPROVED или CHANGES_REQUESTED) по открытому PR.
+func (s *PRService) SubmitReview(ctx context.Context, prID, userID string, state model.ReviewState) (model.PullRequest, error) {
+	if state != model.ReviewApproved && state != model.ReviewChangesRequested {
+		return model.PullRequest{}, ErrInvalidReviewState
 	}
+	return s.setReviewState(ctx, prID, userID, state)
+}
 
-	if err := tx.Commit(); err != nil {
-		return model.PullRequest{}, "", err
+// DismissReview снимает ранее оставленное ревью: оно остаётся назначенным, но в состоянии DISMISSED.
+func (s *PRService) DismissReview(ctx context.Context, prID, userID string) (model.PullRequest, error) {
+	return s.setReviewState(ctx, prID, userID, model.ReviewDismissed)
+}
+
+func (s *PRService) setReviewState(ctx context.Context, prID, userID string, state model.ReviewState) (model.PullRequest, error) {
+	pr, err := s.prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if pr.Status == model.PRStatusMerged {
+		return model.PullRequest{}, ErrPRMerged
+	}
+	if pr.Status != model.PRStatusOpen {
+		return model.PullRequest{}, ErrPRNotOpen
 	}
+	// Статус перепроверяется под блокировкой PR: мерж мог закоммититься после чтения выше.
+	if err := s.prs.SetReviewState(ctx, prID, userID, state); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return model.PullRequest{}, ErrNotAssigned
+		case repository.ErrStatusChanged:
+			return model.PullRequest{}, statusChanged(ctx, s.prs, prID)
+		}
+		return model.PullRequest{}, err
+	}
+	return s.prs.GetWithReviewers(ctx, prID)
+}
 
-	pr, err = s.prs.GetWithReviewers(ctx, prID)
-	return pr, replacement, err
+func (s *PRService) Get(ctx context.Context, prID string) (model.PullRequest, error) {
+	return s.prs.GetWithReviewers(ctx, prID)
 }
 
-func (s *PRService) ListByReviewer(ctx context.Context, userID string) ([]model.PullRequest, error) {
-	return s.prs.ListForReviewer(ctx, userID)
+// History возвращает журнал изменений PR.
+func (s *PRService) History(ctx context.Context, prID string) ([]model.PREvent, error) {
+	if _, err := s.prs.GetWithReviewers(ctx, prID); err != nil {
+		return nil, err
+	}
+	return s.prs.ListEvents(ctx, prID)
 }
 
 func (s *PRService) ReviewerStats(ctx context.Context) ([]model.ReviewerStat, error) {
 	return s.prs.CountAssignmentsByReviewer(ctx)
 }
 
+var ErrInvalidWindow = errors.New("invalid window")
+
+// PairStats возвращает распределение пар автор→ревьювер по PR за последние days дней.
+// Если days не задан (0), берётся pair_window_days команды team, а без команды — 30 дней.
+// Если subteams, учитываются и PR подкоманд team.
+func (s *PRService) PairStats(ctx context.Context, team string, days int, subteams bool) (model.PairStats, error) {
+	if days < 0 {
+		return model.PairStats{}, ErrInvalidWindow
+	}
+	if days == 0 {
+		days = model.DefaultTeamSettings("").PairWindowDays
+		if team != "" {
+			settings, err := s.teams.GetSettings(ctx, team)
+			if err != nil {
+				return model.PairStats{}, err
+			}
+			if settings.PairWindowDays > 0 {
+				days = settings.PairWindowDays
+			}
+		}
+	}
+	teams, err := s.teamScope(ctx, team, subteams)
+	if err != nil {
+		return model.PairStats{}, err
+	}
+	since := s.now().AddDate(0, 0, -days)
+	pairs, err := s.prs.ListReviewerPairs(ctx, teams, since)
+	if err != nil {
+		return model.PairStats{}, err
+	}
+	matrix := make(map[string]map[string]int)
+	for _, p := range pairs {
+		if matrix[p.AuthorID] == nil {
+			matrix[p.AuthorID] = make(map[string]int)
+		}
+		matrix[p.AuthorID][p.ReviewerID] = p.Count
+	}
+	return model.PairStats{Since: since, Pairs: pairs, Matrix: matrix}, nil
+}
+
 type DeactivateResult struct {
-	Team           string   `json:"team_name"`
+	Team string `json:"team_name"`
+	// Subteams — деактивированные вместе с командой подкоманды.
+	Subteams       []string `json:"subteams,omitempty"`
 	Deactivated    []string `json:"deactivated_user_ids"`
 	Reassigned     int      `json:"reassigned"`
 	UnassignedLeft int      `json:"unassigned_left"`
+	// FallbackAssigned — замены, взятые из запасных команд.
+	FallbackAssigned []string `json:"fallback_assigned_user_ids"`
 }
 
-// DeactivateTeam массово деактивирует пользователей команды и старается заменить их в открытых PR.
-// Если кандидатов нет, ревьювер просто снимается.
-func (s *PRService) DeactivateTeam(ctx context.Context, team string) (DeactivateResult, error) {
-	users, err := s.users.ListByTeam(ctx, team)
+// DeactivateTeam массово деактивирует пользователей команды (а если subteams — и всех её подкоманд)
+// и старается заменить их в открытых PR. Если кандидатов нет, ревьювер просто снимается.
+func (s *PRService) DeactivateTeam(ctx context.Context, team string, subteams bool) (DeactivateResult, error) {
+	teams, err := s.teamScope(ctx, team, subteams)
 	if err != nil {
 		return DeactivateResult{}, err
 	}
+	var users []model.User
+	for _, t := range teams {
+		members, err := s.users.ListByTeam(ctx, t)
+		if err != nil {
+			return DeactivateResult{}, err
+		}
+		users = append(users, members...)
+	}
 	if len(users) == 0 {
 		return DeactivateResult{}, repository.ErrNotFound
 	}
+	subs := teams[1:]
+	result, err := s.deactivateUsers(ctx, users, deactivation{
+		reason:  "team_deactivated",
+		event:   model.EventTeamDeactivated,
+		topic:   model.TopicTeamDeactivated,
+		payload: map[string]any{"team_name": team, "subteams": subs},
+	})
+	if err != nil {
+		return DeactivateResult{}, err
+	}
+	result.Team, result.Subteams = team, subs
+	return result, nil
+}
+
+// deactivation — параметры deactivateUsers: причина для журнала назначений, события PR и outbox
+// и их общие поля.
+type deactivation struct {
+	reason  string
+	event   model.PREventType
+	topic   model.EventTopic
+	payload map[string]any
+}
+
+// deactivateUsers одной транзакцией деактивирует users и заменяет их в открытых PR кандидатами
+// из их основных команд (и запасных); если кандидатов нет, ревьювер просто снимается.
+func (s *PRService) deactivateUsers(ctx context.Context, users []model.User, d deactivation) (DeactivateResult, error) {
+	ids := userIDs(users)
+	// Для каждого деактивируемого — его основная команда: замена ищется в ней.
 	deactivated := make(map[string]bool)
+	homes := make(map[string]string, len(users))
 	for _, u := range users {
 		deactivated[u.UserID] = true
+		homes[u.UserID] = u.TeamName
 	}
 
-	// Найдём открытые PR, где есть ревьюверы из этой команды.
+	// Найдём открытые PR, где они назначены ревьюверами.
 	rows, err := s.db.QueryContext(ctx, `
         SELECT DISTINCT pr.pull_request_id
         FROM pull_requests pr
         JOIN pull_request_reviewers r ON pr.pull_request_id = r.pull_request_id
-        JOIN users u ON u.user_id = r.user_id
-        WHERE pr.status = 'OPEN' AND u.team_name = $1
-    `, team)
+        WHERE pr.status = 'OPEN' AND r.user_id = ANY($1)
+    `, pq.Array(ids))
 	if err != nil {
 		return DeactivateResult{}, err
 	}
@@ -219,82 +720,146 @@ func (s *PRService) DeactivateTeam(ctx context.Context, team string) (Deactivate
 	}
 	rows.Close()
 
-	result := DeactivateResult{Team: team}
+	var result DeactivateResult
 
-	// Обрабатываем PR до смены статуса is_active, чтобы ещё можно было выбрать кандидатов из других команд.
-	for _, prID := range prIDs {
-		pr, err := s.prs.GetWithReviewers(ctx, prID)
-		if err != nil {
-			return DeactivateResult{}, err
-		}
-		assignedSet := make(map[string]bool)
-		for _, rid := range pr.AssignedReviewers {
-			assignedSet[rid] = true
-		}
-		for _, rid := range pr.AssignedReviewers {
-			if !deactivated[rid] {
+	// Все изменения и записи журнала делаем одной транзакцией: либо пользователи деактивированы
+	// вместе со всеми заменами, либо ничего не изменилось.
+	err = repository.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		prs := s.prs.WithTx(tx)
+
+		// Обрабатываем PR до смены статуса is_active, чтобы ещё можно было выбрать кандидатов из других команд.
+		for _, prID := range prIDs {
+			pr, err := prs.GetForUpdate(ctx, prID)
+			if err != nil {
+				return err
+			}
+			if pr.Status != model.PRStatusOpen {
+				// PR закрыли или смержили после выборки
 				continue
 			}
-			// снять старого
-			if err := s.prs.RemoveReviewer(ctx, prID, rid); err != nil {
-				return DeactivateResult{}, err
+			assignedSet := make(map[string]bool)
+			for _, rid := range pr.AssignedReviewers {
+				assignedSet[rid] = true
 			}
-			result.Deactivated = append(result.Deactivated, rid)
-			delete(assignedSet, rid)
-
-			// подобрать замену среди активных пользователей той же команды, не автора и не уже назначенных/деактивируемых
-			candidate, err := s.findReplacement(ctx, team, pr.AuthorID, assignedSet, deactivated)
-			if err != nil {
-				return DeactivateResult{}, err
+			unassignedBefore := result.UnassignedLeft
+			var removed, replaced []string
+			for _, rid := range pr.AssignedReviewers {
+				if !deactivated[rid] {
+					continue
+				}
+				// снять старого
+				if err := prs.RemoveReviewer(ctx, prID, rid, d.reason); err != nil {
+					return err
+				}
+				result.Deactivated = append(result.Deactivated, rid)
+				removed = append(removed, rid)
+				delete(assignedSet, rid)
+
+				// подобрать замену среди активных пользователей той же команды или её запасных команд,
+				// не автора и не уже назначенных/деактивируемых
+				candidate, ok, err := s.findReplacement(ctx, homes[rid], pr.AuthorID, assignedSet, deactivated)
+				if err != nil {
+					return err
+				}
+				if ok {
+					if err := prs.AddReviewer(ctx, prID, candidate, d.reason); err != nil {
+						return err
+					}
+					assignedSet[candidate.UserID] = true
+					replaced = append(replaced, candidate.UserID)
+					result.Reassigned++
+					if candidate.Fallback {
+						result.FallbackAssigned = append(result.FallbackAssigned, candidate.UserID)
+					}
+				} else {
+					result.UnassignedLeft++
+				}
 			}
-			if candidate != "" {
-				if err := s.prs.AddReviewer(ctx, prID, candidate); err != nil {
-					return DeactivateResult{}, err
+			if result.UnassignedLeft > unassignedBefore {
+				if err := s.refreshUnderstaffed(ctx, prs, pr.ID, pr.TeamName, len(assignedSet)); err != nil {
+					return err
 				}
-				assignedSet[candidate] = true
-				result.Reassigned++
-			} else {
-				result.UnassignedLeft++
+			}
+			if err := prs.AppendEvent(ctx, prID, d.event, "", withFields(d.payload, map[string]any{
+				"removed":  removed,
+				"replaced": replaced,
+			})); err != nil {
+				return err
 			}
 		}
-	}
 
-	// Теперь деактивируем всех пользователей команды.
-	_, err = s.db.ExecContext(ctx, `UPDATE users SET is_active=FALSE WHERE team_name=$1`, team)
+		// Теперь деактивируем всех пользователей.
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active=FALSE WHERE user_id = ANY($1)`, pq.Array(ids)); err != nil {
+			return err
+		}
+		return repository.Enqueue(ctx, tx, d.topic, withFields(d.payload, map[string]any{
+			"deactivated_user_ids": ids,
+			"reassigned":           result.Reassigned,
+			"unassigned_left":      result.UnassignedLeft,
+		}))
+	})
 	if err != nil {
 		return DeactivateResult{}, err
 	}
 	return result, nil
 }
 
-func (s *PRService) findReplacement(ctx context.Context, team, author string, assigned map[string]bool, deactivated map[string]bool) (string, error) {
-	rows, err := s.db.QueryContext(ctx, `
-        SELECT user_id
-        FROM users
-        WHERE team_name=$1 AND is_active=TRUE AND user_id <> $2
-    `, team, author)
-	if err != nil {
-		return "", err
+// withFields возвращает новый payload из полей base и extra.
+func withFields(base, extra map[string]any) map[string]any {
+	res := make(map[string]any, len(base)+len(extra))
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range extra {
+		res[k] = v
 	}
-	defer rows.Close()
+	return res
+}
 
-	var pool []string
-	for rows.Next() {
-		var uid string
-		if err := rows.Scan(&uid); err != nil {
-			return "", err
-		}
-		if assigned[uid] || deactivated[uid] {
-			continue
-		}
-		pool = append(pool, uid)
+func userIDs(users []model.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.UserID)
 	}
-	if err := rows.Err(); err != nil {
-		return "", err
+	return ids
+}
+
+func (s *PRService) findReplacement(ctx context.Context, team, author string, assigned map[string]bool, deactivated map[string]bool) (model.ReviewerAssignment, bool, error) {
+	settings, err := s.teamSettings(ctx, team)
+	if err != nil {
+		return model.ReviewerAssignment{}, false, err
+	}
+	p, err := s.newPick(ctx, settings, author)
+	if err != nil {
+		return model.ReviewerAssignment{}, false, err
+	}
+	for uid := range assigned {
+		p.exclude[uid] = true
+	}
+	for uid := range deactivated {
+		p.exclude[uid] = true
 	}
-	if len(pool) == 0 {
-		return "", nil
+	pool, err := s.pickWithFallbacks(ctx, settings, p, 1)
+	if err != nil || len(pool) == 0 {
+		return model.ReviewerAssignment{}, false, err
+	}
+	return pool[0], true, nil
+}
+
+// teamSettings возвращает настройки команды team; для пользователя вне команд (пустой team) —
+// настройки по умолчанию, без кандидатов в пуле команды.
+func (s *PRService) teamSettings(ctx context.Context, team string) (model.TeamSettings, error) {
+	if team == "" {
+		return model.DefaultTeamSettings(""), nil
+	}
+	return s.teams.GetSettings(ctx, team)
+}
+
+// refreshUnderstaffed пересчитывает флаг understaffed по min_reviewers команды PR.
+func (s *PRService) refreshUnderstaffed(ctx context.Context, prs *repository.PRsRepo, prID, team string, reviewers int) error {
+	settings, err := s.teamSettings(ctx, team)
+	if err != nil {
+		return err
 	}
-	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
-	return pool[0], nil
+	return prs.SetUnderstaffed(ctx, prID, reviewers < settings.MinReviewers)
 }
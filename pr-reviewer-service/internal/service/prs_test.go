@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestSubmitReview_RejectsNonVerdictStates(t *testing.T) {
+	s := NewPRService(nil, nil, nil, nil)
+	for _, st := range []model.ReviewState{model.ReviewPending, model.ReviewDismissed, "LGTM"} {
+		if _, err := s.SubmitReview(context.Background(), "pr-1", "u2", st); err != ErrInvalidReviewState {
+			t.Fatalf("%s: expected ErrInvalidReviewState, got %v", st, err)
+		}
+	}
+}
+
+func TestListByReviewer_RejectsUnknownState(t *testing.T) {
+	s := NewPRService(nil, nil, nil, nil)
+	states := []model.ReviewState{model.ReviewPending, "DONE"}
+	if _, err := s.ListByReviewer(context.Background(), "u2", states); err != ErrInvalidReviewState {
+		t.Fatalf("expected ErrInvalidReviewState, got %v", err)
+	}
+}
+
+func TestUnmetMergeConditions(t *testing.T) {
+	pr := func(states ...model.ReviewState) model.PullRequest {
+		var p model.PullRequest
+		for i, st := range states {
+			p.Reviewers = append(p.Reviewers, model.ReviewerAssignment{UserID: []string{"u1", "u2", "u3"}[i], State: st})
+		}
+		return p
+	}
+	settings := func(approvals int, block bool) model.TeamSettings {
+		s := model.DefaultTeamSettings("backend")
+		s.RequiredApprovals, s.BlockOnChangesRequested = approvals, block
+		return s
+	}
+
+	cases := []struct {
+		name     string
+		pr       model.PullRequest
+		settings model.TeamSettings
+		want     []string
+	}{
+		{"no rules", pr(model.ReviewPending), settings(0, false), nil},
+		{"enough approvals", pr(model.ReviewApproved, model.ReviewApproved, model.ReviewPending), settings(2, true), nil},
+		{"dismissed does not count", pr(model.ReviewApproved, model.ReviewDismissed), settings(2, true),
+			[]string{"approvals: 1 of 2 required"}},
+		{"changes requested blocks", pr(model.ReviewApproved, model.ReviewChangesRequested, model.ReviewChangesRequested), settings(1, true),
+			[]string{"changes requested by: u2, u3"}},
+		{"changes requested allowed", pr(model.ReviewApproved, model.ReviewChangesRequested), settings(1, false), nil},
+		{"both", pr(model.ReviewChangesRequested), settings(1, true),
+			[]string{"approvals: 0 of 1 required", "changes requested by: u1"}},
+	}
+	for _, c := range cases {
+		if got := unmetMergeConditions(c.pr, c.settings); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// prTransitions — допустимые переходы статусов PR. Любая смена статуса проверяется по этой таблице.
+// MERGED — финальный статус.
+var prTransitions = map[model.PRStatus][]model.PRStatus{
+	model.PRStatusDraft:  {model.PRStatusOpen, model.PRStatusClosed},
+	model.PRStatusOpen:   {model.PRStatusDraft, model.PRStatusClosed, model.PRStatusMerged},
+	model.PRStatusClosed: {model.PRStatusOpen},
+}
+
+// InvalidTransitionError — запрошенный переход статуса не разрешён.
+type InvalidTransitionError struct {
+	From model.PRStatus
+	To   model.PRStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot move PR from %s to %s", e.From, e.To)
+}
+
+func checkTransition(from, to model.PRStatus) error {
+	for _, allowed := range prTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: from, To: to}
+}
+
+// Ready переводит DRAFT в OPEN и назначает ревьюверов по правилам команды PR.
+func (s *PRService) Ready(ctx context.Context, prID string) (model.PullRequest, error) {
+	pr, err := s.prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if err := checkTransition(pr.Status, model.PRStatusOpen); err != nil {
+		return model.PullRequest{}, err
+	}
+	if pr.Status != model.PRStatusDraft {
+		return model.PullRequest{}, &InvalidTransitionError{From: pr.Status, To: model.PRStatusOpen}
+	}
+	return s.open(ctx, pr)
+}
+
+// ConvertToDraft возвращает OPEN PR в черновики. Ревьюверы и их ревью сохраняются;
+// PR не считается их нагрузкой, пока снова не станет OPEN.
+func (s *PRService) ConvertToDraft(ctx context.Context, prID string) (model.PullRequest, error) {
+	pr, err := s.prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if err := checkTransition(pr.Status, model.PRStatusDraft); err != nil {
+		return model.PullRequest{}, err
+	}
+	return s.prs.Transition(ctx, prID, pr.Status, model.PRStatusDraft)
+}
+
+// Close закрывает PR без мержа. Ревьюверы остаются назначенными, но PR больше не считается их нагрузкой.
+func (s *PRService) Close(ctx context.Context, prID string) (model.PullRequest, error) {
+	pr, err := s.prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if err := checkTransition(pr.Status, model.PRStatusClosed); err != nil {
+		return model.PullRequest{}, err
+	}
+	return s.prs.Transition(ctx, prID, pr.Status, model.PRStatusClosed)
+}
+
+// Reopen возвращает закрытый PR в OPEN. Если ревьюверов у него нет (например, он был закрыт
+// из DRAFT), они назначаются так же, как при создании.
+func (s *PRService) Reopen(ctx context.Context, prID string) (model.PullRequest, error) {
+	pr, err := s.prs.GetWithReviewers(ctx, prID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	if err := checkTransition(pr.Status, model.PRStatusOpen); err != nil {
+		return model.PullRequest{}, err
+	}
+	if pr.Status != model.PRStatusClosed {
+		return model.PullRequest{}, &InvalidTransitionError{From: pr.Status, To: model.PRStatusOpen}
+	}
+	if len(pr.AssignedReviewers) > 0 {
+		return s.prs.Transition(ctx, prID, pr.Status, model.PRStatusOpen)
+	}
+	return s.open(ctx, pr)
+}
+
+func (s *PRService) open(ctx context.Context, pr model.PullRequest) (model.PullRequest, error) {
+	author, err := s.users.GetUser(ctx, pr.AuthorID)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	st, err := s.staff(ctx, author, pr)
+	if err != nil {
+		return model.PullRequest{}, err
+	}
+	return s.prs.OpenWithReviewers(ctx, pr.ID, pr.Status, st.reviewers, st.understaffed, st.uncovered)
+}
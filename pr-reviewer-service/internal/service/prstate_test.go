@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestCheckTransition(t *testing.T) {
+	cases := []struct {
+		from, to model.PRStatus
+		ok       bool
+	}{
+		{model.PRStatusDraft, model.PRStatusOpen, true},
+		{model.PRStatusDraft, model.PRStatusClosed, true},
+		{model.PRStatusDraft, model.PRStatusMerged, false},
+		{model.PRStatusOpen, model.PRStatusMerged, true},
+		{model.PRStatusOpen, model.PRStatusClosed, true},
+		{model.PRStatusOpen, model.PRStatusDraft, true},
+		{model.PRStatusClosed, model.PRStatusDraft, false},
+		{model.PRStatusClosed, model.PRStatusOpen, true},
+		{model.PRStatusClosed, model.PRStatusMerged, false},
+		{model.PRStatusMerged, model.PRStatusOpen, false},
+		{model.PRStatusMerged, model.PRStatusClosed, false},
+	}
+	for _, c := range cases {
+		err := checkTransition(c.from, c.to)
+		if c.ok && err != nil {
+			t.Errorf("%s -> %s: unexpected error %v", c.from, c.to, err)
+		}
+		if !c.ok {
+			var te *InvalidTransitionError
+			if !errors.As(err, &te) || te.From != c.from || te.To != c.to {
+				t.Errorf("%s -> %s: expected InvalidTransitionError, got %v", c.from, c.to, err)
+			}
+		}
+	}
+}
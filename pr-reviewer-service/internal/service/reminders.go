@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+// ReviewScheduler следит за SLA ревью: напоминает ревьюверам о PR, которые ждут их дольше
+// reminder_after_hours, и заменяет тех, кто не ответил за escalate_after_hours.
+type ReviewScheduler struct {
+	prs      *PRService
+	interval time.Duration
+}
+
+func NewReviewScheduler(prs *PRService, interval time.Duration) *ReviewScheduler {
+	return &ReviewScheduler{prs: prs, interval: interval}
+}
+
+// SLAResult — итог одного прохода планировщика.
+type SLAResult struct {
+	Reminded  int
+	Escalated int
+	// NoCandidate — эскалации, для которых не нашлось замены; ревьювер остался назначен.
+	NoCandidate int
+}
+
+// Run работает до отмены ctx.
+func (s *ReviewScheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		res, err := s.Tick(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("review sla: %v", err)
+		}
+		if res.Reminded+res.Escalated+res.NoCandidate > 0 {
+			log.Printf("review sla: reminded %d, escalated %d, no candidate %d", res.Reminded, res.Escalated, res.NoCandidate)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick выполняет один проход. Ошибка по одному ревью не мешает обработать остальные.
+func (s *ReviewScheduler) Tick(ctx context.Context) (SLAResult, error) {
+	now := s.prs.now()
+	var res SLAResult
+	var errs []error
+
+	overdue, err := s.prs.prs.ListOverdueReviews(ctx, now)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range overdue {
+		switch overdueAction(o, now) {
+		case slaRemind:
+			err := s.prs.prs.RemindReviewer(ctx, o.PullRequestID, o.UserID, now)
+			switch err {
+			case nil:
+				res.Reminded++
+			case repository.ErrNotFound:
+				// ревью уже оставлено или ревьювер снят
+			default:
+				errs = append(errs, err)
+			}
+		case slaEscalate:
+			esc, err := s.prs.escalate(ctx, o)
+			switch {
+			case err == nil && esc.ToUserID != nil:
+				res.Escalated++
+			case err == nil:
+				res.NoCandidate++
+			case err == ErrNotAssigned:
+			default:
+				errs = append(errs, err)
+			}
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+type slaAction int
+
+const (
+	slaNone slaAction = iota
+	slaRemind
+	slaEscalate
+)
+
+// overdueAction решает, что делать с ревью к моменту now. Эскалация важнее напоминания;
+// напоминание повторяется каждые reminder_after_hours с предыдущего.
+func overdueAction(o model.OverdueReview, now time.Time) slaAction {
+	waited := now.Sub(o.AssignedAt)
+	if o.EscalateAfterHours > 0 && o.EscalatedAt == nil && waited >= hours(o.EscalateAfterHours) {
+		return slaEscalate
+	}
+	last := o.AssignedAt
+	if o.RemindedAt != nil {
+		last = *o.RemindedAt
+	}
+	if o.ReminderAfterHours > 0 && now.Sub(last) >= hours(o.ReminderAfterHours) {
+		return slaRemind
+	}
+	return slaNone
+}
+
+func hours(n int) time.Duration { return time.Duration(n) * time.Hour }
+
+// escalate заменяет ревьювера просроченного ревью по той же логике, что и Reassign, и записывает эскалацию.
+// Если замены нет, ревьювер остаётся назначен, а эскалация записывается без to_user_id.
+// Если ревью уже не в PENDING или PR не OPEN, возвращается ErrNotAssigned.
+func (s *PRService) escalate(ctx context.Context, o model.OverdueReview) (model.Escalation, error) {
+	esc := model.Escalation{
+		PullRequestID: o.PullRequestID,
+		TeamName:      o.TeamName,
+		FromUserID:    o.UserID,
+		AssignedAt:    o.AssignedAt,
+	}
+	err := repository.InTx(ctx, s.db, func(tx *sql.Tx) error {
+		prs := s.prs.WithTx(tx)
+		pr, err := prs.GetForUpdate(ctx, o.PullRequestID)
+		if err != nil {
+			return err
+		}
+		if pr.Status != model.PRStatusOpen || !isPending(pr, o.UserID) {
+			return ErrNotAssigned
+		}
+
+		rv, err := s.replaceReviewer(ctx, prs, pr, o.UserID, "escalation")
+		switch err {
+		case nil:
+			esc.ToUserID = &rv.UserID
+		case ErrNoCandidate:
+		default:
+			return err
+		}
+		esc, err = prs.RecordEscalation(ctx, esc)
+		return err
+	})
+	if err != nil {
+		return model.Escalation{}, err
+	}
+	return esc, nil
+}
+
+func isPending(pr model.PullRequest, userID string) bool {
+	for _, rv := range pr.Reviewers {
+		if rv.UserID == userID {
+			return rv.State == model.ReviewPending
+		}
+	}
+	return false
+}
+
+// Escalations возвращает последние эскалации; пустой team — по всем командам,
+// если subteams — вместе с подкомандами team.
+func (s *PRService) Escalations(ctx context.Context, team string, subteams bool) ([]model.Escalation, error) {
+	teams, err := s.teamScope(ctx, team, subteams)
+	if err != nil {
+		return nil, err
+	}
+	return s.prs.ListEscalations(ctx, teams, 0)
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestOverdueAction(t *testing.T) {
+	now := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	ago := func(h int) *time.Time {
+		t := now.Add(-time.Duration(h) * time.Hour)
+		return &t
+	}
+	review := func(assignedHoursAgo int) model.OverdueReview {
+		return model.OverdueReview{AssignedAt: *ago(assignedHoursAgo), ReminderAfterHours: 24, EscalateAfterHours: 72}
+	}
+
+	cases := []struct {
+		name string
+		mod  func(*model.OverdueReview)
+		age  int
+		want slaAction
+	}{
+		{"fresh", nil, 23, slaNone},
+		{"first reminder", nil, 24, slaRemind},
+		{"reminded recently", func(o *model.OverdueReview) { o.RemindedAt = ago(10) }, 40, slaNone},
+		{"repeat reminder", func(o *model.OverdueReview) { o.RemindedAt = ago(24) }, 48, slaRemind},
+		{"escalation wins", func(o *model.OverdueReview) { o.RemindedAt = ago(1) }, 72, slaEscalate},
+		{"escalated without candidate keeps reminding", func(o *model.OverdueReview) {
+			o.EscalatedAt, o.RemindedAt = ago(30), ago(30)
+		}, 100, slaRemind},
+		{"reminders disabled", func(o *model.OverdueReview) { o.ReminderAfterHours = 0 }, 50, slaNone},
+		{"escalation disabled", func(o *model.OverdueReview) {
+			o.EscalateAfterHours = 0
+			o.RemindedAt = ago(1)
+		}, 500, slaNone},
+	}
+	for _, c := range cases {
+		o := review(c.age)
+		if c.mod != nil {
+			c.mod(&o)
+		}
+		if got := overdueAction(o, now); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"pr-reviewer-service/internal/model"
+)
+
+// ReviewerSelector выбирает до n ревьюверов из уже отфильтрованного пула кандидатов.
+// pool — ключ пула (имя команды), нужен стратегиям с состоянием. Кандидатов с недавними
+// парами с автором (Pairs) стратегия должна выбирать реже остальных.
+type ReviewerSelector interface {
+	Select(pool string, candidates []model.ReviewCandidate, n int) []model.ReviewCandidate
+}
+
+// NewSelectors возвращает встроенные стратегии выбора ревьюверов.
+func NewSelectors() map[model.SelectionStrategy]ReviewerSelector {
+	return map[model.SelectionStrategy]ReviewerSelector{
+		model.StrategyRandom:      RandomSelector{},
+		model.StrategyRoundRobin:  NewRoundRobinSelector(),
+		model.StrategyLeastLoaded: LeastLoadedSelector{},
+		model.StrategyWeighted:    WeightedSelector{},
+	}
+}
+
+// RandomSelector выбирает кандидатов случайно, начиная с тех, у кого меньше недавних пар с автором.
+type RandomSelector struct{}
+
+func (RandomSelector) Select(_ string, candidates []model.ReviewCandidate, n int) []model.ReviewCandidate {
+	pool := shuffled(candidates)
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].Pairs < pool[j].Pairs })
+	return head(pool, n)
+}
+
+// RoundRobinSelector обходит кандидатов пула по кругу в порядке user_id,
+// продолжая с пользователя, следующего за последним выбранным. Кандидаты с недавними
+// парами с автором пропускаются в этом круге, если без них набирается n.
+type RoundRobinSelector struct {
+	mu   sync.Mutex
+	last map[string]string
+}
+
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{last: make(map[string]string)}
+}
+
+func (s *RoundRobinSelector) Select(pool string, candidates []model.ReviewCandidate, n int) []model.ReviewCandidate {
+	if len(candidates) == 0 || n <= 0 {
+		return nil
+	}
+	sorted := append([]model.ReviewCandidate(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UserID < sorted[j].UserID })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := sort.Search(len(sorted), func(i int) bool { return sorted[i].UserID > s.last[pool] })
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	order := make([]model.ReviewCandidate, 0, len(sorted))
+	for i := range sorted {
+		order = append(order, sorted[(start+i)%len(sorted)])
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Pairs < order[j].Pairs })
+	res := order[:n]
+	s.last[pool] = res[len(res)-1].UserID
+	return res
+}
+
+// LeastLoadedSelector предпочитает кандидатов с наименьшей нагрузкой, при равенстве — случайно.
+// Каждая недавняя пара с автором весит как одно открытое ревью, поэтому свободный ревьювер,
+// уже ревьюивший автора, всё равно предпочтительнее загруженного.
+type LeastLoadedSelector struct{}
+
+func (LeastLoadedSelector) Select(_ string, candidates []model.ReviewCandidate, n int) []model.ReviewCandidate {
+	pool := shuffled(candidates)
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].Load+pool[i].Pairs < pool[j].Load+pool[j].Pairs })
+	return head(pool, n)
+}
+
+// WeightedSelector выбирает кандидатов случайно с вероятностью, пропорциональной весу
+// (взвешенная выборка без возвращения, алгоритм Efraimidis–Spirakis). Вес кандидата
+// делится на 1+Pairs: две недавние пары с автором втрое снижают шанс выбора.
+type WeightedSelector struct{}
+
+func (WeightedSelector) Select(_ string, candidates []model.ReviewCandidate, n int) []model.ReviewCandidate {
+	type keyed struct {
+		c   model.ReviewCandidate
+		key float64
+	}
+	pool := make([]keyed, 0, len(candidates))
+	for _, c := range candidates {
+		w := c.Weight
+		if w <= 0 {
+			w = 1
+		}
+		pool = append(pool, keyed{c: c, key: math.Pow(rand.Float64(), float64(1+c.Pairs)/float64(w))})
+	}
+	sort.Slice(pool, func(i, j int) bool { return pool[i].key > pool[j].key })
+
+	res := make([]model.ReviewCandidate, 0, len(pool))
+	for _, k := range pool {
+		res = append(res, k.c)
+	}
+	return head(res, n)
+}
+
+func shuffled(candidates []model.ReviewCandidate) []model.ReviewCandidate {
+	pool := append([]model.ReviewCandidate(nil), candidates...)
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	return pool
+}
+
+func head(candidates []model.ReviewCandidate, n int) []model.ReviewCandidate {
+	if n < 0 {
+		n = 0
+	}
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
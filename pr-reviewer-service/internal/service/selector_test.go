@@ -0,0 +1,178 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func ids(cs []model.ReviewCandidate) []string {
+	var res []string
+	for _, c := range cs {
+		res = append(res, c.UserID)
+	}
+	return res
+}
+
+func TestRoundRobinSelector_Rotates(t *testing.T) {
+	s := NewRoundRobinSelector()
+	pool := []model.ReviewCandidate{{UserID: "u3"}, {UserID: "u1"}, {UserID: "u2"}}
+
+	want := [][]string{{"u1", "u2"}, {"u3", "u1"}, {"u2", "u3"}}
+	for i, w := range want {
+		got := ids(s.Select("backend", pool, 2))
+		if len(got) != 2 || got[0] != w[0] || got[1] != w[1] {
+			t.Fatalf("round %d: got %v, want %v", i, got, w)
+		}
+	}
+	if got := ids(s.Select("frontend", pool, 1)); got[0] != "u1" {
+		t.Fatalf("pools must rotate independently, got %v", got)
+	}
+}
+
+func TestLeastLoadedSelector_PrefersLowLoad(t *testing.T) {
+	pool := []model.ReviewCandidate{
+		{UserID: "busy", Load: 10},
+		{UserID: "idle", Load: 0},
+		{UserID: "some", Load: 2},
+	}
+	got := ids(LeastLoadedSelector{}.Select("t", pool, 2))
+	if len(got) != 2 || got[0] != "idle" || got[1] != "some" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestLeastLoadedSelector_BreaksTiesRandomly(t *testing.T) {
+	pool := []model.ReviewCandidate{
+		{UserID: "a", Load: 1},
+		{UserID: "b", Load: 1},
+		{UserID: "busy", Load: 3},
+	}
+	seen := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		seen[LeastLoadedSelector{}.Select("t", pool, 1)[0].UserID]++
+	}
+	if seen["busy"] != 0 || seen["a"] == 0 || seen["b"] == 0 {
+		t.Fatalf("equal load must be picked at random, got %v", seen)
+	}
+}
+
+func TestWeightedSelector_RespectsWeights(t *testing.T) {
+	pool := []model.ReviewCandidate{{UserID: "heavy", Weight: 50}, {UserID: "light", Weight: 1}}
+	heavy := 0
+	for i := 0; i < 1000; i++ {
+		if (WeightedSelector{}).Select("t", pool, 1)[0].UserID == "heavy" {
+			heavy++
+		}
+	}
+	if heavy < 900 {
+		t.Fatalf("heavy picked %d/1000 times, expected strong preference", heavy)
+	}
+}
+
+func TestSelectors_LimitAndEmpty(t *testing.T) {
+	pool := []model.ReviewCandidate{{UserID: "u1"}}
+	for name, s := range NewSelectors() {
+		if got := s.Select("t", pool, 2); len(got) != 1 {
+			t.Fatalf("%s: expected 1 candidate, got %d", name, len(got))
+		}
+		if got := s.Select("t", nil, 2); len(got) != 0 {
+			t.Fatalf("%s: expected no candidates, got %d", name, len(got))
+		}
+	}
+}
+
+func TestSelectFrom_PrefersWorkingHours(t *testing.T) {
+	// 02:00 в Новосибирске, 22:00 в Москве, среда.
+	clock := func() time.Time { return time.Date(2025, 10, 21, 19, 0, 0, 0, time.UTC) }
+	s := (&PRService{selectors: NewSelectors()}).WithClock(clock)
+
+	moscowLate := &model.WorkSchedule{Timezone: "Europe/Moscow", Start: "12:00", End: "23:00", Days: model.DefaultWorkDays}
+	novosibirsk := &model.WorkSchedule{Timezone: "Asia/Novosibirsk", Start: "10:00", End: "19:00", Days: model.DefaultWorkDays}
+	pool := []model.ReviewCandidate{
+		{UserID: "nsk1", Schedule: novosibirsk},
+		{UserID: "msk", Schedule: moscowLate},
+		{UserID: "nsk2", Schedule: novosibirsk},
+		{UserID: "anytime"},
+	}
+
+	for i := 0; i < 20; i++ {
+		got := s.selectFrom(model.StrategyRandom, "t", pool, &pick{}, 2)
+		if len(got) != 2 || !(got[0] == "msk" || got[0] == "anytime") || !(got[1] == "msk" || got[1] == "anytime") {
+			t.Fatalf("in-hours candidates must be picked first, got %v", got)
+		}
+	}
+
+	got := s.selectFrom(model.StrategyRandom, "t", pool, &pick{exclude: map[string]bool{"anytime": true}}, 2)
+	if len(got) != 2 || got[0] != "msk" || (got[1] != "nsk1" && got[1] != "nsk2") {
+		t.Fatalf("off-hours candidates must fill the rest, got %v", got)
+	}
+
+	got = s.selectFrom(model.StrategyRandom, "t", pool[:1], &pick{}, 2)
+	if len(got) != 1 || got[0] != "nsk1" {
+		t.Fatalf("off-hours candidate must be used when nobody is in hours, got %v", got)
+	}
+}
+
+func TestSelectFrom_PenalizesRecentPairs(t *testing.T) {
+	s := (&PRService{selectors: NewSelectors()}).WithClock(time.Now)
+	pool := []model.ReviewCandidate{{UserID: "often"}, {UserID: "once"}, {UserID: "never"}}
+	p := &pick{pairs: map[string]int{"often": 3, "once": 1}}
+
+	for i := 0; i < 20; i++ {
+		got := s.selectFrom(model.StrategyLeastLoaded, "t", pool, p, 2)
+		if len(got) != 2 || got[0] != "never" || got[1] != "once" {
+			t.Fatalf("rarer pairs must be picked first, got %v", got)
+		}
+	}
+}
+
+func TestSelectFrom_PairsWeighedAgainstLoad(t *testing.T) {
+	s := (&PRService{selectors: NewSelectors()}).WithClock(time.Now)
+	pool := []model.ReviewCandidate{{UserID: "busy", Load: 5}, {UserID: "paired", Load: 0}}
+
+	// Одна недавняя пара не перевешивает пять открытых ревью.
+	got := s.selectFrom(model.StrategyLeastLoaded, "t", pool, &pick{pairs: map[string]int{"paired": 1}}, 1)
+	if len(got) != 1 || got[0] != "paired" {
+		t.Fatalf("lightly loaded paired reviewer must win, got %v", got)
+	}
+	// А шесть — перевешивают.
+	got = s.selectFrom(model.StrategyLeastLoaded, "t", pool, &pick{pairs: map[string]int{"paired": 6}}, 1)
+	if len(got) != 1 || got[0] != "busy" {
+		t.Fatalf("frequent pair must lose to the busy reviewer, got %v", got)
+	}
+}
+
+func TestSelectFrom_PairsRespectedByEveryStrategy(t *testing.T) {
+	s := (&PRService{selectors: NewSelectors()}).WithClock(time.Now)
+	pool := []model.ReviewCandidate{{UserID: "paired"}, {UserID: "fresh1"}, {UserID: "fresh2"}}
+	p := &pick{pairs: map[string]int{"paired": 1}}
+
+	for _, strategy := range []model.SelectionStrategy{model.StrategyRandom, model.StrategyRoundRobin, model.StrategyLeastLoaded} {
+		for i := 0; i < 20; i++ {
+			got := s.selectFrom(strategy, "t", pool, p, 2)
+			if len(got) != 2 || got[0] == "paired" || got[1] == "paired" {
+				t.Fatalf("%s: recent pair must be avoided, got %v", strategy, got)
+			}
+		}
+		// Если других кандидатов не хватает, пара всё же выбирается.
+		if got := s.selectFrom(strategy, "t", pool, p, 3); len(got) != 3 {
+			t.Fatalf("%s: paired reviewer must fill the rest, got %v", strategy, got)
+		}
+	}
+}
+
+func TestWeightedSelector_PairsReduceWeight(t *testing.T) {
+	pool := []model.ReviewCandidate{{UserID: "paired", Weight: 1, Pairs: 9}, {UserID: "fresh", Weight: 1}}
+	paired := 0
+	for i := 0; i < 1000; i++ {
+		if (WeightedSelector{}).Select("t", pool, 1)[0].UserID == "paired" {
+			paired++
+		}
+	}
+	// Вес 1/10 против 1: ожидается около 91 из 1000.
+	if paired > 200 {
+		t.Fatalf("paired picked %d/1000 times, expected a rare pick", paired)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer-service/internal/model"
+)
+
+var (
+	ErrInvalidLevel   = errors.New("invalid level")
+	ErrSeniorityUnmet = errors.New("seniority rules unmet")
+)
+
+// seniorityGap возвращает, скольких подходящих ревьюверов не хватает ревьюверам с уровнями levels
+// до правил уровня команды: недостающих senior/lead, а если их хватает — одного не-junior,
+// когда все ревьюверы junior и это запрещено. Без ревьюверов forbid_junior_only не нарушен.
+func seniorityGap(settings model.TeamSettings, levels []model.Level) int {
+	seniors, juniors := 0, 0
+	for _, l := range levels {
+		if l.Senior() {
+			seniors++
+		}
+		if l == model.LevelJunior {
+			juniors++
+		}
+	}
+	if missing := settings.MinSeniorReviewers - seniors; missing > 0 {
+		return missing
+	}
+	if settings.ForbidJuniorOnly && len(levels) > 0 && juniors == len(levels) {
+		return 1
+	}
+	return 0
+}
+
+// levelFilter возвращает фильтр кандидатов, с которыми ревьюверы с уровнями levels ближе к правилам
+// уровня команды, чем с junior на том же месте; nil — подойдёт любой кандидат.
+func levelFilter(settings model.TeamSettings, levels []model.Level) func(model.ReviewCandidate) bool {
+	with := func(l model.Level) int {
+		return seniorityGap(settings, append(levels[:len(levels):len(levels)], l))
+	}
+	worst := with(model.LevelJunior)
+	if worst == 0 {
+		return nil
+	}
+	return func(c model.ReviewCandidate) bool { return with(c.Level) < worst }
+}
+
+// levelsOf возвращает уровни пользователей ids в том же порядке.
+func (s *PRService) levelsOf(ctx context.Context, ids []string) ([]model.Level, error) {
+	users, err := s.users.ListByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Level, len(users))
+	for _, u := range users {
+		byID[u.UserID] = u.Level
+	}
+	res := make([]model.Level, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, byID[id])
+	}
+	return res, nil
+}
+
+// enforceSeniority доводит состав ревьюверов PR (assigned и новые reviewers) до правил уровня команды.
+// Кандидаты нужного уровня берутся из команды settings и её запасных команд: сначала на свободные
+// места до reviewer_count, затем вместо последних выбранных новых ревьюверов, которые правилам
+// не помогают. Если подходящих кандидатов нет, возвращается ErrSeniorityUnmet.
+func (s *PRService) enforceSeniority(ctx context.Context, settings model.TeamSettings, p *pick, assigned []string, reviewers []model.ReviewerAssignment) ([]model.ReviewerAssignment, error) {
+	if (settings.MinSeniorReviewers == 0 && !settings.ForbidJuniorOnly) || len(assigned)+len(reviewers) == 0 {
+		return reviewers, nil
+	}
+	for {
+		levels, err := s.levelsOf(ctx, reviewerIDs(assigned, reviewers))
+		if err != nil {
+			return nil, err
+		}
+		gap := seniorityGap(settings, levels)
+		if gap == 0 {
+			return reviewers, nil
+		}
+
+		// Место для нового ревьювера: свободное или занятое последним новым ревьювером,
+		// без которого правила выполняются не хуже.
+		slot := len(reviewers)
+		if len(assigned)+len(reviewers) >= settings.ReviewerCount {
+			slot = -1
+			for i := len(reviewers) - 1; i >= 0; i-- {
+				rest := append(append([]model.Level(nil), levels[:len(assigned)+i]...), levels[len(assigned)+i+1:]...)
+				if seniorityGap(settings, rest) <= gap {
+					slot = i
+					break
+				}
+			}
+			if slot < 0 {
+				return nil, ErrSeniorityUnmet
+			}
+			levels = append(levels[:len(assigned)+slot:len(assigned)+slot], levels[len(assigned)+slot+1:]...)
+		}
+
+		p.only = levelFilter(settings, levels)
+		picked, err := s.pickWithFallbacks(ctx, settings, p, 1)
+		p.only = nil
+		if err != nil {
+			return nil, err
+		}
+		if len(picked) == 0 {
+			return nil, ErrSeniorityUnmet
+		}
+		picked[0].Reason = model.ReasonSeniority
+		if slot == len(reviewers) {
+			reviewers = append(reviewers, picked[0])
+		} else {
+			reviewers[slot] = picked[0]
+		}
+	}
+}
+
+// replacementFilter возвращает фильтр кандидатов на место oldUserID в PR, при которых правила уровня
+// команды PR остаются выполненными; nil — подойдёт любой кандидат.
+func (s *PRService) replacementFilter(ctx context.Context, pr model.PullRequest, oldUserID string) (func(model.ReviewCandidate) bool, error) {
+	rules, err := s.teamSettings(ctx, pr.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	if rules.MinSeniorReviewers == 0 && !rules.ForbidJuniorOnly {
+		return nil, nil
+	}
+	var rest []string
+	for _, uid := range pr.AssignedReviewers {
+		if uid != oldUserID {
+			rest = append(rest, uid)
+		}
+	}
+	levels, err := s.levelsOf(ctx, rest)
+	if err != nil {
+		return nil, err
+	}
+	return levelFilter(rules, levels), nil
+}
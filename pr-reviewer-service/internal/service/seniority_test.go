@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestSeniorityGap(t *testing.T) {
+	j, m, s, l := model.LevelJunior, model.LevelMiddle, model.LevelSenior, model.LevelLead
+	oneSenior := model.TeamSettings{MinSeniorReviewers: 1}
+	noJuniorOnly := model.TeamSettings{ForbidJuniorOnly: true}
+
+	cases := []struct {
+		name     string
+		settings model.TeamSettings
+		levels   []model.Level
+		want     int
+	}{
+		{"no rules", model.TeamSettings{}, []model.Level{j, j}, 0},
+		{"senior present", oneSenior, []model.Level{j, s}, 0},
+		{"lead counts as senior", oneSenior, []model.Level{m, l}, 0},
+		{"senior missing", oneSenior, []model.Level{j, m}, 1},
+		{"two seniors missing", model.TeamSettings{MinSeniorReviewers: 2}, []model.Level{s}, 1},
+		{"no reviewers need a senior", oneSenior, nil, 1},
+		{"juniors only", noJuniorOnly, []model.Level{j, j}, 1},
+		{"unset level is not junior", noJuniorOnly, []model.Level{j, ""}, 0},
+		{"no reviewers are not junior-only", noJuniorOnly, nil, 0},
+	}
+	for _, c := range cases {
+		if got := seniorityGap(c.settings, c.levels); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	candidate := func(l model.Level) model.ReviewCandidate { return model.ReviewCandidate{Level: l} }
+
+	if f := levelFilter(model.TeamSettings{MinSeniorReviewers: 1}, []model.Level{model.LevelSenior}); f != nil {
+		t.Fatal("rules already hold, any candidate must fit")
+	}
+
+	f := levelFilter(model.TeamSettings{MinSeniorReviewers: 1}, []model.Level{model.LevelMiddle})
+	if f == nil || f(candidate(model.LevelMiddle)) || !f(candidate(model.LevelLead)) {
+		t.Fatal("only senior or lead may replace the last senior")
+	}
+
+	f = levelFilter(model.TeamSettings{ForbidJuniorOnly: true}, []model.Level{model.LevelJunior})
+	if f == nil || f(candidate(model.LevelJunior)) || !f(candidate(model.LevelMiddle)) || !f(candidate("")) {
+		t.Fatal("a junior must not join a junior-only review")
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"pr-reviewer-service/internal/model"
+	"pr-reviewer-service/internal/repository"
+)
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// SubscriptionsService управляет подписками на исходящие вебхуки и показывает журнал их доставок.
+type SubscriptionsService struct {
+	subs   *repository.SubscriptionsRepo
+	outbox *repository.OutboxRepo
+}
+
+func NewSubscriptionsService(subs *repository.SubscriptionsRepo, outbox *repository.OutboxRepo) *SubscriptionsService {
+	return &SubscriptionsService{subs: subs, outbox: outbox}
+}
+
+func (s *SubscriptionsService) Create(ctx context.Context, sub model.WebhookSubscription) (model.WebhookSubscription, error) {
+	if !isHTTPURL(sub.URL) {
+		return model.WebhookSubscription{}, ErrInvalidSubscription
+	}
+	if sub.Secret == "" {
+		return model.WebhookSubscription{}, ErrInvalidSubscription
+	}
+	seen := make(map[model.EventTopic]bool)
+	for _, t := range sub.Topics {
+		if !t.Valid() || seen[t] {
+			return model.WebhookSubscription{}, ErrInvalidSubscription
+		}
+		seen[t] = true
+	}
+	if sub.Topics == nil {
+		sub.Topics = []model.EventTopic{}
+	}
+	created, err := s.subs.Create(ctx, sub)
+	created.Secret = ""
+	return created, err
+}
+
+// List возвращает подписки без секретов.
+func (s *SubscriptionsService) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	subs, err := s.subs.List(ctx)
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, err
+}
+
+func (s *SubscriptionsService) Delete(ctx context.Context, id int64) error {
+	return s.subs.Delete(ctx, id)
+}
+
+// Deliveries возвращает журнал доставок вебхуков; subscriptionID == 0 — по всем подпискам.
+func (s *SubscriptionsService) Deliveries(ctx context.Context, subscriptionID int64, status model.DeliveryStatus) ([]model.Delivery, error) {
+	f := repository.DeliveryFilter{Channel: "webhook", Status: status}
+	if subscriptionID != 0 {
+		f.Target = strconv.FormatInt(subscriptionID, 10)
+	}
+	return s.outbox.ListDeliveries(ctx, f)
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pr-reviewer-service/internal/model"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,63}$`)
+
+// normalizeTags приводит теги к нижнему регистру, убирает повторы и сортирует.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !tagPattern.MatchString(t) {
+			return nil, ErrInvalidTag
+		}
+		if !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// missingTags возвращает теги из required, которых нет ни у одного пользователя из have.
+func missingTags(required []string, have map[string][]string) []string {
+	covered := make(map[string]bool)
+	for _, tags := range have {
+		for _, t := range tags {
+			covered[t] = true
+		}
+	}
+	var res []string
+	for _, t := range required {
+		if !covered[t] {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// uncoveredTags возвращает обязательные теги, которых нет ни у одного из ревьюверов ids.
+func (s *PRService) uncoveredTags(ctx context.Context, required, ids []string) ([]string, error) {
+	if len(required) == 0 {
+		return nil, nil
+	}
+	users, err := s.users.ListByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string][]string, len(users))
+	for _, u := range users {
+		have[u.UserID] = u.Tags
+	}
+	return missingTags(required, have), nil
+}
+
+// pickForTags выбирает до n ревьюверов так, чтобы покрыть теги missing: для каждого ещё не покрытого тега
+// стратегией команды выбирается один из кандидатов с этим тегом — сначала в команде settings, затем
+// в запасных командах и командах выше по дереву. Выбранные добавляются в p.exclude.
+func (s *PRService) pickForTags(ctx context.Context, settings model.TeamSettings, missing []string, p *pick, n int) ([]model.ReviewerAssignment, error) {
+	if len(missing) == 0 || n <= 0 {
+		return nil, nil
+	}
+	pools, err := s.pools(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	covered := make(map[string]bool)
+
+	var res []model.ReviewerAssignment
+	for i, team := range pools {
+		if len(res) >= n || len(covered) == len(missing) {
+			break
+		}
+		all, err := s.users.ListReviewCandidates(ctx, team, s.now())
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]model.ReviewCandidate, len(all))
+		for _, c := range all {
+			byID[c.UserID] = c
+		}
+		for _, tag := range missing {
+			if covered[tag] || len(res) >= n {
+				continue
+			}
+			var skilled []model.ReviewCandidate
+			for _, c := range all {
+				if hasTag(c.Tags, tag) {
+					skilled = append(skilled, c)
+				}
+			}
+			for _, uid := range s.selectFrom(settings.SelectionStrategy, "skill:"+team, skilled, p, 1) {
+				p.exclude[uid] = true
+				var matched []string
+				for _, t := range missing {
+					if !covered[t] && hasTag(byID[uid].Tags, t) {
+						covered[t] = true
+						matched = append(matched, t)
+					}
+				}
+				res = append(res, model.ReviewerAssignment{UserID: uid, Fallback: i > 0, Reason: model.ReasonSkill, MatchedTags: matched})
+			}
+		}
+	}
+	return res, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
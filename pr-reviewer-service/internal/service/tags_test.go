@@ -0,0 +1,35 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := normalizeTags([]string{" Postgres", "security", "postgres", "c++"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c++", "postgres", "security"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, bad := range []string{"", "two words", "-lead", "кириллица"} {
+		if _, err := normalizeTags([]string{bad}); err != ErrInvalidTag {
+			t.Fatalf("%q: expected ErrInvalidTag, got %v", bad, err)
+		}
+	}
+}
+
+func TestMissingTags(t *testing.T) {
+	have := map[string][]string{
+		"u1": {"frontend"},
+		"u2": {"postgres", "go"},
+	}
+	got := missingTags([]string{"frontend", "postgres", "security"}, have)
+	if want := []string{"security"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := missingTags(nil, have); len(got) != 0 {
+		t.Fatalf("no required tags must leave nothing uncovered, got %v", got)
+	}
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"pr-reviewer-service/internal/codeowners"
 	"pr-reviewer-service/internal/model"
 	"pr-reviewer-service/internal/repository"
 )
@@ -19,6 +22,123 @@ func (s *TeamsService) Create(ctx context.Context, t model.Team) (model.Team, er
 	return s.teams.CreateTeamWithMembers(ctx, t)
 }
 
-func (s *TeamsService) Get(ctx context.Context, name string) (model.Team, error) {
-	return s.teams.GetTeam(ctx, name)
+// Get возвращает команду; если tags не пуст — только с участниками, у которых есть все эти теги,
+// если subteams — вместе с участниками всех её подкоманд.
+func (s *TeamsService) Get(ctx context.Context, name string, tags []string, subteams bool) (model.Team, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return model.Team{}, err
+	}
+	return s.teams.GetTeam(ctx, name, tags, subteams)
+}
+
+// SetParent задаёт родительскую команду; пустой parent делает команду командой верхнего уровня.
+func (s *TeamsService) SetParent(ctx context.Context, team, parent string) (model.Team, error) {
+	return s.teams.SetParent(ctx, team, parent)
+}
+
+var (
+	ErrInvalidMembers  = errors.New("invalid members")
+	ErrInvalidTeamName = errors.New("invalid team name")
+	ErrInvalidWeight   = errors.New("invalid review weight")
+)
+
+// AddMembers добавляет участников в существующую команду: новые пользователи заводятся с ней как
+// с основной, уже заведённые начинают ревьюить и в ней.
+func (s *TeamsService) AddMembers(ctx context.Context, team string, members []model.TeamMember) (model.Team, error) {
+	if len(members) == 0 {
+		return model.Team{}, ErrInvalidMembers
+	}
+	for _, m := range members {
+		if m.UserID == "" {
+			return model.Team{}, ErrInvalidMembers
+		}
+	}
+	return s.teams.AddMembers(ctx, team, members)
+}
+
+// SetMemberWeight задаёт вес участника в команде для стратегии weighted; nil — общий вес пользователя.
+func (s *TeamsService) SetMemberWeight(ctx context.Context, team, userID string, weight *int) error {
+	if weight != nil && *weight < 1 {
+		return ErrInvalidWeight
+	}
+	return s.teams.SetMemberWeight(ctx, team, userID, weight)
+}
+
+// Rename переименовывает команду вместе с её участниками, настройками и правилами CODEOWNERS.
+func (s *TeamsService) Rename(ctx context.Context, name, newName string) (model.Team, error) {
+	if newName == "" || newName == name {
+		return model.Team{}, ErrInvalidTeamName
+	}
+	return s.teams.RenameTeam(ctx, name, newName)
+}
+
+// Delete удаляет команду без участников, которая не указана запасной у других команд.
+func (s *TeamsService) Delete(ctx context.Context, name string) error {
+	return s.teams.DeleteTeam(ctx, name)
+}
+
+var ErrInvalidSettings = errors.New("invalid team settings")
+
+func (s *TeamsService) GetSettings(ctx context.Context, team string) (model.TeamSettings, error) {
+	return s.teams.GetSettings(ctx, team)
+}
+
+func (s *TeamsService) UpdateSettings(ctx context.Context, settings model.TeamSettings) (model.TeamSettings, error) {
+	if !settings.SelectionStrategy.Valid() || !settings.UnderstaffedPolicy.Valid() {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.ReviewerCount < 1 || settings.MinReviewers < 0 || settings.MinReviewers > settings.ReviewerCount {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.RequiredApprovals < 0 {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	seen := map[string]bool{settings.TeamName: true}
+	for _, fb := range settings.FallbackTeams {
+		if seen[fb] {
+			return model.TeamSettings{}, ErrInvalidSettings
+		}
+		seen[fb] = true
+	}
+	if settings.ChatWebhookURL != "" && !isHTTPURL(settings.ChatWebhookURL) {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.ReminderAfterHours < 0 || settings.EscalateAfterHours < 0 || settings.PairWindowDays < 0 {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.MinSeniorReviewers < 0 || settings.MinSeniorReviewers > settings.ReviewerCount {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.EscalateAfterHours > 0 && settings.EscalateAfterHours <= settings.ReminderAfterHours {
+		return model.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.FallbackTeams == nil {
+		settings.FallbackTeams = []string{}
+	}
+	return s.teams.UpsertSettings(ctx, settings)
+}
+
+var ErrInvalidCodeowners = errors.New("invalid codeowners")
+
+// SetCodeowners разбирает файл CODEOWNERS и заменяет им правила команды или репозитория.
+func (s *TeamsService) SetCodeowners(ctx context.Context, scope model.CodeownersScope, name, content string) ([]model.CodeownersRule, error) {
+	if name == "" || (scope != model.CodeownersTeam && scope != model.CodeownersRepository) {
+		return nil, ErrInvalidCodeowners
+	}
+	rules, err := codeowners.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCodeowners, err)
+	}
+	if err := s.teams.ReplaceCodeowners(ctx, scope, name, rules); err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []model.CodeownersRule{}
+	}
+	return rules, nil
+}
+
+func (s *TeamsService) GetCodeowners(ctx context.Context, scope model.CodeownersScope, name string) ([]model.CodeownersRule, error) {
+	return s.teams.ListCodeowners(ctx, scope, name)
 }
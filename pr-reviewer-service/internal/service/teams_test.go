@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestUpdateSettings_RejectsInvalid(t *testing.T) {
+	s := NewTeamsService(nil)
+	cases := map[string]func(*model.TeamSettings){
+		"unknown strategy": func(st *model.TeamSettings) { st.SelectionStrategy = "fastest" },
+		"unknown policy":   func(st *model.TeamSettings) { st.UnderstaffedPolicy = "ignore" },
+		"zero reviewers":   func(st *model.TeamSettings) { st.ReviewerCount = 0 },
+		"negative minimum": func(st *model.TeamSettings) { st.MinReviewers = -1 },
+		"minimum above count": func(st *model.TeamSettings) {
+			st.ReviewerCount, st.MinReviewers = 2, 3
+		},
+		"self fallback":      func(st *model.TeamSettings) { st.FallbackTeams = []string{"backend"} },
+		"duplicate fallback": func(st *model.TeamSettings) { st.FallbackTeams = []string{"ops", "ops"} },
+	}
+	for name, mutate := range cases {
+		settings := model.DefaultTeamSettings("backend")
+		mutate(&settings)
+		if _, err := s.UpdateSettings(context.Background(), settings); err != ErrInvalidSettings {
+			t.Fatalf("%s: expected ErrInvalidSettings, got %v", name, err)
+		}
+	}
+}
+
+func TestRename_RejectsInvalidName(t *testing.T) {
+	s := NewTeamsService(nil)
+	for _, name := range []string{"", "backend"} {
+		if _, err := s.Rename(context.Background(), "backend", name); err != ErrInvalidTeamName {
+			t.Fatalf("%q: expected ErrInvalidTeamName, got %v", name, err)
+		}
+	}
+}
+
+func TestAddMembers_RejectsInvalid(t *testing.T) {
+	s := NewTeamsService(nil)
+	cases := map[string][]model.TeamMember{
+		"no members": nil,
+		"no user_id": {{UserID: "u1", Username: "Alice"}, {Username: "Bob"}},
+	}
+	for name, members := range cases {
+		if _, err := s.AddMembers(context.Background(), "backend", members); err != ErrInvalidMembers {
+			t.Fatalf("%s: expected ErrInvalidMembers, got %v", name, err)
+		}
+	}
+}
+
+func TestSetMemberWeight_RejectsNonPositive(t *testing.T) {
+	s := NewTeamsService(nil)
+	for _, w := range []int{0, -2} {
+		if err := s.SetMemberWeight(context.Background(), "backend", "u1", &w); err != ErrInvalidWeight {
+			t.Fatalf("%d: expected ErrInvalidWeight, got %v", w, err)
+		}
+	}
+}
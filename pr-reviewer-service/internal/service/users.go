@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"net/mail"
+	"strings"
 
 	"pr-reviewer-service/internal/model"
 	"pr-reviewer-service/internal/repository"
@@ -22,3 +25,153 @@ func (s *UsersService) SetIsActive(ctx context.Context, id string, active bool)
 func (s *UsersService) Get(ctx context.Context, id string) (model.User, error) {
 	return s.users.GetUser(ctx, id)
 }
+
+// List возвращает пользователей команды team (или всех, если она не задана), у которых есть все теги из tags.
+func (s *UsersService) List(ctx context.Context, team string, tags []string) ([]model.User, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.users.List(ctx, team, tags)
+}
+
+// AddTags добавляет навыки пользователю.
+func (s *UsersService) AddTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil || len(tags) == 0 {
+		return model.User{}, ErrInvalidTag
+	}
+	return s.users.AddTags(ctx, id, tags)
+}
+
+// RemoveTags снимает навыки с пользователя.
+func (s *UsersService) RemoveTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil || len(tags) == 0 {
+		return model.User{}, ErrInvalidTag
+	}
+	return s.users.RemoveTags(ctx, id, tags)
+}
+
+// SetTags заменяет навыки пользователя; пустой список убирает все.
+func (s *UsersService) SetTags(ctx context.Context, id string, tags []string) (model.User, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return model.User{}, err
+	}
+	return s.users.SetTags(ctx, id, tags)
+}
+
+var ErrInvalidLimit = errors.New("invalid review limit")
+
+func (s *UsersService) SetMaxOpenReviews(ctx context.Context, id string, limit *int) (model.User, error) {
+	if limit != nil && *limit < 0 {
+		return model.User{}, ErrInvalidLimit
+	}
+	return s.users.SetMaxOpenReviews(ctx, id, limit)
+}
+
+// SetChatHandle задаёт идентификатор пользователя в чате для упоминаний; nil или пустая строка убирают его.
+func (s *UsersService) SetChatHandle(ctx context.Context, id string, handle *string) (model.User, error) {
+	if handle != nil {
+		h := strings.TrimPrefix(strings.TrimSpace(*handle), "@")
+		handle = &h
+		if h == "" {
+			handle = nil
+		}
+	}
+	return s.users.SetChatHandle(ctx, id, handle)
+}
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+// SetEmail задаёт адрес для email-уведомлений; nil или пустая строка убирают его.
+func (s *UsersService) SetEmail(ctx context.Context, id string, email *string) (model.User, error) {
+	if email != nil {
+		e := strings.TrimSpace(*email)
+		if e == "" {
+			email = nil
+		} else {
+			addr, err := mail.ParseAddress(e)
+			if err != nil || addr.Name != "" {
+				return model.User{}, ErrInvalidEmail
+			}
+			email = &addr.Address
+		}
+	}
+	return s.users.SetEmail(ctx, id, email)
+}
+
+func (s *UsersService) GetNotificationPreferences(ctx context.Context, id string) (model.NotificationPreferences, error) {
+	return s.users.GetNotificationPreferences(ctx, id)
+}
+
+func (s *UsersService) UpdateNotificationPreferences(ctx context.Context, p model.NotificationPreferences) (model.NotificationPreferences, error) {
+	return s.users.UpsertNotificationPreferences(ctx, p)
+}
+
+// SetLevel задаёт уровень пользователя; пустой уровень убирает его.
+func (s *UsersService) SetLevel(ctx context.Context, id string, level model.Level) (model.User, error) {
+	if level != "" && !level.Valid() {
+		return model.User{}, ErrInvalidLevel
+	}
+	return s.users.SetLevel(ctx, id, level)
+}
+
+var ErrInvalidSchedule = errors.New("invalid work schedule")
+
+// SetWorkSchedule задаёт рабочие часы пользователя; nil убирает их. Без дней — понедельник–пятница.
+func (s *UsersService) SetWorkSchedule(ctx context.Context, id string, schedule *model.WorkSchedule) (model.User, error) {
+	if schedule != nil {
+		if len(schedule.Days) == 0 {
+			schedule.Days = model.DefaultWorkDays
+		}
+		if !schedule.Valid() {
+			return model.User{}, ErrInvalidSchedule
+		}
+	}
+	return s.users.SetWorkSchedule(ctx, id, schedule)
+}
+
+var ErrInvalidAvailability = errors.New("invalid availability window")
+
+// ListAvailability возвращает текущие и будущие периоды недоступности пользователя.
+func (s *UsersService) ListAvailability(ctx context.Context, userID string) ([]model.Availability, error) {
+	return s.users.ListAvailability(ctx, userID)
+}
+
+// AddAvailability добавляет период недоступности; пока он идёт, пользователь не выбирается ревьювером.
+func (s *UsersService) AddAvailability(ctx context.Context, a model.Availability) (model.Availability, error) {
+	if a.UserID == "" || !validWindow(a) {
+		return model.Availability{}, ErrInvalidAvailability
+	}
+	return s.users.CreateAvailability(ctx, a)
+}
+
+// UpdateAvailability меняет сроки, причину и auto_reassign периода; пользователь не меняется.
+func (s *UsersService) UpdateAvailability(ctx context.Context, a model.Availability) (model.Availability, error) {
+	if !validWindow(a) {
+		return model.Availability{}, ErrInvalidAvailability
+	}
+	return s.users.UpdateAvailability(ctx, a)
+}
+
+func (s *UsersService) DeleteAvailability(ctx context.Context, id int64) error {
+	return s.users.DeleteAvailability(ctx, id)
+}
+
+func validWindow(a model.Availability) bool {
+	return !a.StartsAt.IsZero() && a.EndsAt.After(a.StartsAt)
+}
+
+var ErrInvalidAccount = errors.New("invalid external account")
+
+// LinkAccount привязывает логин во внешней системе (например, GitHub) к пользователю.
+func (s *UsersService) LinkAccount(ctx context.Context, acc model.ExternalAccount) (model.ExternalAccount, error) {
+	acc.Provider = strings.ToLower(strings.TrimSpace(acc.Provider))
+	acc.Login = strings.TrimSpace(acc.Login)
+	if acc.Provider == "" || acc.Login == "" || acc.UserID == "" {
+		return model.ExternalAccount{}, ErrInvalidAccount
+	}
+	return s.users.LinkAccount(ctx, acc)
+}
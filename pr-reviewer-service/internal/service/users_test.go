@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestSetMaxOpenReviews_RejectsNegative(t *testing.T) {
+	s := NewUsersService(nil)
+	limit := -1
+	if _, err := s.SetMaxOpenReviews(context.Background(), "u1", &limit); err != ErrInvalidLimit {
+		t.Fatalf("expected ErrInvalidLimit, got %v", err)
+	}
+}
+
+func TestAvailability_RejectsInvalidWindow(t *testing.T) {
+	s := NewUsersService(nil)
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	cases := map[string]model.Availability{
+		"no user":      {StartsAt: start, EndsAt: start.Add(time.Hour)},
+		"no start":     {UserID: "u1", EndsAt: start},
+		"empty window": {UserID: "u1", StartsAt: start, EndsAt: start},
+		"reversed":     {UserID: "u1", StartsAt: start, EndsAt: start.Add(-time.Hour)},
+	}
+	for name, a := range cases {
+		if _, err := s.AddAvailability(context.Background(), a); err != ErrInvalidAvailability {
+			t.Fatalf("%s: expected ErrInvalidAvailability, got %v", name, err)
+		}
+	}
+	a := cases["reversed"]
+	a.ID = 1
+	if _, err := s.UpdateAvailability(context.Background(), a); err != ErrInvalidAvailability {
+		t.Fatalf("update: expected ErrInvalidAvailability, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Package webhook разбирает входящие вебхуки внешних систем (GitHub, GitLab)
+// и приводит события PR к общему виду model.ForgeEvent.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"pr-reviewer-service/internal/model"
+)
+
+const ProviderGitHub = "github"
+
+var (
+	// ErrUnsupported — событие корректно, но сервису не интересно (другой тип события или действие).
+	ErrUnsupported = errors.New("unsupported event")
+	ErrBadPayload  = errors.New("bad payload")
+)
+
+// VerifyGitHubSignature проверяет заголовок X-Hub-Signature-256 ("sha256=<hex>") —
+// HMAC-SHA256 тела запроса на секрете вебхука.
+func VerifyGitHubSignature(secret string, body []byte, header string) bool {
+	hexSig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok || secret == "" {
+		return false
+	}
+	sig, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest *struct {
+		Title  string      `json:"title"`
+		Draft  bool        `json:"draft"`
+		Merged bool        `json:"merged"`
+		User   githubUser  `json:"user"`
+		Merger *githubUser `json:"merged_by"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender githubUser `json:"sender"`
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// ParseGitHub разбирает тело вебхука с типом события eventType (заголовок X-GitHub-Event).
+// Поддерживаются события pull_request с действиями opened, closed, reopened, ready_for_review
+// и converted_to_draft;
+// для остальных возвращается ErrUnsupported.
+func ParseGitHub(eventType string, body []byte) (model.ForgeEvent, error) {
+	if eventType != "pull_request" {
+		return model.ForgeEvent{}, ErrUnsupported
+	}
+	var p githubPullRequestEvent
+	if err := json.Unmarshal(body, &p); err != nil {
+		return model.ForgeEvent{}, ErrBadPayload
+	}
+	if p.PullRequest == nil || p.Repository.FullName == "" || p.Number == 0 {
+		return model.ForgeEvent{}, ErrBadPayload
+	}
+
+	ev := model.ForgeEvent{
+		Provider:    ProviderGitHub,
+		Repo:        p.Repository.FullName,
+		Number:      p.Number,
+		Title:       p.PullRequest.Title,
+		AuthorLogin: p.PullRequest.User.Login,
+		ActorLogin:  p.Sender.Login,
+		Draft:       p.PullRequest.Draft,
+	}
+	switch p.Action {
+	case "opened":
+		ev.Action = model.ForgeOpened
+	case "ready_for_review":
+		ev.Action = model.ForgeReady
+	case "converted_to_draft":
+		ev.Action = model.ForgeConvertedToDraft
+	case "reopened":
+		ev.Action = model.ForgeReopened
+	case "closed":
+		ev.Action = model.ForgeClosed
+		if p.PullRequest.Merged {
+			ev.Action = model.ForgeMerged
+			if p.PullRequest.Merger != nil {
+				ev.ActorLogin = p.PullRequest.Merger.Login
+			}
+		}
+	default:
+		return model.ForgeEvent{}, ErrUnsupported
+	}
+	return ev, nil
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := fixture(t, "github/pull_request_opened.json")
+	good := sign("s3cret", body)
+
+	if !VerifyGitHubSignature("s3cret", body, good) {
+		t.Fatal("valid signature rejected")
+	}
+	cases := map[string]struct {
+		secret, header string
+		body           []byte
+	}{
+		"wrong secret":   {"other", good, body},
+		"tampered body":  {"s3cret", good, append([]byte(" "), body...)},
+		"no prefix":      {"s3cret", good[len("sha256="):], body},
+		"sha1 header":    {"s3cret", "sha1=" + good[len("sha256="):], body},
+		"not hex":        {"s3cret", "sha256=zz", body},
+		"empty secret":   {"", sign("", body), body},
+		"missing header": {"s3cret", "", body},
+	}
+	for name, c := range cases {
+		if VerifyGitHubSignature(c.secret, c.body, c.header) {
+			t.Errorf("%s: signature accepted", name)
+		}
+	}
+}
+
+func TestParseGitHub_Fixtures(t *testing.T) {
+	cases := []struct {
+		file  string
+		want  model.ForgeAction
+		id    string
+		actor string
+		draft bool
+	}{
+		{"pull_request_opened.json", model.ForgeOpened, "github:acme/api#42", "octo-alice", false},
+		{"pull_request_opened_draft.json", model.ForgeOpened, "github:acme/api#43", "octo-alice", true},
+		{"pull_request_ready_for_review.json", model.ForgeReady, "github:acme/api#43", "octo-alice", false},
+		{"pull_request_closed.json", model.ForgeClosed, "github:acme/api#42", "octo-bob", false},
+		{"pull_request_closed_merged.json", model.ForgeMerged, "github:acme/api#42", "Octo-Bob", false},
+		{"pull_request_reopened.json", model.ForgeReopened, "github:acme/api#42", "octo-alice", false},
+		{"pull_request_converted_to_draft.json", model.ForgeConvertedToDraft, "github:acme/api#42", "octo-alice", true},
+	}
+	for _, c := range cases {
+		ev, err := ParseGitHub("pull_request", fixture(t, filepath.Join("github", c.file)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.file, err)
+		}
+		if ev.Action != c.want || ev.PullRequestID() != c.id || ev.ActorLogin != c.actor || ev.Draft != c.draft {
+			t.Errorf("%s: got %+v", c.file, ev)
+		}
+		if ev.AuthorLogin != "Octo-Alice" || ev.Title != "Add search endpoint" {
+			t.Errorf("%s: author/title not parsed: %+v", c.file, ev)
+		}
+	}
+}
+
+func TestParseGitHub_Unsupported(t *testing.T) {
+	if _, err := ParseGitHub("pull_request", fixture(t, "github/pull_request_labeled.json")); err != ErrUnsupported {
+		t.Fatalf("labeled: got %v", err)
+	}
+	if _, err := ParseGitHub("ping", fixture(t, "github/ping.json")); err != ErrUnsupported {
+		t.Fatalf("ping: got %v", err)
+	}
+	if _, err := ParseGitHub("pull_request", []byte(`{"action":"opened"}`)); err != ErrBadPayload {
+		t.Fatalf("incomplete payload: got %v", err)
+	}
+	if _, err := ParseGitHub("pull_request", []byte(`not json`)); err != ErrBadPayload {
+		t.Fatalf("bad json: got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+
+	"pr-reviewer-service/internal/model"
+)
+
+const ProviderGitLab = "gitlab"
+
+// VerifyGitLabToken сравнивает заголовок X-Gitlab-Token с секретом вебхука.
+func VerifyGitLabToken(secret, header string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte(secret)) == 1
+}
+
+type gitlabMergeRequestEvent struct {
+	ObjectKind string     `json:"object_kind"`
+	User       gitlabUser `json:"user"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	Attrs *struct {
+		IID            int    `json:"iid"`
+		Title          string `json:"title"`
+		Action         string `json:"action"`
+		Draft          *bool  `json:"draft"`
+		WorkInProgress bool   `json:"work_in_progress"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Draft          *gitlabBoolChange `json:"draft"`
+		WorkInProgress *gitlabBoolChange `json:"work_in_progress"`
+	} `json:"changes"`
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabBoolChange struct {
+	Previous bool `json:"previous"`
+	Current  bool `json:"current"`
+}
+
+// ParseGitLab разбирает тело вебхука с типом события eventType (заголовок X-Gitlab-Event).
+// Поддерживается Merge Request Hook с действиями open, close, merge, reopen и update,
+// если update переключает черновик; для остальных возвращается ErrUnsupported.
+//
+// В payload GitLab у MR есть только числовой author_id, поэтому автором открытого MR
+// считается пользователь, выполнивший действие open.
+func ParseGitLab(eventType string, body []byte) (model.ForgeEvent, error) {
+	if eventType != "Merge Request Hook" {
+		return model.ForgeEvent{}, ErrUnsupported
+	}
+	var p gitlabMergeRequestEvent
+	if err := json.Unmarshal(body, &p); err != nil {
+		return model.ForgeEvent{}, ErrBadPayload
+	}
+	if p.ObjectKind != "merge_request" || p.Attrs == nil || p.Project.PathWithNamespace == "" || p.Attrs.IID == 0 {
+		return model.ForgeEvent{}, ErrBadPayload
+	}
+
+	// Старые версии GitLab сообщают черновик только через work_in_progress.
+	draft := p.Attrs.WorkInProgress
+	if p.Attrs.Draft != nil {
+		draft = *p.Attrs.Draft
+	}
+	ev := model.ForgeEvent{
+		Provider:    ProviderGitLab,
+		Repo:        p.Project.PathWithNamespace,
+		Number:      p.Attrs.IID,
+		Title:       p.Attrs.Title,
+		AuthorLogin: p.User.Username,
+		ActorLogin:  p.User.Username,
+		Draft:       draft,
+	}
+	switch p.Attrs.Action {
+	case "open":
+		ev.Action = model.ForgeOpened
+	case "close":
+		ev.Action = model.ForgeClosed
+	case "merge":
+		ev.Action = model.ForgeMerged
+	case "reopen":
+		ev.Action = model.ForgeReopened
+	case "update":
+		change := p.Changes.Draft
+		if change == nil {
+			change = p.Changes.WorkInProgress
+		}
+		if change == nil || change.Previous == change.Current {
+			return model.ForgeEvent{}, ErrUnsupported
+		}
+		ev.Action = model.ForgeReady
+		if change.Current {
+			ev.Action = model.ForgeConvertedToDraft
+		}
+	default:
+		return model.ForgeEvent{}, ErrUnsupported
+	}
+	return ev, nil
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+
+	"pr-reviewer-service/internal/model"
+)
+
+func TestVerifyGitLabToken(t *testing.T) {
+	if !VerifyGitLabToken("t0ken", "t0ken") {
+		t.Fatal("valid token rejected")
+	}
+	for _, c := range [][2]string{{"t0ken", "other"}, {"t0ken", ""}, {"", ""}} {
+		if VerifyGitLabToken(c[0], c[1]) {
+			t.Errorf("secret %q, header %q: token accepted", c[0], c[1])
+		}
+	}
+}
+
+func TestParseGitLab_Fixtures(t *testing.T) {
+	cases := []struct {
+		file  string
+		want  model.ForgeAction
+		id    string
+		actor string
+		draft bool
+	}{
+		{"merge_request_open.json", model.ForgeOpened, "gitlab:acme/web#7", "bob.dev", false},
+		{"merge_request_open_draft.json", model.ForgeOpened, "gitlab:acme/web#8", "bob.dev", true},
+		{"merge_request_mark_ready.json", model.ForgeReady, "gitlab:acme/web#8", "bob.dev", false},
+		{"merge_request_mark_ready_legacy.json", model.ForgeReady, "gitlab:acme/web#8", "bob.dev", false},
+		{"merge_request_mark_draft.json", model.ForgeConvertedToDraft, "gitlab:acme/web#7", "bob.dev", true},
+		{"merge_request_close.json", model.ForgeClosed, "gitlab:acme/web#7", "carol", false},
+		{"merge_request_merge.json", model.ForgeMerged, "gitlab:acme/web#7", "carol", false},
+		{"merge_request_reopen.json", model.ForgeReopened, "gitlab:acme/web#7", "bob.dev", false},
+	}
+	for _, c := range cases {
+		ev, err := ParseGitLab("Merge Request Hook", fixture(t, filepath.Join("gitlab", c.file)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.file, err)
+		}
+		if ev.Action != c.want || ev.PullRequestID() != c.id || ev.ActorLogin != c.actor || ev.Draft != c.draft {
+			t.Errorf("%s: got %+v", c.file, ev)
+		}
+		if ev.Provider != ProviderGitLab || ev.Repo != "acme/web" {
+			t.Errorf("%s: project not parsed: %+v", c.file, ev)
+		}
+	}
+}
+
+func TestParseGitLab_Unsupported(t *testing.T) {
+	for _, f := range []string{"merge_request_update_title.json", "merge_request_approved.json"} {
+		if _, err := ParseGitLab("Merge Request Hook", fixture(t, filepath.Join("gitlab", f))); err != ErrUnsupported {
+			t.Errorf("%s: got %v", f, err)
+		}
+	}
+	if _, err := ParseGitLab("Push Hook", fixture(t, "gitlab/merge_request_open.json")); err != ErrUnsupported {
+		t.Errorf("push hook: got %v", err)
+	}
+	if _, err := ParseGitLab("Merge Request Hook", []byte(`{"object_kind":"merge_request"}`)); err != ErrBadPayload {
+		t.Errorf("incomplete payload: got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+ALTER TABLE users
+    ADD COLUMN review_weight INTEGER NOT NULL DEFAULT 1 CHECK (review_weight > 0);
+
+CREATE TABLE team_settings (
+    team_name          TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    selection_strategy TEXT NOT NULL DEFAULT 'random'
+        CHECK (selection_strategy IN ('random', 'round_robin', 'least_loaded', 'weighted'))
+);
@@ -0,0 +1,8 @@
+-- NULL — без ограничения на число одновременных открытых ревью.
+ALTER TABLE users
+    ADD COLUMN max_open_reviews INTEGER CHECK (max_open_reviews >= 0);
+
+ALTER TABLE team_settings
+    ALTER COLUMN selection_strategy SET DEFAULT 'least_loaded';
+
+CREATE INDEX idx_pull_requests_status ON pull_requests(status);
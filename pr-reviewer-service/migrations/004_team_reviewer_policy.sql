@@ -0,0 +1,9 @@
+ALTER TABLE team_settings
+    ADD COLUMN reviewer_count INTEGER NOT NULL DEFAULT 2 CHECK (reviewer_count >= 1),
+    ADD COLUMN min_reviewers INTEGER NOT NULL DEFAULT 1 CHECK (min_reviewers >= 0),
+    ADD COLUMN understaffed_policy TEXT NOT NULL DEFAULT 'understaffed'
+        CHECK (understaffed_policy IN ('reject', 'fallback', 'understaffed')),
+    ADD CONSTRAINT team_settings_min_le_count CHECK (min_reviewers <= reviewer_count);
+
+ALTER TABLE pull_requests
+    ADD COLUMN understaffed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+CREATE TABLE team_fallbacks (
+    team_name     TEXT NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    fallback_team TEXT NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    PRIMARY KEY (team_name, fallback_team),
+    UNIQUE (team_name, position),
+    CHECK (team_name <> fallback_team)
+);
+
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN is_fallback BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN state TEXT NOT NULL DEFAULT 'PENDING'
+        CHECK (state IN ('PENDING', 'APPROVED', 'CHANGES_REQUESTED', 'DISMISSED')),
+    ADD COLUMN assigned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN reviewed_at TIMESTAMPTZ;
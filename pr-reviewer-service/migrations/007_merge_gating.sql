@@ -0,0 +1,15 @@
+ALTER TABLE team_settings
+    ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0 CHECK (required_approvals >= 0),
+    ADD COLUMN block_on_changes_requested BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Журнал принудительных мержей в обход правил команды.
+CREATE TABLE merge_overrides (
+    id               BIGSERIAL PRIMARY KEY,
+    pull_request_id  TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    actor            TEXT NOT NULL,
+    reason           TEXT NOT NULL DEFAULT '',
+    unmet_conditions TEXT[] NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_merge_overrides_pr ON merge_overrides(pull_request_id);
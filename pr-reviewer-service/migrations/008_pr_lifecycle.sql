@@ -0,0 +1,4 @@
+ALTER TABLE pull_requests
+    DROP CONSTRAINT pull_requests_status_check,
+    ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('DRAFT', 'OPEN', 'CLOSED', 'MERGED')),
+    ADD COLUMN closed_at TIMESTAMPTZ;
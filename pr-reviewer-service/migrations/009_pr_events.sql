@@ -0,0 +1,31 @@
+-- Журнал событий PR: только добавление, строки не меняются и не удаляются.
+CREATE TABLE pr_events (
+    id              BIGSERIAL PRIMARY KEY,
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id),
+    event_type      TEXT NOT NULL,
+    user_id         TEXT,
+    payload         JSONB NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_pr_events_pr ON pr_events(pull_request_id, id);
+
+CREATE FUNCTION pr_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'pr_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER pr_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON pr_events
+    FOR EACH ROW EXECUTE FUNCTION pr_events_append_only();
+
+-- История до появления журнала: текущие назначения считаем первоначальными.
+INSERT INTO pr_events (pull_request_id, event_type, payload, created_at)
+SELECT pull_request_id, 'created', jsonb_build_object('author_id', author_id, 'backfilled', true), created_at
+FROM pull_requests;
+
+INSERT INTO pr_events (pull_request_id, event_type, user_id, payload, created_at)
+SELECT pull_request_id, 'reviewer_assigned', user_id,
+       jsonb_build_object('fallback', is_fallback, 'backfilled', true), assigned_at
+FROM pull_request_reviewers;
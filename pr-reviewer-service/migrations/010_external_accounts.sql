@@ -0,0 +1,10 @@
+-- Учётные записи пользователей во внешних системах (GitHub и т.п.): login → user_id.
+-- Логины храним в нижнем регистре: у GitHub они регистронезависимы.
+CREATE TABLE external_accounts (
+    provider TEXT NOT NULL,
+    login    TEXT NOT NULL CHECK (login = lower(login)),
+    user_id  TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    PRIMARY KEY (provider, login)
+);
+
+CREATE INDEX idx_external_accounts_user ON external_accounts(user_id);
@@ -0,0 +1,20 @@
+-- Откуда пришёл PR: вручную через API или из внешней системы (GitHub, GitLab).
+-- Для внешних PR храним проект и номер (GitHub number / GitLab IID) — пара уникальна в рамках источника.
+ALTER TABLE pull_requests
+    ADD COLUMN source           TEXT NOT NULL DEFAULT 'manual',
+    ADD COLUMN external_project TEXT,
+    ADD COLUMN external_iid     INT;
+
+UPDATE pull_requests
+SET source           = 'github',
+    external_project = substring(pull_request_id FROM '^github:(.+)#[0-9]+$'),
+    external_iid     = substring(pull_request_id FROM '#([0-9]+)$')::int
+WHERE pull_request_id ~ '^github:.+#[0-9]+$';
+
+ALTER TABLE pull_requests
+    ADD CONSTRAINT pull_requests_external_ref_check
+        CHECK ((external_project IS NULL) = (external_iid IS NULL));
+
+CREATE UNIQUE INDEX uq_pull_requests_external
+    ON pull_requests(source, external_project, external_iid)
+    WHERE external_project IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Transactional outbox: события пишутся в той же транзакции, что и изменение,
+-- и рассылаются отдельным процессом. Откат транзакции — нет события.
+CREATE TABLE outbox (
+    id            BIGSERIAL PRIMARY KEY,
+    topic         TEXT NOT NULL,
+    payload       JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE webhook_subscriptions (
+    id         BIGSERIAL PRIMARY KEY,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    topics     TEXT[] NOT NULL DEFAULT '{}',
+    is_active  BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Доставки событий получателям по каналам (webhook — target = id подписки) и журнал попыток.
+CREATE TABLE deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    outbox_id        BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+    channel          TEXT NOT NULL,
+    target           TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'FAILED')),
+    attempts         INT NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (outbox_id, channel, target)
+);
+
+CREATE INDEX idx_deliveries_due ON deliveries(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_deliveries_target ON deliveries(channel, target, id);
@@ -0,0 +1,3 @@
+-- Уведомления в чат (Slack/Mattermost incoming webhook): канал команды и упоминание пользователя.
+ALTER TABLE team_settings ADD COLUMN chat_webhook_url TEXT;
+ALTER TABLE users ADD COLUMN chat_handle TEXT;
@@ -0,0 +1,10 @@
+-- Email-уведомления: адрес пользователя и его настройки уведомлений.
+ALTER TABLE users ADD COLUMN email TEXT;
+
+-- Нет строки — значения по умолчанию (все уведомления включены).
+CREATE TABLE notification_preferences (
+    user_id        TEXT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    email_assigned BOOLEAN NOT NULL DEFAULT TRUE,
+    email_replaced BOOLEAN NOT NULL DEFAULT TRUE,
+    email_reminder BOOLEAN NOT NULL DEFAULT TRUE
+);
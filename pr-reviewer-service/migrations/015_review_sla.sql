@@ -0,0 +1,23 @@
+-- SLA ревью: через reminder_after_hours без ревью ревьюверу уходит напоминание (и повторяется с тем же
+-- интервалом), через escalate_after_hours ревьювер заменяется. 0 отключает соответствующий шаг;
+-- эскалация по умолчанию выключена: автозамена ревьювера включается командой явно.
+ALTER TABLE team_settings
+    ADD COLUMN reminder_after_hours INT NOT NULL DEFAULT 24 CHECK (reminder_after_hours >= 0),
+    ADD COLUMN escalate_after_hours INT NOT NULL DEFAULT 0 CHECK (escalate_after_hours >= 0);
+
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN reminded_at  TIMESTAMPTZ,
+    ADD COLUMN escalated_at TIMESTAMPTZ;
+
+-- Эскалации: ревьювер не ответил за escalate_after_hours. to_user_id NULL — замены не нашлось.
+CREATE TABLE review_escalations (
+    id              BIGSERIAL PRIMARY KEY,
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id),
+    team_name       TEXT NOT NULL,
+    from_user_id    TEXT NOT NULL REFERENCES users(user_id),
+    to_user_id      TEXT REFERENCES users(user_id),
+    assigned_at     TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_review_escalations_team ON review_escalations(team_name, id);
@@ -0,0 +1,17 @@
+-- Периоды недоступности пользователя (отпуск, больничный): [starts_at, ends_at).
+-- Пока период идёт, пользователь не выбирается ревьювером.
+CREATE TABLE user_availability (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    starts_at     TIMESTAMPTZ NOT NULL,
+    ends_at       TIMESTAMPTZ NOT NULL,
+    reason        TEXT NOT NULL DEFAULT '',
+    -- auto_reassign: с началом периода PENDING-ревью пользователя в OPEN PR передаются другим;
+    -- reassigned_at — когда это сделано.
+    auto_reassign BOOLEAN NOT NULL DEFAULT FALSE,
+    reassigned_at TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_user_availability_user ON user_availability(user_id, ends_at);
@@ -0,0 +1,3 @@
+-- Рабочие часы пользователя: {"timezone": "Europe/Moscow", "start": "10:00", "end": "19:00", "days": [1,2,3,4,5]}.
+-- NULL — пользователь доступен в любое время.
+ALTER TABLE users ADD COLUMN work_schedule JSONB;
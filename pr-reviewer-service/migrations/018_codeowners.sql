@@ -0,0 +1,23 @@
+-- Правила CODEOWNERS для команды автора или для репозитория PR, в порядке строк файла.
+CREATE TABLE codeowners_rules (
+    scope      TEXT NOT NULL CHECK (scope IN ('team', 'repository')),
+    scope_name TEXT NOT NULL,
+    position   INT NOT NULL,
+    pattern    TEXT NOT NULL,
+    owners     TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (scope, scope_name, position)
+);
+
+-- Репозиторий и изменённые файлы PR нужны и при создании, и при переходе из DRAFT в OPEN.
+ALTER TABLE pull_requests
+    ADD COLUMN repository    TEXT,
+    ADD COLUMN changed_files TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE pull_requests SET repository = external_project WHERE external_project IS NOT NULL;
+
+-- Почему выбран ревьювер: codeowner, team, fallback_team, outside_team.
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN selection_reason TEXT NOT NULL DEFAULT 'team',
+    ADD COLUMN matched_paths    TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE pull_request_reviewers SET selection_reason = 'fallback_team' WHERE is_fallback;
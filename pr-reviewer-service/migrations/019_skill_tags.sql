@@ -0,0 +1,11 @@
+-- Навыки пользователя («postgres», «frontend», «security»), в нижнем регистре.
+ALTER TABLE users ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX idx_users_tags ON users USING GIN (tags);
+
+-- Навыки, которые должны быть у ревьюверов PR, и те из них, что не покрыл ни один ревьювер.
+ALTER TABLE pull_requests
+    ADD COLUMN required_tags  TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN uncovered_tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Обязательные теги PR, ради которых выбран ревьювер (selection_reason = 'skill').
+ALTER TABLE pull_request_reviewers ADD COLUMN matched_tags TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- Окно в днях, за которое учитываются недавние пары автор→ревьювер: кто чаще ревьюил автора,
+-- тот выбирается позже. 0 отключает учёт пар.
+ALTER TABLE team_settings
+    ADD COLUMN pair_window_days INT NOT NULL DEFAULT 30 CHECK (pair_window_days >= 0);
+
+CREATE INDEX idx_pull_requests_author ON pull_requests(author_id);
@@ -0,0 +1,9 @@
+-- Уровень пользователя; NULL — не задан (для правил считается не junior и не senior).
+ALTER TABLE users
+    ADD COLUMN level TEXT CHECK (level IN ('junior', 'middle', 'senior', 'lead'));
+
+-- Правила уровня ревьюверов PR: сколько из них должны быть senior или lead и можно ли
+-- оставлять PR только junior-ревьюверам.
+ALTER TABLE team_settings
+    ADD COLUMN min_senior_reviewers INT NOT NULL DEFAULT 0 CHECK (min_senior_reviewers >= 0),
+    ADD COLUMN forbid_junior_only   BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Пользователь может быть вне команды (после /team/removeMember); такие не попадают в кандидаты.
+-- Переименование команды переносит на новое имя и её участников.
+ALTER TABLE users
+    ALTER COLUMN team_name DROP NOT NULL,
+    DROP CONSTRAINT users_team_name_fkey,
+    ADD CONSTRAINT users_team_name_fkey
+        FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE RESTRICT;
@@ -0,0 +1,21 @@
+-- Участие пользователей в командах: кроме основной команды (users.team_name) пользователь может
+-- ревьюить и в других. Основная команда тоже хранится здесь. review_weight — вес для стратегии
+-- weighted в этой команде; NULL — общий вес пользователя (users.review_weight).
+CREATE TABLE team_memberships (
+    team_name     TEXT NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id       TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    review_weight INT CHECK (review_weight > 0),
+    PRIMARY KEY (team_name, user_id)
+);
+
+CREATE INDEX idx_team_memberships_user ON team_memberships(user_id);
+
+INSERT INTO team_memberships (team_name, user_id)
+SELECT team_name, user_id FROM users WHERE team_name IS NOT NULL;
+
+-- Команда, от имени которой создан PR: её пул и настройки используются при подборе ревьюверов.
+ALTER TABLE pull_requests
+    ADD COLUMN team_name TEXT REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE pull_requests pr SET team_name = u.team_name
+FROM users u WHERE u.user_id = pr.author_id;
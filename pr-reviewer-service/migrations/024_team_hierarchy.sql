@@ -0,0 +1,7 @@
+-- Иерархия команд: отдел → команды. Когда в команде кончаются кандидаты, подбор поднимается
+-- к соседним командам, затем к родителю. Циклы запрещаются при изменении parent_team.
+ALTER TABLE teams
+    ADD COLUMN parent_team TEXT REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE RESTRICT,
+    ADD CONSTRAINT teams_parent_not_self CHECK (parent_team <> team_name);
+
+CREATE INDEX idx_teams_parent_team ON teams(parent_team);